@@ -0,0 +1,189 @@
+package main
+
+// ===================== BAREMA ITEM 1: ARQUITETURA =====================
+// Configuração do cliente interativo.
+// Como no servidor, cada opção é uma flag e os valores vêm, em ordem
+// crescente de prioridade, do padrão, do perfil salvo (último servidor e
+// nome usados), de variáveis de ambiente (JOGO_CLIENTE_<FLAG>) e da linha
+// de comando. O perfil é regravado após cada conexão bem-sucedida; o modo
+// -json não lê nem grava o perfil, para que scripts sejam reproduzíveis.
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"meujogo/seguranca"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BAREMA ITEM 1: ARQUITETURA - Parâmetros de execução do cliente
+type configCliente struct {
+	Host       string // Host do servidor
+	Porta      int    // Porta do servidor
+	Nome       string // Nome do jogador (sugerido no prompt se veio do perfil)
+	Senha      string // Registra o nome no servidor ou prova que ele é seu (nunca vai para o perfil)
+	Fila       bool   // Entra na fila logo após conectar (e após reconectar)
+	Tentativas int    // BAREMA ITEM 2: COMUNICAÇÃO - Tentativas na conexão inicial, com espera crescente
+	Reconectar bool   // BAREMA ITEM 2: COMUNICAÇÃO - Reconecta automaticamente se a conexão cair
+	TUI        bool   // Interface em tela cheia
+	JSON       bool   // BAREMA ITEM 3: API REMOTA - Sem interface: JSON por linha na entrada e na saída
+	Perfil     string // Arquivo do perfil ("" não lê nem grava)
+	TLS        seguranca.OpcoesCliente
+
+	nomeInformado bool // Nome veio de flag ou ambiente: não pergunta
+}
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Dados lembrados entre execuções
+type perfilCliente struct {
+	Host  string `json:"host"`
+	Porta int    `json:"porta"`
+	Nome  string `json:"nome"`
+}
+
+func (c *configCliente) registrarFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Host, "host", "localhost", "host do servidor")
+	fs.IntVar(&c.Porta, "porta", 65432, "porta do servidor")
+	fs.StringVar(&c.Nome, "nome", "", "nome do jogador (se vazio, é perguntado)")
+	fs.StringVar(&c.Senha, "senha", "", "senha do nome: a primeira usada o registra, e sem ela não há amigos nem mensagens privadas")
+	fs.BoolVar(&c.Fila, "fila", true, "entra na fila de pareamento ao conectar")
+	fs.IntVar(&c.Tentativas, "tentativas", 8, "tentativas de conexão inicial, com espera crescente entre elas")
+	fs.BoolVar(&c.Reconectar, "reconectar", true, "reconecta automaticamente quando a conexão cai")
+	fs.BoolVar(&c.TUI, "tui", false, "interface de terminal em tela cheia")
+	fs.BoolVar(&c.JSON, "json", false, "sem interface: lê um comando JSON por linha e escreve cada mensagem do servidor como uma linha JSON")
+	fs.StringVar(&c.Perfil, "perfil", arquivoPerfilPadrao(), "arquivo do perfil com o último servidor e nome (vazio desabilita)")
+	c.TLS.RegistrarFlags(fs)
+}
+
+// carregarConfig monta a configuração: padrão < perfil < ambiente < flags
+func carregarConfig(args []string) (configCliente, error) {
+	var cfg configCliente
+	fs := flag.NewFlagSet("cliente", flag.ExitOnError)
+	cfg.registrarFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	// Guarda as flags informadas explicitamente para reaplicá-las por último
+	explicitas := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) { explicitas[f.Name] = f.Value.String() })
+
+	informadas := make(map[string]bool) // Vindas do ambiente ou da linha de comando
+	var errEnv error
+	fs.VisitAll(func(f *flag.Flag) {
+		if valor, ok := os.LookupEnv(nomeVariavelAmbiente(f.Name)); ok {
+			informadas[f.Name] = true
+			if err := fs.Set(f.Name, valor); err != nil && errEnv == nil {
+				errEnv = fmt.Errorf("variável %s: %w", nomeVariavelAmbiente(f.Name), err)
+			}
+		}
+	})
+	if errEnv != nil {
+		return cfg, errEnv
+	}
+	for nome, valor := range explicitas {
+		informadas[nome] = true
+		fs.Set(nome, valor)
+	}
+
+	// O perfil fica abaixo do ambiente e das flags: só preenche o que não foi informado
+	if !cfg.JSON {
+		if p, err := lerPerfil(cfg.Perfil); err != nil {
+			fmt.Printf("[CLIENTE] Ignorando o perfil %s: %v\n", cfg.Perfil, err)
+		} else if p != nil {
+			if p.Host != "" && !informadas["host"] {
+				cfg.Host = p.Host
+			}
+			if p.Porta != 0 && !informadas["porta"] {
+				cfg.Porta = p.Porta
+			}
+			if !informadas["nome"] {
+				cfg.Nome = p.Nome
+			}
+		}
+	}
+	cfg.nomeInformado = informadas["nome"]
+	cfg.Nome = strings.TrimSpace(cfg.Nome)
+	if cfg.Nome == "" {
+		cfg.nomeInformado = false
+	}
+	return cfg, cfg.validar()
+}
+
+// nomeVariavelAmbiente converte "tls-ca" em "JOGO_CLIENTE_TLS_CA"
+func nomeVariavelAmbiente(flag string) string {
+	return "JOGO_CLIENTE_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+func (c configCliente) validar() error {
+	var erros []error
+	if strings.TrimSpace(c.Host) == "" {
+		erros = append(erros, errors.New("host não pode ser vazio"))
+	}
+	if c.Porta < 1 || c.Porta > 65535 {
+		erros = append(erros, fmt.Errorf("porta deve estar entre 1 e 65535 (recebido %d)", c.Porta))
+	}
+	if c.Tentativas <= 0 {
+		erros = append(erros, fmt.Errorf("tentativas deve ser positivo (recebido %d)", c.Tentativas))
+	}
+	return errors.Join(erros...)
+}
+
+// endereco devolve host:porta do servidor
+func (c configCliente) endereco() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Porta))
+}
+
+/* ====================== Perfil ====================== */
+
+// O perfil fica junto dos hosts conhecidos do TOFU
+func arquivoPerfilPadrao() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".meujogo", "perfil.json")
+}
+
+// lerPerfil devolve nil (sem erro) quando o perfil está desabilitado ou ainda não existe
+func lerPerfil(arquivo string) (*perfilCliente, error) {
+	if arquivo == "" {
+		return nil, nil
+	}
+	conteudo, err := os.ReadFile(arquivo)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p perfilCliente
+	if err := json.Unmarshal(conteudo, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Lembra o servidor e o nome usados na última conexão
+func salvarPerfil(arquivo string, p perfilCliente) error {
+	if arquivo == "" {
+		return nil
+	}
+	conteudo, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(arquivo), 0o700); err != nil {
+		return err
+	}
+	// Grava em um arquivo temporário e renomeia, para nunca deixar um perfil pela metade
+	tmp := arquivo + ".tmp"
+	if err := os.WriteFile(tmp, append(conteudo, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, arquivo)
+}
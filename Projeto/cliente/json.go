@@ -0,0 +1,102 @@
+package main
+
+// ===================== BAREMA ITEM 3: API REMOTA =====================
+// Modo sem interface (flag -json), para scripts, CI e bots em qualquer linguagem.
+// Cada linha da entrada padrão é um protocolo.Mensagem em JSON, enviado ao
+// servidor sem alterações; cada mensagem do servidor, inclusive os PINGs (que
+// o cliente já responde sozinho), sai na saída padrão como uma linha JSON.
+// A saída padrão só contém mensagens do servidor: avisos do próprio cliente
+// vão para a saída de erro. O fim da entrada encerra a sessão com QUIT.
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"meujogo/clientesdk"
+	"meujogo/protocolo"
+	"os"
+	"strings"
+	"time"
+)
+
+// Tamanho máximo de uma linha de comando (o servidor ainda aplica o próprio limite)
+const maxLinhaJSON = 64 << 10
+
+// BAREMA ITEM 3: API REMOTA - Executa o modo -json e devolve o código de saída do processo
+func executarJSON(cfg configCliente) int {
+	// O LOGIN e a fila automáticos só acontecem se o nome foi informado;
+	// sem ele o script envia o próprio LOGIN
+	nome := ""
+	if cfg.nomeInformado {
+		nome = cfg.Nome
+	}
+	cliente, err := clientesdk.Conectar(context.Background(), clientesdk.Opcoes{
+		Endereco:          cfg.endereco(),
+		TLS:               cfg.TLS,
+		Timeout:           10 * time.Second,
+		Nome:              nome,
+		Senha:             cfg.Senha,
+		TentativasConexao: cfg.Tentativas,
+		Reconectar:        cfg.Reconectar,
+		EntregarPings:     true,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[CLIENTE] Não foi possível conectar a %s: %v\n", cfg.endereco(), err)
+		return 1
+	}
+	if nome != "" && cfg.Fila {
+		cliente.EntrarNaFila()
+	}
+
+	go lerComandosJSON(cliente)
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Uma linha por mensagem, escrita assim que chega
+	saida := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(saida)
+	for ev := range cliente.Eventos() {
+		switch ev.Comando {
+		case clientesdk.EventoDesconectado:
+			fmt.Fprintf(os.Stderr, "[CLIENTE] Conexão com o servidor foi perdida: %v\n", ev.Erro)
+			continue
+		case clientesdk.EventoReconectado:
+			fmt.Fprintln(os.Stderr, "[CLIENTE] Reconectado ao servidor.")
+			if nome != "" && cfg.Fila {
+				cliente.EntrarNaFila()
+			}
+			continue
+		}
+		if err := enc.Encode(ev.Bruto); err != nil {
+			return 1 // Saída fechada (ex.: o programa que lia terminou)
+		}
+		if err := saida.Flush(); err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+// lerComandosJSON envia cada linha da entrada como uma mensagem do protocolo
+// e fecha o cliente no fim da entrada (o que encerra o laço de eventos)
+func lerComandosJSON(cliente *clientesdk.Cliente) {
+	defer cliente.Fechar()
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 4096), maxLinhaJSON)
+	for n := 1; scanner.Scan(); n++ {
+		linha := strings.TrimSpace(scanner.Text())
+		if linha == "" {
+			continue
+		}
+		var msg protocolo.Mensagem
+		if err := json.Unmarshal([]byte(linha), &msg); err != nil || msg.Comando == "" {
+			fmt.Fprintf(os.Stderr, "[CLIENTE] Linha %d ignorada: esperado {\"comando\": ..., \"dados\": ...}\n", n)
+			continue
+		}
+		if err := cliente.EnviarMensagem(msg); err != nil {
+			fmt.Fprintf(os.Stderr, "[CLIENTE] Linha %d não enviada: %v\n", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "[CLIENTE] Erro lendo a entrada: %v\n", err)
+	}
+}
@@ -7,68 +7,92 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"meujogo/clientesdk"
 	"meujogo/protocolo"
-	"net"
 	"os"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // BAREMA ITEM 1: ARQUITETURA - Variáveis globais do cliente
-var meuNome string                  // Nome do jogador atual
-var meuInventario []protocolo.Carta // Cartas que o jogador possui
+var meuNome string // Nome do jogador atual
+
+// BAREMA ITEM 6: LATÊNCIA - Intervalo dos PINGs automáticos do modo interativo
+const intervaloPing = 5 * time.Second
 
 // BAREMA ITEM 1: ARQUITETURA - Exibe a interface de ajuda para o usuário
 // Mostra todos os comandos disponíveis e suas funcionalidades
 func printAjuda() {
 	fmt.Println("\n------------ COMANDOS ---------------")
+	fmt.Println("/fila       - Entra na fila de pareamento (automático, salvo com -fila=false).")
 	fmt.Println("/comprar    - Compra um pacote de cartas para (re)iniciar a partida.")
-	fmt.Println("/jogar <ID> - Joga uma carta da sua mão usando o ID dela.")
+	fmt.Println("/jogar <N>  - Joga a N-ésima carta da sua mão (ou informe o nome da carta).")
 	fmt.Println("/cartas     - Mostra as cartas que você tem na mão.")
-	fmt.Println("/ping       - Mede sua latência (atraso) com o servidor.")
+	fmt.Println("/ping       - Mede sua latência (atraso) com o servidor e mostra média e jitter.")
 	fmt.Println("/sair       - Abandona a partida atual e volta para a fila.")
-	fmt.Println("Qualquer outra coisa que você digitar será enviada como chat.")
+	fmt.Println("/canal <C>  - Entra no canal C do lobby (ex.: global, troca) e mostra o histórico.")
+	fmt.Println("/deixar <C> - Sai do canal C.")
+	fmt.Println("/c <C> <T>  - Envia o texto T ao canal C, mesmo durante a partida.")
+	fmt.Println("/amigo <N>  - Adiciona N como amigo (ou aceita o pedido de N).")
+	fmt.Println("/desamigo <N> - Remove N da sua lista de amigos.")
+	fmt.Println("/amigos     - Mostra seus amigos, se estão online, e os pedidos pendentes.")
+	fmt.Println("/msg <N> <T> - Envia o texto T só para o amigo N (guardado se ele estiver offline).")
+	fmt.Println("/desafiar <N> - Convida o amigo N para uma partida privada.")
+	fmt.Println("/aceitar <N> | /recusar <N> - Responde ao desafio de N.")
+	fmt.Println("/bloquear <N> | /desbloquear <N> - Deixa (ou volta) a receber chat, mensagens e pedidos de N.")
+	fmt.Println("/denunciar <N> [motivo] - Envia N e suas últimas mensagens de chat à moderação.")
+	fmt.Println("Qualquer outra coisa que você digitar será enviada como chat (à sala ou, no lobby, ao último canal).")
 	fmt.Println("-------------------------------------")
 	fmt.Print("> ")
 }
 
 // BAREMA ITEM 2: COMUNICAÇÃO - Processa mensagens recebidas do servidor
 // Roda em uma goroutine separada para não bloquear a interface do usuário
-func handleServerMessages(conn net.Conn) {
-	decoder := json.NewDecoder(conn)
-	for {
-		var msg protocolo.Mensagem
-		if err := decoder.Decode(&msg); err != nil {
-			fmt.Println("\n[CLIENTE] Conexão com o servidor foi perdida.")
-			os.Exit(0)
-		}
-
+func handleServerMessages(cliente *clientesdk.Cliente, cfg configCliente) {
+	var latencias map[string]protocolo.ResumoLatencia // Último LATENCIA da partida atual
+	for ev := range cliente.Eventos() {
 		// BAREMA ITEM 3: API REMOTA - Processa diferentes tipos de mensagens do servidor
-		switch msg.Comando {
+		switch ev.Comando {
 
 		// BAREMA ITEM 6: LATÊNCIA - Processa resposta de ping para medir latência
 		case "PONG":
-			var dadosPong protocolo.DadosPong
-			if err := json.Unmarshal(msg.Dados, &dadosPong); err == nil {
-				latencia := time.Now().UnixMilli() - dadosPong.Timestamp
-				fmt.Printf("\r[SISTEMA] Sua latência com o servidor é de %dms.\n> ", latencia)
+			fmt.Printf("\r[SISTEMA] %s\n> ", descreverMinhaLatencia(cliente.Latencia()))
+
+		// BAREMA ITEM 6: LATÊNCIA - Latência dos jogadores da sala, medida pelo servidor
+		case "LATENCIA":
+			if dados, ok := ev.Dados.(protocolo.DadosLatencia); ok {
+				latencias = dados.Jogadores
+			}
+
+		// BAREMA ITEM 6: LATÊNCIA - O oponente parou (ou voltou a) responder ao servidor
+		case "ATRASO_JOGADOR":
+			if dados, ok := ev.Dados.(protocolo.DadosAtraso); ok {
+				fmt.Printf("\r[SISTEMA] %s\n> ", descreverAtraso(dados))
 			}
 
 		// BAREMA ITEM 7: PARTIDAS - Notifica que uma partida foi encontrada
 		case "PARTIDA_ENCONTRADA":
-			var dados protocolo.DadosPartidaEncontrada
-			if err := json.Unmarshal(msg.Dados, &dados); err == nil {
+			if dados, ok := ev.Dados.(protocolo.DadosPartidaEncontrada); ok {
 				fmt.Printf("\r[SISTEMA] Partida encontrada! Seu oponente é: %s.\n", dados.OponenteNome)
+				minhaMao.limpar() // Cada partida começa com os pacotes comprados para ela
+				latencias = nil
 				printAjuda()
 			}
 
 		// BAREMA ITEM 3: API REMOTA - Atualiza o estado do jogo na interface
 		case "ATUALIZACAO_JOGO":
-			var dados protocolo.DadosAtualizacaoJogo
-			if err := json.Unmarshal(msg.Dados, &dados); err == nil {
-				fmt.Printf("\r--- Rodada %d ---\n", dados.NumeroRodada)
+			if dados, ok := ev.Dados.(protocolo.DadosAtualizacaoJogo); ok {
+				cabecalho := fmt.Sprintf("--- Rodada %d ---", dados.NumeroRodada)
+				if l := latenciaOponentes(latencias); l != "" {
+					cabecalho += " (" + l + ")"
+				}
+				fmt.Printf("\r%s\n", cabecalho)
 				fmt.Println(dados.MensagemDoTurno)
 
 				// Exibe cartas jogadas na mesa
@@ -94,113 +118,187 @@ func handleServerMessages(conn net.Conn) {
 				for nome, contagem := range dados.ContagemCartas {
 					fmt.Printf("  %s: %d cartas\n", nome, contagem)
 				}
+
+				// Reconcilia e mostra a mão, numerada para o /jogar
+				minhaMao.sincronizar(dados)
+				if mao := minhaMao.lista(); len(mao) > 0 {
+					fmt.Printf("\nSua mão: %s\n", descreverMao(mao))
+				}
 				fmt.Println("-------------------")
 				fmt.Print("> ")
 			}
 
 		// BAREMA ITEM 7: PARTIDAS - Notifica fim da partida
 		case "FIM_DE_JOGO":
-			var dados protocolo.DadosFimDeJogo
-			if err := json.Unmarshal(msg.Dados, &dados); err == nil {
+			if dados, ok := ev.Dados.(protocolo.DadosFimDeJogo); ok {
 				if dados.VencedorNome == "EMPATE" {
 					fmt.Printf("\n=== FIM DE JOGO — EMPATE ===\n")
 				} else {
 					fmt.Printf("\n=== FIM DE JOGO — VENCEDOR DA PARTIDA: %s ===\n", dados.VencedorNome)
 				}
+				minhaMao.limpar() // O servidor descarta as cartas ao fim da partida
 				// Volta ao lobby: pode comprar pacotes e reiniciar
 				printAjuda()
 			}
 
 		// BAREMA ITEM 8: PACOTES - Processa resultado da compra de pacote
 		case "PACOTE_RESULTADO":
-			var r protocolo.ComprarPacoteResp
-			if err := json.Unmarshal(msg.Dados, &r); err == nil {
-				// Limpa o inventário antigo ao comprar um novo pacote
-				meuInventario = r.Cartas
-
-				// Exibe as cartas recebidas de forma organizada
-				n := make([]string, 0, len(r.Cartas))
-				for _, c := range r.Cartas {
-					n = append(n, fmt.Sprintf("%s (ID: %s, Poder: %d)", c.Nome, c.ID, c.Valor))
-				}
-				fmt.Printf("\n[PACOTE] Você recebeu: %s\n", strings.Join(n, ", "))
+			if r, ok := ev.Dados.(protocolo.ComprarPacoteResp); ok {
+				minhaMao.receberPacote(r.Cartas)
+
+				// Exibe a mão numerada, já com as cartas recebidas
+				fmt.Printf("\n[PACOTE] Você recebeu %d cartas. Sua mão: %s\n", len(r.Cartas), descreverMao(minhaMao.lista()))
 				fmt.Print("> ")
 			}
 
-		case "CARTAS_DETALHADAS":
-			var e protocolo.DadosErro
-			if err := json.Unmarshal(msg.Dados, &e); err == nil {
+		case "CARTAS_DETALHADAS", "SISTEMA":
+			if e, ok := ev.Dados.(protocolo.DadosErro); ok {
 				fmt.Printf("\r%s\n> ", e.Mensagem)
 			}
 
-		case "SISTEMA":
-			var e protocolo.DadosErro
-			if err := json.Unmarshal(msg.Dados, &e); err == nil {
-				fmt.Printf("\r%s\n> ", e.Mensagem)
+		case "RECEBER_CHAT":
+			if dadosChat, ok := ev.Dados.(protocolo.DadosReceberChat); ok {
+				fmt.Printf("\r%s\n> ", linhaChat(dadosChat))
 			}
 
-		case "RECEBER_CHAT":
-			var dadosChat protocolo.DadosReceberChat
-			if err := json.Unmarshal(msg.Dados, &dadosChat); err == nil {
-				prefix := dadosChat.NomeJogador
-				if dadosChat.NomeJogador == meuNome {
-					prefix = "[VOCÊ]"
+		// BAREMA ITEM 2: COMUNICAÇÃO - Entrada em um canal do lobby, com as últimas mensagens
+		case "HISTORICO_CANAL":
+			if dados, ok := ev.Dados.(protocolo.DadosHistoricoCanal); ok {
+				fmt.Printf("\r[SISTEMA] %s\n", descreverEntradaCanal(dados))
+				for _, m := range dados.Mensagens {
+					fmt.Println(linhaChat(m))
 				}
-				fmt.Printf("\r%s: %s\n> ", prefix, dadosChat.Texto)
+				fmt.Print("> ")
+			}
+
+		// BAREMA ITEM 2: COMUNICAÇÃO - Amigos, presença e mensagens privadas
+		case "LISTA_AMIGOS":
+			if dados, ok := ev.Dados.(protocolo.DadosListaAmigos); ok {
+				fmt.Printf("\r[AMIGOS] %s\n> ", descreverAmigos(dados))
+			}
+
+		case "PRESENCA_AMIGO":
+			if dados, ok := ev.Dados.(protocolo.DadosPresenca); ok {
+				fmt.Printf("\r[AMIGOS] %s\n> ", descreverPresenca(dados))
+			}
+
+		case "MENSAGEM_PRIVADA":
+			if dados, ok := ev.Dados.(protocolo.DadosMensagemPrivada); ok {
+				fmt.Printf("\r%s\n> ", linhaPrivada(dados))
 			}
 
-		case "PING":
-			var dadosPing protocolo.DadosPing
-			if err := json.Unmarshal(msg.Dados, &dadosPing); err == nil {
-				// Responde ao ping do servidor para manter a conexão ativa
-				encoder := json.NewEncoder(conn)
-				_ = encoder.Encode(protocolo.Mensagem{
-					Comando: "PONG",
-					Dados:   mustJSON(protocolo.DadosPong{Timestamp: dadosPing.Timestamp}),
-				})
+		case "DESAFIO":
+			if dados, ok := ev.Dados.(protocolo.DadosAmigo); ok {
+				fmt.Printf("\r[DESAFIO] %s\n> ", descreverDesafio(dados))
 			}
 
 		case "ERRO":
-			var e protocolo.DadosErro
-			if err := json.Unmarshal(msg.Dados, &e); err == nil {
+			if e, ok := ev.Dados.(protocolo.DadosErro); ok {
 				fmt.Printf("\n[ERRO] %s\n> ", e.Mensagem)
 			}
+
+		// BAREMA ITEM 2: COMUNICAÇÃO - Queda e retomada da conexão (com -reconectar)
+		case clientesdk.EventoDesconectado:
+			fmt.Println("\n[CLIENTE] Conexão com o servidor foi perdida.")
+			if cfg.Reconectar {
+				fmt.Print("[CLIENTE] Tentando reconectar...\n> ")
+			}
+
+		case clientesdk.EventoReconectado:
+			// A sessão nova não tem sala nem cartas: a partida anterior foi perdida
+			minhaMao.limpar()
+			fmt.Println("\r[CLIENTE] Reconectado ao servidor.")
+			voltarAFila(cliente, cfg)
+			fmt.Print("> ")
 		}
 	}
+	fmt.Println("\n[CLIENTE] Conexão com o servidor foi encerrada.")
+	os.Exit(0)
+}
+
+// voltarAFila entra na fila se o pareamento automático estiver ligado
+func voltarAFila(cliente *clientesdk.Cliente, cfg configCliente) {
+	if cfg.Fila {
+		cliente.EntrarNaFila()
+		return
+	}
+	fmt.Println("[SISTEMA] Use /fila para procurar um oponente.")
 }
 
 // BAREMA ITEM 2: COMUNICAÇÃO - Função principal do cliente
 // Inicializa conexão com o servidor e gerencia interface do usuário
 func main() {
+	// BAREMA ITEM 1: ARQUITETURA - Servidor, nome, TLS e comportamento da conexão
+	// (flags, variáveis JOGO_CLIENTE_* ou perfil salvo)
+	cfg, err := carregarConfig(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Configuração inválida: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.JSON {
+		os.Exit(executarJSON(cfg))
+	}
+
 	fmt.Println("--- Jogo de Cartas Multiplayer ---")
 	scanner := bufio.NewScanner(os.Stdin)
 
-	// BAREMA ITEM 1: ARQUITETURA - Solicita nome do usuário
-	fmt.Print("Digite seu nome de usuário: ")
-	scanner.Scan()
-	meuNome = scanner.Text()
-	if strings.TrimSpace(meuNome) == "" {
+	// BAREMA ITEM 1: ARQUITETURA - Solicita nome do usuário (o do perfil é sugerido)
+	meuNome = cfg.Nome
+	if !cfg.nomeInformado {
+		if cfg.Nome != "" {
+			fmt.Printf("Digite seu nome de usuário [%s]: ", cfg.Nome)
+		} else {
+			fmt.Print("Digite seu nome de usuário: ")
+		}
+		scanner.Scan()
+		if digitado := strings.TrimSpace(scanner.Text()); digitado != "" {
+			meuNome = digitado
+		}
+	}
+	if meuNome == "" {
 		meuNome = "Jogador" // Nome padrão se não informado
 	}
 
-	// BAREMA ITEM 2: COMUNICAÇÃO - Conecta com o servidor
-	// Ajuste o host conforme seu cenário (ex.: "127.0.0.1:65432")
-	conn, err := net.Dial("tcp", "servidor:65432")
+	// BAREMA ITEM 2: COMUNICAÇÃO - Conecta com o servidor (tentando de novo com
+	// espera crescente) e faz o LOGIN
+	fmt.Printf("Conectando a %s...\n", cfg.endereco())
+	cliente, err := clientesdk.Conectar(context.Background(), clientesdk.Opcoes{
+		Endereco:          cfg.endereco(),
+		TLS:               cfg.TLS,
+		Timeout:           10 * time.Second,
+		Nome:              meuNome,
+		Senha:             cfg.Senha,
+		TentativasConexao: cfg.Tentativas,
+		Reconectar:        cfg.Reconectar,
+		IntervaloPing:     intervaloPing,
+	})
 	if err != nil {
 		fmt.Printf("Não foi possível conectar: %s\n", err)
 		return
 	}
-	defer conn.Close()
-	fmt.Printf("Conectado como '%s'. Aguardando pareamento...\n", meuNome)
+	defer cliente.Fechar()
+	if err := salvarPerfil(cfg.Perfil, perfilCliente{Host: cfg.Host, Porta: cfg.Porta, Nome: meuNome}); err != nil {
+		fmt.Printf("[CLIENTE] Não foi possível salvar o perfil: %v\n", err)
+	}
+	fmt.Printf("Conectado como '%s'.\n", meuNome)
 
-	encoder := json.NewEncoder(conn)
+	// BAREMA ITEM 3: API REMOTA - Entrada na fila automática
+	if cfg.Fila {
+		fmt.Println("Aguardando pareamento...")
+	}
+	voltarAFila(cliente, cfg)
 
-	// BAREMA ITEM 3: API REMOTA - Login e entrada na fila automática
-	_ = encoder.Encode(protocolo.Mensagem{Comando: "LOGIN", Dados: mustJSON(protocolo.DadosLogin{Nome: meuNome})})
-	_ = encoder.Encode(protocolo.Mensagem{Comando: "ENTRAR_NA_FILA"})
+	if cfg.TUI {
+		motivo, err := executarTUI(cliente, cfg)
+		if err == nil {
+			fmt.Println(motivo)
+			return
+		}
+		fmt.Printf("[CLIENTE] Interface em tela cheia indisponível (%v); usando o modo de linhas.\n", err)
+	}
 
 	// BAREMA ITEM 2: COMUNICAÇÃO - Inicia goroutine para processar mensagens do servidor
-	go handleServerMessages(conn)
+	go handleServerMessages(cliente, cfg)
 
 	// BAREMA ITEM 1: ARQUITETURA - Loop principal de interface do usuário
 	printAjuda()
@@ -213,48 +311,104 @@ func main() {
 		}
 
 		comando := partes[0]
-		var msg protocolo.Mensagem
+		var err error
 
 		// BAREMA ITEM 3: API REMOTA - Processa comandos do usuário
 		switch comando {
+		case "/fila":
+			err = cliente.EntrarNaFila()
+
 		case "/comprar":
-			msg = protocolo.Mensagem{
-				Comando: "COMPRAR_PACOTE",
-				Dados:   mustJSON(protocolo.ComprarPacoteReq{Quantidade: 1}),
-			}
+			err = cliente.ComprarPacote(1)
 
 		case "/jogar":
 			if len(partes) < 2 {
-				fmt.Println("[SISTEMA] Uso: /jogar <ID_da_carta>")
+				fmt.Println("[SISTEMA] Uso: /jogar <posição|nome da carta>")
 				fmt.Print("> ")
 				continue
 			}
-			cartaID := partes[1]
-			msg = protocolo.Mensagem{
-				Comando: "JOGAR_CARTA",
-				Dados:   mustJSON(protocolo.DadosJogarCarta{CartaID: cartaID}),
+			// BAREMA ITEM 4: ENCAPSULAMENTO - Jogadas inválidas são barradas antes do envio
+			carta, errCarta := minhaMao.escolher(strings.Join(partes[1:], " "))
+			if errCarta != nil {
+				fmt.Printf("[SISTEMA] Jogada recusada: %s.\n> ", errCarta)
+				continue
+			}
+			if err = cliente.JogarCarta(carta.ID); err == nil {
+				minhaMao.retirar(carta.ID)
 			}
 
 		case "/cartas":
-			msg = protocolo.Mensagem{Comando: "VER_CARTAS"}
+			err = cliente.VerCartas()
 
 		case "/ping":
-			// BAREMA ITEM 6: LATÊNCIA - Usa ICMP para medição mais precisa de latência
-			medirLatenciaICMP()
+			// BAREMA ITEM 6: LATÊNCIA - PING/PONG pelo próprio protocolo; a resposta é exibida ao chegar
+			err = cliente.Ping()
 
 		case "/sair":
-			msg = protocolo.Mensagem{Comando: "SAIR_DA_SALA"}
+			err = cliente.SairDaSala()
+			minhaMao.limpar()
 			fmt.Println("[SISTEMA] Você saiu da sala. Aguardando novo oponente...")
 
+		// BAREMA ITEM 2: COMUNICAÇÃO - Canais de chat do lobby
+		case "/canal", "/deixar":
+			if len(partes) != 2 {
+				fmt.Printf("[SISTEMA] Uso: %s <canal>\n> ", comando)
+				continue
+			}
+			if comando == "/canal" {
+				err = cliente.EntrarCanal(partes[1])
+			} else {
+				err = cliente.SairCanal(partes[1])
+			}
+
+		case "/c":
+			if len(partes) < 3 {
+				fmt.Print("[SISTEMA] Uso: /c <canal> <texto>\n> ")
+				continue
+			}
+			texto := strings.Join(partes[2:], " ")
+			if err = cliente.ChatCanal(partes[1], texto); err == nil {
+				fmt.Println(linhaChat(protocolo.DadosReceberChat{NomeJogador: meuNome, Texto: texto, Canal: partes[1]}))
+			}
+
+		// BAREMA ITEM 2: COMUNICAÇÃO - Amigos, mensagens privadas, desafios e bloqueios
+		case "/amigo", "/desamigo", "/desafiar", "/aceitar", "/recusar", "/bloquear", "/desbloquear":
+			if len(partes) < 2 {
+				fmt.Printf("[SISTEMA] Uso: %s <nome>\n> ", comando)
+				continue
+			}
+			err = comandoAmigo(cliente, comando, strings.Join(partes[1:], " "))
+
+		case "/amigos":
+			err = cliente.ListarAmigos()
+
+		case "/msg":
+			if len(partes) < 3 {
+				fmt.Print("[SISTEMA] Uso: /msg <nome> <texto>\n> ")
+				continue
+			}
+			texto := strings.Join(partes[2:], " ")
+			if err = cliente.MensagemPrivada(partes[1], texto); err == nil {
+				fmt.Println(linhaPrivadaEnviada(partes[1], texto))
+			}
+
+		case "/denunciar", "/report":
+			if len(partes) < 2 {
+				fmt.Printf("[SISTEMA] Uso: %s <nome> [motivo]\n> ", comando)
+				continue
+			}
+			err = cliente.Denunciar(partes[1], strings.Join(partes[2:], " "))
+
 		default:
-			// qualquer texto que não seja comando vira chat
-			msg = protocolo.Mensagem{
-				Comando: "ENVIAR_CHAT",
-				Dados:   mustJSON(protocolo.DadosEnviarChat{Texto: entrada}),
+			// qualquer texto que não seja comando vira chat; o servidor não o devolve ao autor
+			if err = cliente.Chat(entrada); err == nil {
+				fmt.Println(linhaChat(protocolo.DadosReceberChat{NomeJogador: meuNome, Texto: entrada}))
 			}
 		}
 
-		if err := encoder.Encode(msg); err != nil {
+		if errors.Is(err, clientesdk.ErrDesconectado) && cfg.Reconectar {
+			fmt.Println("[CLIENTE] Sem conexão no momento; aguarde a reconexão.")
+		} else if err != nil {
 			fmt.Println("[CLIENTE] Falha ao enviar mensagem para o servidor.")
 			return
 		}
@@ -262,82 +416,134 @@ func main() {
 	}
 }
 
-// BAREMA ITEM 6: LATÊNCIA - Mede latência usando ICMP para máxima precisão
-func medirLatenciaICMP() {
-	// BAREMA ITEM 2: COMUNICAÇÃO - Cria socket ICMP raw
-	conn, err := net.Dial("ip4:icmp", "servidor")
-	if err != nil {
-		fmt.Printf("[ERRO] Não foi possível conectar via ICMP: %v\n", err)
-		return
+// BAREMA ITEM 6: LATÊNCIA - Resume a latência medida pelo próprio cliente
+func descreverMinhaLatencia(r protocolo.ResumoLatencia) string {
+	return fmt.Sprintf("Sua latência com o servidor é de %.0fms (média %.0fms, jitter %.0fms, %d amostras).",
+		r.UltimaMs, r.MediaMs, r.JitterMs, r.Amostras)
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Média de cada oponente, como "Bia: 45ms"
+func latenciaOponentes(latencias map[string]protocolo.ResumoLatencia) string {
+	var partes []string
+	for nome, r := range latencias {
+		if nome != meuNome {
+			partes = append(partes, fmt.Sprintf("%s: %.0fms", nome, r.MediaMs))
+		}
 	}
-	defer conn.Close()
+	sort.Strings(partes)
+	return strings.Join(partes, ", ")
+}
 
-	// BAREMA ITEM 6: LATÊNCIA - Cria pacote ICMP Echo Request
-	icmpPacket := createICMPEchoRequest()
+// BAREMA ITEM 6: LATÊNCIA - Texto do aviso de atraso de um jogador
+func descreverAtraso(d protocolo.DadosAtraso) string {
+	if !d.Atrasado {
+		return fmt.Sprintf("%s voltou a responder.", d.Jogador)
+	}
+	return fmt.Sprintf("%s está sem responder ao servidor há %ds; aguarde ou use /sair.", d.Jogador, d.SemRespostaMs/1000)
+}
 
-	start := time.Now()
-	_, err = conn.Write(icmpPacket)
-	if err != nil {
-		fmt.Printf("[ERRO] Falha ao enviar ping ICMP: %v\n", err)
-		return
+// BAREMA ITEM 2: COMUNICAÇÃO - Linha de chat, com o canal quando veio do lobby
+func linhaChat(d protocolo.DadosReceberChat) string {
+	nome := d.NomeJogador
+	if nome == meuNome {
+		nome = "[VOCÊ]"
 	}
+	if d.Canal != "" {
+		return semControles(fmt.Sprintf("[#%s] %s: %s", d.Canal, nome, d.Texto))
+	}
+	return semControles(nome + ": " + d.Texto)
+}
 
-	// BAREMA ITEM 6: LATÊNCIA - Configura timeout para resposta
-	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+// semControles troca caracteres de controle (ESC, \r, \b...) por '�', para que o
+// texto de outro jogador não mova o cursor nem apague o terminal
+func semControles(texto string) string {
+	if !strings.ContainsFunc(texto, unicode.IsControl) {
+		return texto
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return utf8.RuneError
+		}
+		return r
+	}, texto)
+}
 
-	// BAREMA ITEM 6: LATÊNCIA - Aguarda resposta
-	buffer := make([]byte, 1024)
-	_, err = conn.Read(buffer)
-	if err != nil {
-		fmt.Printf("[ERRO] Timeout ou erro ao receber resposta ICMP: %v\n", err)
-		return
+// descreverEntradaCanal resume o HISTORICO_CANAL recebido ao entrar em um canal
+func descreverEntradaCanal(d protocolo.DadosHistoricoCanal) string {
+	return fmt.Sprintf("Você entrou no canal #%s (%d conectados, %d mensagens recentes). Use /c %s <texto> para falar nele.",
+		d.Canal, d.Membros, len(d.Mensagens), d.Canal)
+}
+
+// comandoAmigo envia os comandos que recebem só o nome de outro jogador
+func comandoAmigo(cliente *clientesdk.Cliente, comando, nome string) error {
+	switch comando {
+	case "/amigo":
+		return cliente.AdicionarAmigo(nome)
+	case "/desamigo":
+		return cliente.RemoverAmigo(nome)
+	case "/desafiar":
+		return cliente.Desafiar(nome)
+	case "/aceitar":
+		return cliente.AceitarDesafio(nome)
+	case "/bloquear":
+		return cliente.Bloquear(nome)
+	case "/desbloquear":
+		return cliente.Desbloquear(nome)
+	default:
+		return cliente.RecusarDesafio(nome)
 	}
+}
 
-	// BAREMA ITEM 6: LATÊNCIA - Calcula latência total
-	latencia := time.Since(start)
-	fmt.Printf("\r[SISTEMA] Sua latência ICMP com o servidor é de %.2fms.\n> ", float64(latencia.Nanoseconds())/1e6)
+// Como cada presença aparece para o jogador
+var textoPresenca = map[string]string{
+	protocolo.PresencaOffline:  "offline",
+	protocolo.PresencaOnline:   "online",
+	protocolo.PresencaFila:     "na fila",
+	protocolo.PresencaPartida:  "em partida",
+	protocolo.PresencaPendente: "aguardando aceitar seu pedido",
 }
 
-// BAREMA ITEM 6: LATÊNCIA - Cria pacote ICMP Echo Request
-func createICMPEchoRequest() []byte {
-	// BAREMA ITEM 6: LATÊNCIA - Estrutura do pacote ICMP
-	packet := make([]byte, 8)
-	packet[0] = 8 // Tipo: Echo Request
-	packet[1] = 0 // Código: 0
-	packet[2] = 0 // Checksum (será calculado)
-	packet[3] = 0 // Checksum (será calculado)
-	packet[4] = 0 // Identifier (16 bits)
-	packet[5] = 1 // Identifier (16 bits)
-	packet[6] = 0 // Sequence Number (16 bits)
-	packet[7] = 1 // Sequence Number (16 bits)
-
-	// BAREMA ITEM 6: LATÊNCIA - Calcula checksum
-	checksum := calculateICMPChecksum(packet)
-	packet[2] = byte(checksum >> 8)
-	packet[3] = byte(checksum & 0xFF)
-
-	return packet
+// BAREMA ITEM 2: COMUNICAÇÃO - Resume a LISTA_AMIGOS, como "Bia (online), Caio (em partida)"
+func descreverAmigos(d protocolo.DadosListaAmigos) string {
+	var partes []string
+	for _, a := range d.Amigos {
+		partes = append(partes, fmt.Sprintf("%s (%s)", a.Nome, textoPresenca[a.Presenca]))
+	}
+	texto := "Você ainda não tem amigos. Use /amigo <nome> para adicionar."
+	if len(partes) > 0 {
+		texto = "Amigos: " + strings.Join(partes, ", ") + "."
+	}
+	if len(d.Pedidos) > 0 {
+		texto += fmt.Sprintf(" Pedidos de amizade: %s (use /amigo <nome> para aceitar).", strings.Join(d.Pedidos, ", "))
+	}
+	if len(d.Bloqueados) > 0 {
+		texto += fmt.Sprintf(" Bloqueados: %s (use /desbloquear <nome> para liberar).", strings.Join(d.Bloqueados, ", "))
+	}
+	return texto
 }
 
-// BAREMA ITEM 6: LATÊNCIA - Calcula checksum ICMP
-func calculateICMPChecksum(data []byte) uint16 {
-	var sum uint32
-	for i := 0; i < len(data); i += 2 {
-		if i+1 < len(data) {
-			sum += uint32(data[i])<<8 + uint32(data[i+1])
-		} else {
-			sum += uint32(data[i]) << 8
-		}
+// descreverPresenca anuncia a mudança de presença de um amigo
+func descreverPresenca(d protocolo.DadosPresenca) string {
+	if d.Presenca == protocolo.PresencaOffline {
+		return d.Nome + " ficou offline."
 	}
+	return fmt.Sprintf("%s está %s.", d.Nome, textoPresenca[d.Presenca])
+}
 
-	for sum>>16 != 0 {
-		sum = (sum & 0xFFFF) + (sum >> 16)
+// BAREMA ITEM 2: COMUNICAÇÃO - Mensagem privada recebida; as guardadas mostram o horário de envio
+func linhaPrivada(d protocolo.DadosMensagemPrivada) string {
+	if d.Guardada {
+		return semControles(fmt.Sprintf("[privada de %s, %s] %s", d.De, time.UnixMilli(d.EnviadaEm).Format("02/01 15:04"), d.Texto))
 	}
+	return semControles(fmt.Sprintf("[privada de %s] %s", d.De, d.Texto))
+}
 
-	return uint16(^sum)
+// linhaPrivadaEnviada é o eco local de /msg (o servidor não devolve a mensagem)
+func linhaPrivadaEnviada(para, texto string) string {
+	return fmt.Sprintf("[privada para %s] %s", para, texto)
 }
 
-func mustJSON(v any) []byte {
-	b, _ := json.Marshal(v)
-	return b
+// descreverDesafio explica como responder a um desafio recebido
+func descreverDesafio(d protocolo.DadosAmigo) string {
+	return fmt.Sprintf("%s desafiou você para uma partida privada. Use /aceitar %s ou /recusar %s.", d.Nome, d.Nome, d.Nome)
 }
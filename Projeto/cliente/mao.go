@@ -0,0 +1,149 @@
+package main
+
+// ===================== BAREMA ITEM 4: ENCAPSULAMENTO =====================
+// Mão do jogador mantida localmente.
+// Os pacotes comprados entram na mão, as jogadas saem dela na hora e cada
+// ATUALIZACAO_JOGO traz a mão vista pelo servidor, que prevalece sobre a
+// cópia local. Com ela o jogador escolhe a carta pela posição ou pelo nome
+// (em vez do ID opaco do servidor) e jogadas inválidas são barradas antes
+// de chegar ao servidor.
+
+import (
+	"errors"
+	"fmt"
+	"meujogo/protocolo"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BAREMA ITEM 5: CONCORRÊNCIA - No modo de linhas a mão é alterada pela goroutine
+// das mensagens do servidor e lida pelo laço de comandos
+type maoJogador struct {
+	mutex   sync.Mutex
+	cartas  []protocolo.Carta
+	jogando bool // Há partida em andamento (só então o servidor aceita jogadas)
+	jogou   bool // Já há carta nossa na mesa nesta jogada
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Mão do jogador atual
+var minhaMao maoJogador
+
+// receberPacote acrescenta as cartas de um pacote comprado
+func (m *maoJogador) receberPacote(cartas []protocolo.Carta) {
+	m.mutex.Lock()
+	m.cartas = append(m.cartas, cartas...)
+	m.mutex.Unlock()
+}
+
+// BAREMA ITEM 3: API REMOTA - Reconcilia com o estado enviado pelo servidor
+func (m *maoJogador) sincronizar(dados protocolo.DadosAtualizacaoJogo) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.jogando = true
+	_, m.jogou = dados.UltimaJogada[meuNome]
+	if dados.MinhaMao != nil { // Servidores antigos não enviam a mão
+		m.cartas = append(m.cartas[:0], dados.MinhaMao...)
+	}
+}
+
+// limpar esvazia a mão quando a partida acaba ou muda (o servidor descarta as cartas)
+func (m *maoJogador) limpar() {
+	m.mutex.Lock()
+	m.cartas = m.cartas[:0]
+	m.jogando, m.jogou = false, false
+	m.mutex.Unlock()
+}
+
+// retirar remove a carta jogada sem esperar o servidor; se a jogada for
+// recusada, a próxima atualização devolve a carta
+func (m *maoJogador) retirar(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i, c := range m.cartas {
+		if c.ID == id {
+			m.cartas = append(m.cartas[:i], m.cartas[i+1:]...)
+			m.jogou = true
+			return
+		}
+	}
+}
+
+// lista devolve uma cópia da mão, na ordem do servidor (a mesma de /cartas)
+func (m *maoJogador) lista() []protocolo.Carta {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]protocolo.Carta(nil), m.cartas...)
+}
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Escolhe a carta a jogar pela posição na mão
+// (a partir de 1), pelo nome (sem diferenciar maiúsculas) ou pelo ID, e recusa
+// localmente as jogadas que o servidor rejeitaria
+func (m *maoJogador) escolher(ref string) (protocolo.Carta, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ref = strings.TrimSpace(ref)
+	switch {
+	case len(m.cartas) == 0:
+		return protocolo.Carta{}, errors.New("você não tem cartas na mão; use /comprar")
+	case !m.jogando:
+		return protocolo.Carta{}, errors.New("a partida ainda não começou; aguarde o oponente comprar o pacote")
+	case m.jogou:
+		return protocolo.Carta{}, errors.New("você já jogou nesta jogada; aguarde o oponente")
+	}
+
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(m.cartas) {
+			return protocolo.Carta{}, fmt.Errorf("posição %d inválida: sua mão tem %d cartas", n, len(m.cartas))
+		}
+		return m.cartas[n-1], nil
+	}
+
+	var achadas []int
+	for i, c := range m.cartas {
+		if c.ID == ref {
+			return c, nil
+		}
+		if strings.EqualFold(c.Nome, ref) {
+			achadas = append(achadas, i)
+		}
+	}
+	switch len(achadas) {
+	case 0:
+		return protocolo.Carta{}, fmt.Errorf("você não tem a carta %q", ref)
+	case 1:
+		return m.cartas[achadas[0]], nil
+	}
+	posicoes := make([]string, len(achadas))
+	for i, p := range achadas {
+		c := m.cartas[p]
+		posicoes[i] = fmt.Sprintf("%d (%s, poder %d)", p+1, c.Naipe, c.Valor)
+	}
+	return protocolo.Carta{}, fmt.Errorf("há %d cartas %q; jogue pela posição: %s", len(achadas), m.cartas[achadas[0]].Nome, strings.Join(posicoes, ", "))
+}
+
+// nomesComPrefixo lista, sem repetição, os nomes das cartas da mão que
+// começam com prefixo (usado para completar /jogar com Tab)
+func (m *maoJogador) nomesComPrefixo(prefixo string) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	prefixo = strings.ToLower(prefixo)
+	vistos := make(map[string]bool)
+	var nomes []string
+	for _, c := range m.cartas {
+		if strings.HasPrefix(strings.ToLower(c.Nome), prefixo) && !vistos[c.Nome] {
+			vistos[c.Nome] = true
+			nomes = append(nomes, c.Nome)
+		}
+	}
+	return nomes
+}
+
+// descreverMao resume a mão em uma linha numerada
+func descreverMao(cartas []protocolo.Carta) string {
+	partes := make([]string, len(cartas))
+	for i, c := range cartas {
+		partes[i] = fmt.Sprintf("%d. %s %s (%d)", i+1, c.Nome, c.Naipe, c.Valor)
+	}
+	return strings.Join(partes, "  ")
+}
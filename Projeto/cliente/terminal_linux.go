@@ -0,0 +1,51 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// BAREMA ITEM 1: ARQUITETURA - Coloca o terminal em modo bruto (sem eco e sem
+// esperar Enter) e devolve a função que restaura o modo original
+func modoBruto(f *os.File) (restaurar func(), err error) {
+	var original syscall.Termios
+	if err := ioctlTermios(f, syscall.TCGETS, &original); err != nil {
+		return nil, err
+	}
+	bruto := original
+	bruto.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	bruto.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	bruto.Cflag &^= syscall.CSIZE | syscall.PARENB
+	bruto.Cflag |= syscall.CS8
+	bruto.Cc[syscall.VMIN] = 1
+	bruto.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(f, syscall.TCSETS, &bruto); err != nil {
+		return nil, err
+	}
+	return func() { ioctlTermios(f, syscall.TCSETS, &original) }, nil
+}
+
+// tamanhoTerminal devolve colunas e linhas do terminal
+func tamanhoTerminal(f *os.File) (largura, altura int, err error) {
+	var ws struct{ linhas, colunas, x, y uint16 }
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws))); e != 0 {
+		return 0, 0, e
+	}
+	return int(ws.colunas), int(ws.linhas), nil
+}
+
+// avisarRedimensionamento envia um sinal em c sempre que o terminal muda de tamanho
+func avisarRedimensionamento(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
+
+func ioctlTermios(f *os.File, req uintptr, t *syscall.Termios) error {
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(t))); e != 0 {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// O modo TUI depende de termios; nos demais sistemas o cliente usa o modo de linhas
+func modoBruto(*os.File) (func(), error) {
+	return nil, errors.New("modo TUI disponível apenas no Linux")
+}
+
+func tamanhoTerminal(*os.File) (int, int, error) { return 80, 24, nil }
+
+func avisarRedimensionamento(chan<- os.Signal) {}
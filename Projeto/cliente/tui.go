@@ -0,0 +1,692 @@
+package main
+
+// ===================== BAREMA ITEM 1: ARQUITETURA =====================
+// Interface de terminal em tela cheia (flag -tui).
+// A tela é redesenhada por inteiro a cada evento, com sequências ANSI, em
+// painéis separados: mesa e placar, mão do jogador, chat, avisos do sistema e
+// a linha de digitação. As setas escolhem a carta e Enter a joga; o que for
+// digitado continua virando comando ou chat, sem que as mensagens recebidas
+// atropelem o texto em edição, e Tab completa comandos e nomes de cartas.
+// Uma única goroutine cuida do estado da tela.
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"meujogo/clientesdk"
+	"meujogo/protocolo"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Histórico mantido nos painéis de chat e avisos
+const maxLinhasPainel = 200
+
+// Teclas especiais reconhecidas
+const (
+	teclaTexto = iota
+	teclaCima
+	teclaBaixo
+	teclaEnter
+	teclaApagar
+	teclaLimpar
+	teclaTab
+	teclaEsc
+	teclaSair
+)
+
+type tecla struct {
+	tipo int
+	r    rune // Caractere digitado (teclaTexto)
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Estado exibido pela TUI
+type telaJogo struct {
+	cliente  *clientesdk.Cliente
+	cfg      configCliente
+	saida    *bufio.Writer
+	largura  int
+	altura   int
+	oponente string
+
+	// Mesa e placar, vindos do último ATUALIZACAO_JOGO
+	rodada        int
+	mesa          map[string]protocolo.Carta
+	pontosRodada  map[string]int
+	pontosPartida map[string]int
+	contagem      map[string]int
+	turno         string
+	latencias     map[string]protocolo.ResumoLatencia // BAREMA ITEM 6: LATÊNCIA - Último LATENCIA da sala
+
+	selecionada int
+	chat        []string
+	avisos      []string
+	entrada     []rune
+	motivoFim   string // Preenchido quando a TUI deve terminar
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Executa a TUI até o jogador sair ou a conexão cair
+// Devolve a mensagem a exibir depois que o terminal for restaurado
+func executarTUI(cliente *clientesdk.Cliente, cfg configCliente) (string, error) {
+	restaurar, err := modoBruto(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	t := &telaJogo{cliente: cliente, cfg: cfg, saida: bufio.NewWriterSize(os.Stdout, 16<<10)}
+	t.medir()
+
+	t.saida.WriteString("\x1b[?1049h") // Tela alternativa: o terminal volta intacto ao sair
+	defer func() {
+		t.saida.WriteString("\x1b[?25h\x1b[?1049l")
+		t.saida.Flush()
+		restaurar()
+	}()
+
+	teclas := make(chan tecla, 64)
+	go lerTeclas(os.Stdin, teclas)
+	redimensionou := make(chan os.Signal, 1)
+	avisarRedimensionamento(redimensionou)
+
+	t.aviso("Bem-vindo! Use /comprar para receber cartas quando a partida começar. /ajuda lista os comandos.")
+	eventos := cliente.Eventos()
+	for t.motivoFim == "" {
+		t.desenhar()
+		select {
+		case ev, ok := <-eventos:
+			if !ok {
+				t.motivoFim = "[CLIENTE] Conexão com o servidor foi encerrada."
+				break
+			}
+			t.aplicar(ev)
+		case k, ok := <-teclas:
+			if !ok {
+				t.motivoFim = "[CLIENTE] Entrada encerrada."
+				break
+			}
+			t.tecla(k)
+		case <-redimensionou:
+			t.medir()
+		}
+	}
+	return t.motivoFim, nil
+}
+
+func (t *telaJogo) medir() {
+	t.largura, t.altura = 80, 24
+	if l, a, err := tamanhoTerminal(os.Stdout); err == nil && l > 0 && a > 0 {
+		t.largura, t.altura = l, a
+	}
+}
+
+/* ====================== Eventos do servidor ====================== */
+
+// BAREMA ITEM 3: API REMOTA - Atualiza o estado da tela com uma mensagem do servidor
+func (t *telaJogo) aplicar(ev clientesdk.Evento) {
+	switch dados := ev.Dados.(type) {
+	case protocolo.DadosPartidaEncontrada:
+		t.oponente = dados.OponenteNome
+		t.limparMesa()
+		minhaMao.limpar()
+		t.selecionada = 0
+		t.aviso(fmt.Sprintf("Partida encontrada! Seu oponente é %s.", dados.OponenteNome))
+
+	case protocolo.DadosAtualizacaoJogo:
+		t.rodada = dados.NumeroRodada
+		t.mesa = dados.UltimaJogada
+		t.pontosRodada = dados.PontosRodada
+		t.pontosPartida = dados.PontosPartida
+		t.contagem = dados.ContagemCartas
+		t.turno = dados.MensagemDoTurno
+		minhaMao.sincronizar(dados)
+		t.moverSelecao(0)
+		if dados.VencedorJogada != "" {
+			t.aviso("Vencedor da jogada: " + dados.VencedorJogada)
+		}
+		if dados.VencedorRodada != "" {
+			t.aviso(fmt.Sprintf("Vencedor da rodada %d: %s", dados.NumeroRodada, dados.VencedorRodada))
+		}
+
+	case protocolo.DadosFimDeJogo:
+		if dados.VencedorNome == "EMPATE" {
+			t.aviso("=== FIM DE JOGO — EMPATE ===")
+		} else {
+			t.aviso("=== FIM DE JOGO — VENCEDOR DA PARTIDA: " + dados.VencedorNome + " ===")
+		}
+		t.oponente = ""
+		t.turno = "Partida encerrada. Aguardando novo oponente..."
+		minhaMao.limpar()
+
+	case protocolo.ComprarPacoteResp:
+		minhaMao.receberPacote(dados.Cartas)
+		t.aviso(fmt.Sprintf("[PACOTE] Você recebeu %d cartas.", len(dados.Cartas)))
+
+	case protocolo.DadosReceberChat:
+		t.chat = anexarLimitado(t.chat, linhaChat(dados))
+
+	case protocolo.DadosHistoricoCanal:
+		t.aviso("[SISTEMA] " + descreverEntradaCanal(dados))
+		for _, m := range dados.Mensagens {
+			t.chat = anexarLimitado(t.chat, linhaChat(m))
+		}
+
+	case protocolo.DadosListaAmigos:
+		t.aviso("[AMIGOS] " + descreverAmigos(dados))
+
+	case protocolo.DadosPresenca:
+		t.aviso("[AMIGOS] " + descreverPresenca(dados))
+
+	case protocolo.DadosMensagemPrivada:
+		t.chat = anexarLimitado(t.chat, linhaPrivada(dados))
+
+	case protocolo.DadosAmigo:
+		t.aviso("[DESAFIO] " + descreverDesafio(dados))
+
+	case protocolo.DadosPong:
+		t.aviso("[SISTEMA] " + descreverMinhaLatencia(t.cliente.Latencia()))
+
+	case protocolo.DadosLatencia:
+		t.latencias = dados.Jogadores
+
+	case protocolo.DadosAtraso:
+		t.aviso("[SISTEMA] " + descreverAtraso(dados))
+
+	case protocolo.DadosErro:
+		if ev.Comando == "ERRO" {
+			t.aviso("[ERRO] " + dados.Mensagem)
+		} else {
+			t.aviso(dados.Mensagem)
+		}
+	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Queda e retomada da conexão (com -reconectar)
+	switch ev.Comando {
+	case clientesdk.EventoDesconectado:
+		if !t.cfg.Reconectar {
+			t.motivoFim = "[CLIENTE] Conexão com o servidor foi perdida."
+			return
+		}
+		t.oponente = ""
+		t.limparMesa()
+		t.aviso("[CLIENTE] Conexão com o servidor foi perdida. Tentando reconectar...")
+	case clientesdk.EventoReconectado:
+		// A sessão nova não tem sala nem cartas: a partida anterior foi perdida
+		minhaMao.limpar()
+		t.aviso("[CLIENTE] Reconectado ao servidor.")
+		if t.cfg.Fila {
+			t.falhou(t.cliente.EntrarNaFila())
+		} else {
+			t.aviso("[SISTEMA] Use /fila para procurar um oponente.")
+		}
+	}
+}
+
+// falhou avisa sobre um envio que não chegou ao servidor
+func (t *telaJogo) falhou(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, clientesdk.ErrDesconectado) && t.cfg.Reconectar:
+		t.aviso("[CLIENTE] Sem conexão no momento; aguarde a reconexão.")
+	default:
+		t.aviso("[CLIENTE] Falha ao enviar mensagem para o servidor.")
+	}
+	return true
+}
+
+func (t *telaJogo) limparMesa() {
+	t.rodada = 0
+	t.mesa, t.pontosRodada, t.pontosPartida, t.contagem = nil, nil, nil, nil
+	t.turno = ""
+	t.latencias = nil
+}
+
+func (t *telaJogo) aviso(texto string) {
+	for _, linha := range strings.Split(texto, "\n") {
+		t.avisos = anexarLimitado(t.avisos, linha)
+	}
+}
+
+// anexarLimitado guarda uma linha no painel, já sem caracteres de controle:
+// um ESC vindo de outro jogador redesenharia ou limparia a tela
+func anexarLimitado(linhas []string, linha string) []string {
+	linhas = append(linhas, semControles(linha))
+	if len(linhas) > maxLinhasPainel {
+		linhas = linhas[len(linhas)-maxLinhasPainel:]
+	}
+	return linhas
+}
+
+/* ====================== Teclado ====================== */
+
+// lerTeclas traduz os bytes do terminal em teclas
+func lerTeclas(f *os.File, teclas chan<- tecla) {
+	defer close(teclas)
+	buf := make([]byte, 256)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			return
+		}
+		for b := buf[:n]; len(b) > 0; {
+			switch c := b[0]; {
+			case c == 0x1b && len(b) >= 2 && (b[1] == '[' || b[1] == 'O'):
+				// Sequência CSI/SS3: parâmetros seguidos de um byte final
+				j := 2
+				for j < len(b) && b[j] >= 0x30 && b[j] <= 0x3f {
+					j++
+				}
+				if j < len(b) {
+					switch b[j] {
+					case 'A':
+						teclas <- tecla{tipo: teclaCima}
+					case 'B':
+						teclas <- tecla{tipo: teclaBaixo}
+					}
+					j++
+				}
+				b = b[j:]
+			case c == 0x1b:
+				teclas <- tecla{tipo: teclaEsc}
+				b = b[1:]
+			case c == '\r' || c == '\n':
+				teclas <- tecla{tipo: teclaEnter}
+				b = b[1:]
+			case c == 0x7f || c == 0x08:
+				teclas <- tecla{tipo: teclaApagar}
+				b = b[1:]
+			case c == 0x03 || c == 0x04: // Ctrl+C, Ctrl+D
+				teclas <- tecla{tipo: teclaSair}
+				b = b[1:]
+			case c == 0x15: // Ctrl+U
+				teclas <- tecla{tipo: teclaLimpar}
+				b = b[1:]
+			case c == '\t':
+				teclas <- tecla{tipo: teclaTab}
+				b = b[1:]
+			case c < 0x20:
+				b = b[1:]
+			default:
+				r, tam := utf8.DecodeRune(b)
+				teclas <- tecla{tipo: teclaTexto, r: r}
+				b = b[tam:]
+			}
+		}
+	}
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Trata uma tecla: setas escolhem a carta, Enter
+// joga a carta escolhida (linha vazia) ou envia o comando/chat digitado
+func (t *telaJogo) tecla(k tecla) {
+	switch k.tipo {
+	case teclaTexto:
+		t.entrada = append(t.entrada, k.r)
+	case teclaApagar:
+		if len(t.entrada) > 0 {
+			t.entrada = t.entrada[:len(t.entrada)-1]
+		}
+	case teclaLimpar, teclaEsc:
+		t.entrada = t.entrada[:0]
+	case teclaCima:
+		t.moverSelecao(-1)
+	case teclaBaixo:
+		t.moverSelecao(1)
+	case teclaTab:
+		t.completar()
+	case teclaSair:
+		t.motivoFim = "Até a próxima!"
+	case teclaEnter:
+		texto := strings.TrimSpace(string(t.entrada))
+		t.entrada = t.entrada[:0]
+		if texto == "" {
+			t.jogarSelecionada()
+		} else {
+			t.executar(texto)
+		}
+	}
+}
+
+// moverSelecao anda delta cartas (circulando) e mantém a seleção dentro da mão
+func (t *telaJogo) moverSelecao(delta int) {
+	n := len(minhaMao.lista())
+	if n == 0 {
+		t.selecionada = 0
+		return
+	}
+	if delta == 0 {
+		t.selecionada = min(t.selecionada, n-1)
+		return
+	}
+	t.selecionada = (t.selecionada + delta + n) % n
+}
+
+func (t *telaJogo) jogarSelecionada() {
+	t.jogar(fmt.Sprint(t.selecionada + 1))
+}
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Joga pela posição ou nome, avisando
+// localmente quando a jogada seria recusada pelo servidor
+func (t *telaJogo) jogar(ref string) {
+	carta, err := minhaMao.escolher(ref)
+	if err != nil {
+		t.aviso("[SISTEMA] Jogada recusada: " + err.Error() + ".")
+		return
+	}
+	if t.falhou(t.cliente.JogarCarta(carta.ID)) {
+		return
+	}
+	minhaMao.retirar(carta.ID)
+	t.moverSelecao(0)
+}
+
+// Comandos oferecidos pelo Tab
+var comandosTUI = []string{"/aceitar", "/ajuda", "/amigo", "/amigos", "/bloquear", "/c", "/canal", "/cartas", "/comprar", "/deixar", "/denunciar", "/desafiar", "/desamigo", "/desbloquear", "/fila", "/jogar", "/msg", "/ping", "/quit", "/recusar", "/sair"}
+
+// BAREMA ITEM 1: ARQUITETURA - Completa o comando digitado ou, depois de
+// /jogar, o nome da carta; com várias opções completa o trecho comum e as lista
+func (t *telaJogo) completar() {
+	texto := string(t.entrada)
+	var base string
+	var opcoes []string
+	if resto, ok := strings.CutPrefix(texto, "/jogar "); ok {
+		base = "/jogar "
+		opcoes = minhaMao.nomesComPrefixo(strings.TrimLeft(resto, " "))
+	} else if strings.HasPrefix(texto, "/") && !strings.Contains(texto, " ") {
+		for _, c := range comandosTUI {
+			if strings.HasPrefix(c, texto) {
+				opcoes = append(opcoes, c)
+			}
+		}
+	}
+
+	switch len(opcoes) {
+	case 0:
+		return
+	case 1:
+		t.entrada = []rune(base + opcoes[0])
+		if base == "" {
+			t.entrada = append(t.entrada, ' ')
+		}
+	default:
+		if comum := prefixoComum(opcoes); utf8.RuneCountInString(base+comum) > len(t.entrada) {
+			t.entrada = []rune(base + comum)
+		}
+		t.aviso("Opções: " + strings.Join(opcoes, ", "))
+	}
+}
+
+// prefixoComum devolve o maior início compartilhado por todos os textos,
+// sem diferenciar maiúsculas (como na busca das cartas)
+func prefixoComum(textos []string) string {
+	comum := []rune(textos[0])
+	for _, texto := range textos[1:] {
+		r := []rune(texto)
+		n := 0
+		for n < len(comum) && n < len(r) && strings.EqualFold(string(comum[n]), string(r[n])) {
+			n++
+		}
+		comum = comum[:n]
+	}
+	return string(comum)
+}
+
+// executar trata a linha digitada com os mesmos comandos do modo de linhas
+func (t *telaJogo) executar(texto string) {
+	partes := strings.Fields(texto)
+	var err error
+	switch partes[0] {
+	case "/fila":
+		err = t.cliente.EntrarNaFila()
+	case "/comprar":
+		err = t.cliente.ComprarPacote(1)
+	case "/jogar":
+		if len(partes) < 2 {
+			t.aviso("[SISTEMA] Uso: /jogar <posição|nome da carta> (Tab completa o nome), ou escolha com as setas e tecle Enter.")
+			return
+		}
+		t.jogar(strings.Join(partes[1:], " "))
+	case "/cartas":
+		err = t.cliente.VerCartas()
+	case "/ping":
+		err = t.cliente.Ping()
+	case "/sair":
+		err = t.cliente.SairDaSala()
+		minhaMao.limpar()
+		t.oponente = ""
+		t.limparMesa()
+		t.aviso("[SISTEMA] Você saiu da sala. Aguardando novo oponente...")
+	case "/canal", "/deixar":
+		if len(partes) != 2 {
+			t.aviso("[SISTEMA] Uso: " + partes[0] + " <canal>")
+			return
+		}
+		if partes[0] == "/canal" {
+			err = t.cliente.EntrarCanal(partes[1])
+		} else {
+			err = t.cliente.SairCanal(partes[1])
+		}
+	case "/c":
+		if len(partes) < 3 {
+			t.aviso("[SISTEMA] Uso: /c <canal> <texto>")
+			return
+		}
+		texto := strings.Join(partes[2:], " ")
+		if err = t.cliente.ChatCanal(partes[1], texto); err == nil {
+			t.chat = anexarLimitado(t.chat, linhaChat(protocolo.DadosReceberChat{NomeJogador: meuNome, Texto: texto, Canal: partes[1]}))
+		}
+	case "/amigo", "/desamigo", "/desafiar", "/aceitar", "/recusar", "/bloquear", "/desbloquear":
+		if len(partes) < 2 {
+			t.aviso("[SISTEMA] Uso: " + partes[0] + " <nome>")
+			return
+		}
+		err = comandoAmigo(t.cliente, partes[0], strings.Join(partes[1:], " "))
+	case "/amigos":
+		err = t.cliente.ListarAmigos()
+	case "/msg":
+		if len(partes) < 3 {
+			t.aviso("[SISTEMA] Uso: /msg <nome> <texto>")
+			return
+		}
+		texto := strings.Join(partes[2:], " ")
+		if err = t.cliente.MensagemPrivada(partes[1], texto); err == nil {
+			t.chat = anexarLimitado(t.chat, linhaPrivadaEnviada(partes[1], texto))
+		}
+	case "/denunciar", "/report":
+		if len(partes) < 2 {
+			t.aviso("[SISTEMA] Uso: " + partes[0] + " <nome> [motivo]")
+			return
+		}
+		err = t.cliente.Denunciar(partes[1], strings.Join(partes[2:], " "))
+	case "/quit":
+		t.motivoFim = "Até a próxima!"
+	case "/ajuda":
+		t.aviso("↑/↓ escolhem a carta e Enter (com a linha vazia) a joga; /jogar <posição|nome> também joga, e Tab completa comandos e nomes. /fila procura um oponente, /comprar recebe um pacote, /cartas detalha a mão, /ping mede a latência, /sair abandona a partida, /canal e /deixar entram e saem de um canal do lobby, /c <canal> <texto> fala nele, /amigo e /desamigo adicionam e removem amigos, /amigos lista quem está online, /msg <nome> <texto> envia uma mensagem privada, /desafiar, /aceitar e /recusar tratam os desafios para uma partida privada, /bloquear e /desbloquear param e retomam o contato com um jogador, /denunciar <nome> [motivo] envia as últimas mensagens à moderação, /quit fecha o cliente. Outro texto vira chat (da sala ou do último canal).")
+	default:
+		if err = t.cliente.Chat(texto); err == nil {
+			t.chat = anexarLimitado(t.chat, linhaChat(protocolo.DadosReceberChat{NomeJogador: meuNome, Texto: texto}))
+		}
+	}
+	t.falhou(err)
+}
+
+/* ====================== Desenho ====================== */
+
+// BAREMA ITEM 1: ARQUITETURA - Redesenha a tela inteira
+func (t *telaJogo) desenhar() {
+	w, h := t.largura, t.altura
+	t.saida.WriteString("\x1b[?25l\x1b[H") // Esconde o cursor e volta ao topo
+	if w < 40 || h < 16 {
+		t.saida.WriteString("\x1b[2JAumente o terminal (mínimo 40x16).")
+		t.saida.Flush()
+		return
+	}
+
+	linhas := make([]string, 0, h)
+	adversario := t.oponente
+	if adversario == "" {
+		adversario = "aguardando oponente"
+	}
+	titulo := fmt.Sprintf(" %s vs %s", meuNome, adversario)
+	if l := latenciaOponentes(t.latencias); l != "" && t.oponente != "" {
+		titulo += " (" + l + ")"
+	}
+	if t.rodada > 0 {
+		titulo += fmt.Sprintf(" — Rodada %d", t.rodada)
+	}
+	linhas = append(linhas, "\x1b[7m"+ajustar(titulo, w)+"\x1b[0m")
+
+	// Mesa e placar
+	linhas = append(linhas, borda('┌', "Mesa", '┐', w, -1, 0))
+	linhas = append(linhas, t.linhasMesa(w)...)
+
+	// Mão, com rolagem para manter a carta escolhida visível
+	mao := minhaMao.lista()
+	maxMao := min(8, h-15)
+	visiveis := max(1, min(len(mao), maxMao))
+	linhas = append(linhas, borda('├', fmt.Sprintf("Sua mão (%d)", len(mao)), '┤', w, -1, 0))
+	if len(mao) == 0 {
+		linhas = append(linhas, conteudo("(sem cartas — use /comprar)", w))
+	} else {
+		inicio := min(max(0, t.selecionada-visiveis+1), len(mao)-visiveis)
+		for i := inicio; i < inicio+visiveis; i++ {
+			c := mao[i]
+			marca := "  "
+			texto := fmt.Sprintf("%d. %s %s  poder %d", i+1, c.Nome, c.Naipe, c.Valor)
+			if c.Raridade != "" {
+				texto += "  [" + c.Raridade + "]"
+			}
+			if i == t.selecionada {
+				marca = "▶ "
+				linhas = append(linhas, "│ \x1b[1m"+ajustar(marca+texto, w-4)+"\x1b[0m │")
+				continue
+			}
+			linhas = append(linhas, conteudo(marca+texto, w))
+		}
+	}
+
+	// Chat e avisos lado a lado
+	esq := (w - 7) / 2
+	dir := w - 7 - esq
+	meio := 3 + esq
+	linhas = append(linhas, borda('├', "Chat", '┤', w, meio, '┬'))
+	altPaineis := h - len(linhas) - 3
+	chat := quebrar(t.chat, esq, altPaineis)
+	avisos := quebrar(t.avisos, dir, altPaineis)
+	for i := 0; i < altPaineis; i++ {
+		linhas = append(linhas, "│ "+ajustar(chat[i], esq)+" │ "+ajustar(avisos[i], dir)+" │")
+	}
+	// Título do painel de avisos sobre a coluna da direita
+	cab := []rune(linhas[len(linhas)-altPaineis-1])
+	copy(cab[meio+2:], []rune(" Avisos "))
+	linhas[len(linhas)-altPaineis-1] = string(cab)
+
+	// Linha de digitação e ajuda
+	linhas = append(linhas, borda('├', "", '┤', w, meio, '┴'))
+	entrada := string(t.entrada)
+	if visivel := w - 6; utf8.RuneCountInString(entrada) > visivel {
+		r := []rune(entrada)
+		entrada = string(r[len(r)-visivel:])
+	}
+	linhas = append(linhas, conteudo("> "+entrada, w))
+	linhas = append(linhas, borda('└', "↑↓ carta · Enter joga · Tab completa · /ajuda · Ctrl+C sai", '┘', w, -1, 0))
+
+	for i, l := range linhas {
+		if i > 0 {
+			t.saida.WriteString("\r\n")
+		}
+		t.saida.WriteString(l)
+	}
+	// Cursor no fim do texto digitado
+	fmt.Fprintf(t.saida, "\x1b[J\x1b[%d;%dH\x1b[?25h", len(linhas)-1, 5+utf8.RuneCountInString(entrada))
+	t.saida.Flush()
+}
+
+func (t *telaJogo) linhasMesa(w int) []string {
+	// Jogadores em ordem estável: você primeiro, depois os demais
+	var nomes []string
+	for nome := range t.contagem {
+		if nome != meuNome {
+			nomes = append(nomes, nome)
+		}
+	}
+	sort.Strings(nomes)
+	if _, ok := t.contagem[meuNome]; ok {
+		nomes = append([]string{meuNome}, nomes...)
+	}
+
+	var mesa, rodada, partida, restantes []string
+	for _, nome := range nomes {
+		if c, ok := t.mesa[nome]; ok {
+			mesa = append(mesa, fmt.Sprintf("%s: %s %s (%d)", nome, c.Nome, c.Naipe, c.Valor))
+		} else {
+			mesa = append(mesa, nome+": —")
+		}
+		rodada = append(rodada, fmt.Sprintf("%s %d", nome, t.pontosRodada[nome]))
+		partida = append(partida, fmt.Sprintf("%s %d", nome, t.pontosPartida[nome]))
+		restantes = append(restantes, fmt.Sprintf("%s %d", nome, t.contagem[nome]))
+	}
+	if len(nomes) == 0 {
+		return []string{
+			conteudo("Nenhuma partida em andamento.", w),
+			conteudo("", w),
+			conteudo("", w),
+			conteudo(t.turno, w),
+		}
+	}
+	return []string{
+		conteudo("Na mesa: "+strings.Join(mesa, "   "), w),
+		conteudo("Pontos na rodada: "+strings.Join(rodada, " × ")+"   Partida: "+strings.Join(partida, " × "), w),
+		conteudo("Cartas restantes: "+strings.Join(restantes, ", "), w),
+		conteudo(t.turno, w),
+	}
+}
+
+// borda monta uma linha horizontal com título; meio >= 0 coloca o divisor
+// das colunas de chat e avisos nessa posição
+func borda(esq rune, titulo string, dir rune, w, meio int, divisor rune) string {
+	r := []rune(strings.Repeat("─", w))
+	r[0], r[w-1] = esq, dir
+	if meio >= 0 {
+		r[meio] = divisor
+	}
+	if titulo != "" {
+		copy(r[2:w-2], []rune(" "+titulo+" "))
+	}
+	return string(r)
+}
+
+func conteudo(texto string, w int) string {
+	return "│ " + ajustar(texto, w-4) + " │"
+}
+
+// ajustar corta ou completa o texto com espaços até exatamente w colunas
+func ajustar(texto string, w int) string {
+	n := utf8.RuneCountInString(texto)
+	if n > w {
+		r := []rune(texto)
+		if w <= 1 {
+			return string(r[:w])
+		}
+		return string(r[:w-1]) + "…"
+	}
+	return texto + strings.Repeat(" ", w-n)
+}
+
+// quebrar divide as linhas na largura da coluna e devolve as últimas n
+// (completando com linhas vazias no topo)
+func quebrar(linhas []string, largura, n int) []string {
+	var saida []string
+	for _, linha := range linhas {
+		r := []rune(linha)
+		for len(r) > largura {
+			saida = append(saida, string(r[:largura]))
+			r = r[largura:]
+		}
+		saida = append(saida, string(r))
+	}
+	if len(saida) >= n {
+		return saida[len(saida)-n:]
+	}
+	return append(make([]string, n-len(saida)), saida...)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"meujogo/protocolo"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// BAREMA ITEM 9: TESTES - Texto de outros jogadores não chega ao terminal com
+// caracteres de controle, nem no painel da TUI nem no modo de linhas
+func TestChatSemControles(t *testing.T) {
+	maliciosos := []string{
+		"\x1b[2J\x1b[Hlimpou a tela",
+		"apaga\b\b\b\bisto",
+		"volta\rao início",
+		"\x1b]0;título falso\x07",
+		"\u009b31mvermelho", // CSI de 8 bits
+	}
+	for _, texto := range maliciosos {
+		linhas := []string{
+			linhaChat(protocolo.DadosReceberChat{NomeJogador: "bia", Texto: texto}),
+			linhaChat(protocolo.DadosReceberChat{NomeJogador: "bia", Texto: texto, Canal: "geral"}),
+			linhaPrivada(protocolo.DadosMensagemPrivada{De: "bia", Texto: texto}),
+			linhaPrivada(protocolo.DadosMensagemPrivada{De: "bia", Texto: texto, Guardada: true}),
+		}
+		var tela telaJogo
+		tela.aviso(texto)
+		for _, linha := range linhas {
+			tela.chat = anexarLimitado(tela.chat, linhaPrivadaEnviada("bia", texto))
+			tela.chat = anexarLimitado(tela.chat, linha)
+		}
+		linhas = append(linhas, tela.chat...)
+		linhas = append(linhas, tela.avisos...)
+		for _, linha := range linhas {
+			if strings.ContainsFunc(linha, unicode.IsControl) {
+				t.Errorf("%q chegou à tela como %q", texto, linha)
+			}
+		}
+	}
+
+	if got := semControles("oi, tudo bem? 🃏"); got != "oi, tudo bem? 🃏" {
+		t.Errorf("texto comum alterado: %q", got)
+	}
+	if got := semControles("a\x1bb"); got != "a�b" {
+		t.Errorf("semControles(%q) = %q, esperado %q", "a\x1bb", got, "a�b")
+	}
+}
@@ -6,13 +6,20 @@ package main
 // e justiça na distribuição de recursos.
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"meujogo/clientesdk"
 	"meujogo/protocolo"
-	"net"
+	"meujogo/seguranca"
+	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -25,29 +32,46 @@ const (
 	serverAddr     = "servidor:65432" // Endereço do servidor para conectar
 )
 
+// BAREMA ITEM 2: COMUNICAÇÃO - Opções de TLS compartilhadas por todos os bots
+var opcoesTLS seguranca.OpcoesCliente
+
+// BAREMA ITEM 6: LATÊNCIA - URL do /metrics do servidor (vazio desabilita a coleta)
+var urlMetricas string
+
+// Séries do servidor acompanhadas durante o teste para correlacionar carga e comportamento
+var metricasAcompanhadas = []string{
+	"jogo_conexoes_ativas",
+	`jogo_salas{estado="JOGANDO"}`,
+	"jogo_pacotes_pendentes",
+	"jogo_pacotes_rejeitados_total",
+	`jogo_mensagens_descartadas_total{prioridade="baixa"}`,
+	`jogo_mensagens_descartadas_total{prioridade="normal"}`,
+	"jogo_desconexoes_cliente_lento_total",
+	"jogo_partidas_concluidas_total",
+}
+
 // BAREMA ITEM 9: TESTES - Estrutura para armazenar resultados do teste
 // Coleta estatísticas de performance, concorrência e justiça
 type TestReport struct {
-	totalBots            int             // Total de bots que tentaram conectar
-	connectionsSucceeded int             // Conexões bem-sucedidas
-	purchasesSucceeded   int             // Compras de pacotes bem-sucedidas
-	gamesCompleted       int             // Partidas completadas
-	totalErrors          int             // Total de erros encontrados
-	latencies            []time.Duration // Medições de latência coletadas
-	mu                   sync.Mutex      // BAREMA ITEM 5: CONCORRÊNCIA - Protege acesso concorrente aos dados
+	totalBots            int                // Total de bots que tentaram conectar
+	connectionsSucceeded int                // Conexões bem-sucedidas
+	purchasesSucceeded   int                // Compras de pacotes bem-sucedidas
+	gamesCompleted       int                // Partidas completadas
+	totalErrors          int                // Total de erros encontrados
+	latencies            []time.Duration    // Medições de latência coletadas
+	metricasServidor     map[string]float64 // BAREMA ITEM 6: LATÊNCIA - Última coleta do /metrics do servidor
+	mu                   sync.Mutex         // BAREMA ITEM 5: CONCORRÊNCIA - Protege acesso concorrente aos dados
 }
 
 // BAREMA ITEM 9: TESTES - Estrutura que representa um bot de teste
 // Simula um jogador real conectado ao servidor
 type Bot struct {
-	ID         int               // Identificador único do bot
-	Conn       net.Conn          // Conexão TCP com o servidor
-	Encoder    *json.Encoder     // Codificador JSON para envio
-	Decoder    *json.Decoder     // Decodificador JSON para recebimento
-	Nome       string            // Nome do bot
-	Inventario []protocolo.Carta // Cartas que o bot possui
-	pingStart  time.Time         // BAREMA ITEM 6: LATÊNCIA - Timestamp para medição de ping
-	mu         sync.Mutex        // BAREMA ITEM 5: CONCORRÊNCIA - Protege o inventário
+	ID         int                 // Identificador único do bot
+	Cliente    *clientesdk.Cliente // Conexão com o servidor
+	Nome       string              // Nome do bot
+	Inventario []protocolo.Carta   // Cartas que o bot possui
+	pingStart  time.Time           // BAREMA ITEM 6: LATÊNCIA - Timestamp para medição de ping
+	mu         sync.Mutex          // BAREMA ITEM 5: CONCORRÊNCIA - Protege o inventário
 }
 
 // BAREMA ITEM 9: TESTES - Simula o ciclo de vida completo de um bot
@@ -55,49 +79,36 @@ type Bot struct {
 func runBotLifecycle(ctx context.Context, botID int, report *TestReport, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	// BAREMA ITEM 2: COMUNICAÇÃO - Tenta conectar com timeout e retry
-	var conn net.Conn
-	var err error
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		conn, err = net.DialTimeout("tcp", serverAddr, 5*time.Second)
-		if err == nil {
-			break
-		}
-		if i < maxRetries-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
+	bot := &Bot{
+		ID:   botID,
+		Nome: fmt.Sprintf("Bot-%d", botID),
 	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Conecta com timeout e retry; o LOGIN é enviado pelo SDK
+	cliente, err := clientesdk.Conectar(ctx, clientesdk.Opcoes{
+		Endereco:          serverAddr,
+		TLS:               opcoesTLS,
+		Timeout:           5 * time.Second,
+		Nome:              bot.Nome,
+		TentativasConexao: 3,
+	})
 	if err != nil {
-		log.Printf("❌ Bot %d: Falha ao conectar após %d tentativas: %v", botID, maxRetries, err)
+		log.Printf("❌ Bot %d: Falha ao conectar: %v", botID, err)
 		report.mu.Lock()
 		report.totalErrors++
 		report.mu.Unlock()
 		return
 	}
-	defer conn.Close()
+	defer cliente.Fechar()
+	bot.Cliente = cliente
 
 	// BAREMA ITEM 9: TESTES - Registra conexão bem-sucedida
 	report.mu.Lock()
 	report.connectionsSucceeded++
 	report.mu.Unlock()
 
-	bot := &Bot{
-		ID:      botID,
-		Conn:    conn,
-		Encoder: json.NewEncoder(conn),
-		Decoder: json.NewDecoder(conn),
-		Nome:    fmt.Sprintf("Bot-%d", botID),
-	}
-
-	// BAREMA ITEM 2: COMUNICAÇÃO - Canal para mensagens assíncronas
-	incomingMessages := make(chan protocolo.Mensagem, 10)
-	errChan := make(chan error, 1)
-	go readFromServer(bot, incomingMessages, errChan)
-
-	// BAREMA ITEM 3: API REMOTA - Login e entrada na fila
-	enviarComando(bot, "LOGIN", protocolo.DadosLogin{Nome: bot.Nome})
-	enviarComando(bot, "ENTRAR_NA_FILA", nil)
+	// BAREMA ITEM 3: API REMOTA - Entrada na fila
+	cliente.EntrarNaFila()
 
 	// BAREMA ITEM 6: LATÊNCIA - Ticker para medições de ping (reduzido para evitar sobrecarga)
 	pingTicker := time.NewTicker(10 * time.Second)
@@ -106,41 +117,38 @@ func runBotLifecycle(ctx context.Context, botID int, report *TestReport, wg *syn
 	// BAREMA ITEM 9: TESTES - Loop principal do bot
 	for {
 		select {
-		case msg := <-incomingMessages:
+		case ev, ok := <-cliente.Eventos():
+			if !ok {
+				return
+			}
 			// BAREMA ITEM 3: API REMOTA - Processa mensagens do servidor
-			switch msg.Comando {
-			case "PARTIDA_ENCONTRADA":
+			switch dados := ev.Dados.(type) {
+			case protocolo.DadosPartidaEncontrada:
 				// BAREMA ITEM 8: PACOTES - Compra pacote quando encontra partida
-				enviarComando(bot, "COMPRAR_PACOTE", protocolo.ComprarPacoteReq{Quantidade: 1})
-			case "PACOTE_RESULTADO":
-				var resp protocolo.ComprarPacoteResp
-				if json.Unmarshal(msg.Dados, &resp) == nil {
-					// BAREMA ITEM 9: TESTES - Registra compra bem-sucedida
-					report.mu.Lock()
-					report.purchasesSucceeded++
-					report.mu.Unlock()
-					bot.mu.Lock()
-					bot.Inventario = resp.Cartas
-					bot.mu.Unlock()
-					// BAREMA ITEM 7: PARTIDAS - Joga primeira carta para iniciar partida
+				cliente.ComprarPacote(1)
+			case protocolo.ComprarPacoteResp:
+				// BAREMA ITEM 9: TESTES - Registra compra bem-sucedida
+				report.mu.Lock()
+				report.purchasesSucceeded++
+				report.mu.Unlock()
+				bot.mu.Lock()
+				bot.Inventario = dados.Cartas
+				bot.mu.Unlock()
+				// BAREMA ITEM 7: PARTIDAS - Joga primeira carta para iniciar partida
+				jogarPrimeiraCarta(bot)
+			case protocolo.DadosAtualizacaoJogo:
+				// BAREMA ITEM 7: PARTIDAS - Joga próxima carta quando jogada anterior é resolvida
+				if dados.VencedorJogada != "" {
 					jogarPrimeiraCarta(bot)
 				}
-			case "ATUALIZACAO_JOGO":
-				var dados protocolo.DadosAtualizacaoJogo
-				if json.Unmarshal(msg.Dados, &dados) == nil {
-					// BAREMA ITEM 7: PARTIDAS - Joga próxima carta quando jogada anterior é resolvida
-					if dados.VencedorJogada != "" {
-						jogarPrimeiraCarta(bot)
-					}
-				}
-			case "FIM_DE_JOGO":
+			case protocolo.DadosFimDeJogo:
 				// BAREMA ITEM 9: TESTES - Registra partida completada
 				report.mu.Lock()
 				report.gamesCompleted++
 				report.mu.Unlock()
 				// BAREMA ITEM 7: PARTIDAS - Volta para fila para nova partida
-				enviarComando(bot, "ENTRAR_NA_FILA", nil)
-			case "PONG":
+				cliente.EntrarNaFila()
+			case protocolo.DadosPong:
 				// BAREMA ITEM 6: LATÊNCIA - Calcula e armazena latência
 				if !bot.pingStart.IsZero() {
 					latencia := time.Since(bot.pingStart)
@@ -148,144 +156,43 @@ func runBotLifecycle(ctx context.Context, botID int, report *TestReport, wg *syn
 					report.latencies = append(report.latencies, latencia)
 					report.mu.Unlock()
 				}
-			case "PING":
-				// BAREMA ITEM 6: LATÊNCIA - Responde ping do servidor
-				var dadosPing protocolo.DadosPing
-				if json.Unmarshal(msg.Dados, &dadosPing) == nil {
-					enviarComando(bot, "PONG", protocolo.DadosPong{Timestamp: dadosPing.Timestamp})
-				}
 			}
-		case <-pingTicker.C:
-			// BAREMA ITEM 6: LATÊNCIA - Mede latência via ICMP com delay aleatório
-			// BAREMA ITEM 5: CONCORRÊNCIA - Delay aleatório para evitar contenção simultânea
-			go func() {
-				delay := time.Duration(bot.ID%1000) * time.Millisecond
-				time.Sleep(delay)
-				bot.pingStart = time.Now()
-				medirLatenciaICMPBot(bot, report)
-			}()
-		case err := <-errChan:
 			// BAREMA ITEM 9: TESTES - Trata erros de comunicação
-			if err.Error() != "EOF" {
-				log.Printf("❌ Bot %d: Erro de comunicação: %v. Saindo.", bot.ID, err)
-				report.mu.Lock()
-				report.totalErrors++
-				report.mu.Unlock()
+			if ev.Comando == clientesdk.EventoDesconectado {
+				if !errors.Is(ev.Erro, io.EOF) {
+					log.Printf("❌ Bot %d: Erro de comunicação: %v. Saindo.", bot.ID, ev.Erro)
+					report.mu.Lock()
+					report.totalErrors++
+					report.mu.Unlock()
+				}
+				return
 			}
-			return
+		case <-pingTicker.C:
+			// BAREMA ITEM 6: LATÊNCIA - Mede a latência pelo protocolo (PING/PONG)
+			bot.pingStart = time.Now()
+			cliente.Ping()
 		case <-ctx.Done():
-			// BAREMA ITEM 9: TESTES - Encerra bot quando teste termina
-			enviarComando(bot, "QUIT", nil)
+			// BAREMA ITEM 9: TESTES - Encerra bot quando teste termina (Fechar envia QUIT)
 			return
 		}
 	}
 }
 
-// readFromServer é uma goroutine que apenas lê do socket e envia para um canal.
-func readFromServer(b *Bot, incoming chan<- protocolo.Mensagem, errs chan<- error) {
-	for {
-		var msg protocolo.Mensagem
-		if err := b.Decoder.Decode(&msg); err != nil {
-			errs <- err
-			return
-		}
-		incoming <- msg
-	}
-}
-
 func jogarPrimeiraCarta(b *Bot) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	if len(b.Inventario) > 0 {
 		cartaAJogar := b.Inventario[0]
 		b.Inventario = b.Inventario[1:] // Remove a carta da mão
-		enviarComando(b, "JOGAR_CARTA", protocolo.DadosJogarCarta{CartaID: cartaAJogar.ID})
+		b.Cliente.JogarCarta(cartaAJogar.ID)
 	}
 }
 
-// BAREMA ITEM 6: LATÊNCIA - Mede latência ICMP para bots de teste
-func medirLatenciaICMPBot(bot *Bot, report *TestReport) {
-	// BAREMA ITEM 6: LATÊNCIA - Timeout de conexão para evitar bloqueios
-	conn, err := net.DialTimeout("ip4:icmp", "servidor", 1*time.Second)
-	if err != nil {
-		return
-	}
-	defer conn.Close()
-
-	// BAREMA ITEM 6: LATÊNCIA - Cria pacote ICMP Echo Request
-	icmpPacket := createICMPEchoRequestBot()
-
-	start := time.Now()
-	conn.Write(icmpPacket)
-
-	// BAREMA ITEM 6: LATÊNCIA - Timeout reduzido para evitar latências altas
-	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
-	buffer := make([]byte, 1024)
-	_, err = conn.Read(buffer)
-	if err != nil {
-		return
-	}
-
-	latencia := time.Since(start)
-
-	// BAREMA ITEM 6: LATÊNCIA - Filtra latências muito altas (provavelmente timeouts)
-	if latencia > 1*time.Second {
-		return
-	}
-
-	report.mu.Lock()
-	report.latencies = append(report.latencies, latencia)
-	report.mu.Unlock()
-}
-
-// BAREMA ITEM 6: LATÊNCIA - Cria pacote ICMP Echo Request para bots
-func createICMPEchoRequestBot() []byte {
-	packet := make([]byte, 8)
-	packet[0] = 8 // Tipo: Echo Request
-	packet[1] = 0 // Código: 0
-	packet[2] = 0 // Checksum (será calculado)
-	packet[3] = 0 // Checksum (será calculado)
-	packet[4] = 0 // Identifier (16 bits)
-	packet[5] = 1 // Identifier (16 bits)
-	packet[6] = 0 // Sequence Number (16 bits)
-	packet[7] = 1 // Sequence Number (16 bits)
-
-	checksum := calculateICMPChecksumBot(packet)
-	packet[2] = byte(checksum >> 8)
-	packet[3] = byte(checksum & 0xFF)
-
-	return packet
-}
-
-// BAREMA ITEM 6: LATÊNCIA - Calcula checksum ICMP para bots
-func calculateICMPChecksumBot(data []byte) uint16 {
-	var sum uint32
-	for i := 0; i < len(data); i += 2 {
-		if i+1 < len(data) {
-			sum += uint32(data[i])<<8 + uint32(data[i+1])
-		} else {
-			sum += uint32(data[i]) << 8
-		}
-	}
-
-	for sum>>16 != 0 {
-		sum = (sum & 0xFFFF) + (sum >> 16)
-	}
-
-	return uint16(^sum)
-}
-
-func enviarComando(b *Bot, comando string, dados interface{}) {
-	var rawDados json.RawMessage
-	if dados != nil {
-		jsonBytes, _ := json.Marshal(dados)
-		rawDados = jsonBytes
-	}
-	msg := protocolo.Mensagem{Comando: comando, Dados: rawDados}
-	b.Encoder.Encode(msg)
-}
-
 func main() {
+	opcoesTLS.RegistrarFlags(flag.CommandLine)
+	flag.StringVar(&urlMetricas, "metricas", "", "URL do /metrics do servidor para acompanhar durante o teste (ex.: http://servidor:8081/metrics)")
+	flag.Parse()
+
 	log.Printf("Iniciando teste de estresse com %d bots por %v (aquecimento de %v)...", numBots, testDuration, rampUpDuration)
 
 	// BAREMA ITEM 9: TESTES - Aguarda servidor estar pronto antes de iniciar bots
@@ -313,6 +220,11 @@ func main() {
 
 	log.Println("Todos os bots foram iniciados. Teste em andamento...")
 
+	// BAREMA ITEM 6: LATÊNCIA - Acompanha as métricas do servidor enquanto a carga é aplicada
+	if urlMetricas != "" {
+		go acompanharMetricas(ctx, report)
+	}
+
 	// Goroutine para esperar o fim do teste e imprimir o relatório.
 	go func() {
 		wg.Wait()
@@ -326,6 +238,65 @@ func main() {
 	// BAREMA ITEM 9: TESTES - Relatório já será impresso pela goroutine acima
 }
 
+// BAREMA ITEM 6: LATÊNCIA - Coleta periodicamente o /metrics do servidor e registra
+// as séries acompanhadas junto com o estado dos bots
+func acompanharMetricas(ctx context.Context, report *TestReport) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			valores, err := coletarMetricas(urlMetricas)
+			if err != nil {
+				log.Printf("Falha ao coletar métricas do servidor: %v", err)
+				continue
+			}
+			report.mu.Lock()
+			report.metricasServidor = valores
+			conexoes, partidas := report.connectionsSucceeded, report.gamesCompleted
+			report.mu.Unlock()
+
+			partes := make([]string, 0, len(metricasAcompanhadas))
+			for _, nome := range metricasAcompanhadas {
+				partes = append(partes, fmt.Sprintf("%s=%g", nome, valores[nome]))
+			}
+			log.Printf("[bots] conexões=%d partidas=%d | [servidor] %s", conexoes, partidas, strings.Join(partes, " "))
+		}
+	}
+}
+
+// coletarMetricas lê o formato de texto do Prometheus e devolve série -> valor
+func coletarMetricas(url string) (map[string]float64, error) {
+	cliente := http.Client{Timeout: 5 * time.Second}
+	resp, err := cliente.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	valores := make(map[string]float64)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		linha := scanner.Text()
+		if linha == "" || strings.HasPrefix(linha, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(linha, ' ')
+		if i < 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(linha[i+1:], 64); err == nil {
+			valores[linha[:i]] = v
+		}
+	}
+	return valores, scanner.Err()
+}
+
 func printReport(r *TestReport) {
 	fmt.Println("\n======================================")
 	fmt.Println("    Relatório Final do Teste de Estresse")
@@ -370,5 +341,18 @@ func printReport(r *TestReport) {
 		fmt.Println("Nenhuma medição de latência registrada.")
 	}
 
+	if urlMetricas != "" {
+		if valores, err := coletarMetricas(urlMetricas); err == nil {
+			r.metricasServidor = valores
+		}
+	}
+	if len(r.metricasServidor) > 0 {
+		fmt.Println("--------------------------------------")
+		fmt.Println("Métricas do Servidor:")
+		for _, nome := range metricasAcompanhadas {
+			fmt.Printf("  %-55s %g\n", nome, r.metricasServidor[nome])
+		}
+	}
+
 	fmt.Println("======================================")
 }
@@ -0,0 +1,392 @@
+package clientesdk
+
+// ===================== BAREMA ITEM 3: API REMOTA =====================
+// SDK para programas Go que falam com o servidor do jogo.
+// Concentra o que todo cliente precisa: conectar (com TLS opcional),
+// codificar os comandos do protocolo, entregar as mensagens do servidor já
+// decodificadas em um canal de eventos, responder aos PINGs do servidor,
+// medir a latência com PING/PONG e, se pedido, reconectar quando a conexão cai. O cliente interativo e os
+// testes de estresse são construídos sobre ele.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"meujogo/protocolo"
+	"meujogo/seguranca"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDesconectado indica que não há conexão ativa no momento do envio
+var ErrDesconectado = errors.New("clientesdk: sem conexão com o servidor")
+
+// Limites da espera entre tentativas de conexão (dobra a cada falha)
+const (
+	esperaInicial = 500 * time.Millisecond
+	esperaMaxima  = 30 * time.Second
+)
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Opções de conexão
+type Opcoes struct {
+	Endereco          string                  // host:porta do servidor
+	TLS               seguranca.OpcoesCliente // TLS opcional (CA, TOFU, TLS mútuo)
+	Timeout           time.Duration           // Timeout de cada tentativa de conexão (padrão 10s)
+	Nome              string                  // Se informado, o LOGIN é enviado logo após conectar
+	Senha             string                  // Senha enviada com o Nome (registra o nome no primeiro uso)
+	TentativasConexao int                     // Tentativas na conexão inicial (padrão 1)
+	Reconectar        bool                    // Reconecta sem limite de tentativas quando a conexão cai
+	TamanhoEventos    int                     // Capacidade do canal de eventos (padrão 64)
+	EntregarPings     bool                    // Entrega também os PINGs do servidor (que continuam respondidos)
+	IntervaloPing     time.Duration           // Se positivo, envia PINGs periódicos para manter Latencia atualizada
+
+	// Discar substitui Endereco/TLS, por exemplo para usar um transporte em memória
+	Discar func(ctx context.Context) (net.Conn, error)
+}
+
+// BAREMA ITEM 3: API REMOTA - Conexão de um jogador com o servidor
+type Cliente struct {
+	opcoes  Opcoes
+	eventos chan Evento
+	ctx     context.Context
+	parar   context.CancelFunc
+
+	mutex   sync.Mutex // Protege encoder, nome e canais
+	encoder *json.Encoder
+	nome    string   // Último nome usado no LOGIN, reenviado ao reconectar
+	senha   string   // Senha do último LOGIN, reenviada com o nome
+	canais  []string // Canais do lobby em que entrou, reinscritos ao reconectar
+
+	// BAREMA ITEM 6: LATÊNCIA - RTT da conexão atual, medido pelos PONGs
+	latencia       protocolo.Latencia
+	pingAutomatico atomic.Int64 // Timestamp do último PING periódico, cujo PONG não vira evento
+}
+
+// Conectar abre a conexão e começa a receber mensagens em Eventos
+func Conectar(ctx context.Context, o Opcoes) (*Cliente, error) {
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.TentativasConexao <= 0 {
+		o.TentativasConexao = 1
+	}
+	if o.TamanhoEventos <= 0 {
+		o.TamanhoEventos = 64
+	}
+
+	c := &Cliente{opcoes: o, eventos: make(chan Evento, o.TamanhoEventos)}
+	c.ctx, c.parar = context.WithCancel(ctx)
+
+	conn, err := c.discarComEspera(o.TentativasConexao)
+	if err != nil {
+		c.parar()
+		return nil, err
+	}
+	pararAoFechar := c.usar(conn)
+	if o.Nome != "" {
+		if err := c.LoginComSenha(o.Nome, o.Senha); err != nil {
+			c.Fechar()
+			return nil, err
+		}
+	}
+	go c.receber(conn, pararAoFechar)
+	if o.IntervaloPing > 0 {
+		go c.pingar(o.IntervaloPing)
+	}
+	return c, nil
+}
+
+// Eventos entrega as mensagens do servidor na ordem em que chegaram.
+// PINGs do servidor são respondidos automaticamente e só aparecem aqui com
+// Opcoes.EntregarPings.
+// O canal é fechado quando o cliente termina (Fechar, ou queda sem Reconectar)
+func (c *Cliente) Eventos() <-chan Evento {
+	return c.eventos
+}
+
+// Fechar avisa o servidor (QUIT) e encerra a conexão
+func (c *Cliente) Fechar() error {
+	c.Enviar("QUIT", nil)
+	c.parar() // Fecha a conexão atual, inclusive uma reconexão em andamento
+	return nil
+}
+
+/* ====================== Comandos ====================== */
+
+// Login identifica o jogador; o nome é reenviado automaticamente após reconexões.
+// Sem senha, o servidor não entrega amigos nem mensagens privadas
+func (c *Cliente) Login(nome string) error {
+	return c.LoginComSenha(nome, "")
+}
+
+// LoginComSenha identifica o jogador com um nome registrado (ou o registra,
+// se ainda estiver livre); nome e senha são reenviados após reconexões
+func (c *Cliente) LoginComSenha(nome, senha string) error {
+	c.mutex.Lock()
+	c.nome, c.senha = nome, senha
+	c.mutex.Unlock()
+	return c.Enviar("LOGIN", protocolo.DadosLogin{Nome: nome, Senha: senha})
+}
+
+func (c *Cliente) EntrarNaFila() error {
+	return c.Enviar("ENTRAR_NA_FILA", nil)
+}
+
+func (c *Cliente) ComprarPacote(quantidade int) error {
+	return c.Enviar("COMPRAR_PACOTE", protocolo.ComprarPacoteReq{Quantidade: quantidade})
+}
+
+func (c *Cliente) JogarCarta(cartaID string) error {
+	return c.Enviar("JOGAR_CARTA", protocolo.DadosJogarCarta{CartaID: cartaID})
+}
+
+func (c *Cliente) Chat(texto string) error {
+	return c.Enviar("ENVIAR_CHAT", protocolo.DadosEnviarChat{Texto: texto})
+}
+
+// ChatCanal envia uma mensagem a um canal do lobby (é preciso ter entrado nele)
+func (c *Cliente) ChatCanal(canal, texto string) error {
+	return c.Enviar("ENVIAR_CHAT", protocolo.DadosEnviarChat{Texto: texto, Canal: canal})
+}
+
+// EntrarCanal inscreve o jogador em um canal do lobby; o histórico chega em
+// Eventos como HISTORICO_CANAL. A inscrição é refeita após reconexões
+func (c *Cliente) EntrarCanal(canal string) error {
+	c.mutex.Lock()
+	if !slices.Contains(c.canais, canal) {
+		c.canais = append(c.canais, canal)
+	}
+	c.mutex.Unlock()
+	return c.Enviar("ENTRAR_CANAL", protocolo.DadosCanal{Canal: canal})
+}
+
+func (c *Cliente) SairCanal(canal string) error {
+	c.mutex.Lock()
+	c.canais = slices.DeleteFunc(c.canais, func(n string) bool { return n == canal })
+	c.mutex.Unlock()
+	return c.Enviar("SAIR_CANAL", protocolo.DadosCanal{Canal: canal})
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Adiciona um amigo (ou aceita o pedido dele); a
+// presença aparece quando os dois se adicionaram
+func (c *Cliente) AdicionarAmigo(nome string) error {
+	return c.Enviar("ADICIONAR_AMIGO", protocolo.DadosAmigo{Nome: nome})
+}
+
+func (c *Cliente) RemoverAmigo(nome string) error {
+	return c.Enviar("REMOVER_AMIGO", protocolo.DadosAmigo{Nome: nome})
+}
+
+// ListarAmigos pede a lista, que chega em Eventos como LISTA_AMIGOS
+func (c *Cliente) ListarAmigos() error {
+	return c.Enviar("LISTAR_AMIGOS", nil)
+}
+
+// MensagemPrivada envia um texto a um amigo; se ele estiver offline, o servidor o guarda
+func (c *Cliente) MensagemPrivada(para, texto string) error {
+	return c.Enviar("MENSAGEM_PRIVADA", protocolo.DadosEnviarPrivada{Para: para, Texto: texto})
+}
+
+// BAREMA ITEM 7: PARTIDAS - Convida um amigo para uma sala privada
+func (c *Cliente) Desafiar(nome string) error {
+	return c.Enviar("DESAFIAR", protocolo.DadosAmigo{Nome: nome})
+}
+
+func (c *Cliente) AceitarDesafio(nome string) error {
+	return c.Enviar("ACEITAR_DESAFIO", protocolo.DadosAmigo{Nome: nome})
+}
+
+func (c *Cliente) RecusarDesafio(nome string) error {
+	return c.Enviar("RECUSAR_DESAFIO", protocolo.DadosAmigo{Nome: nome})
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Deixa de receber chat, mensagens privadas e
+// pedidos do jogador; a amizade, se havia, é desfeita
+func (c *Cliente) Bloquear(nome string) error {
+	return c.Enviar("BLOQUEAR", protocolo.DadosAmigo{Nome: nome})
+}
+
+func (c *Cliente) Desbloquear(nome string) error {
+	return c.Enviar("DESBLOQUEAR", protocolo.DadosAmigo{Nome: nome})
+}
+
+// Denunciar envia à moderação o jogador e as últimas mensagens de chat que esta sessão viu
+func (c *Cliente) Denunciar(nome, motivo string) error {
+	return c.Enviar("DENUNCIAR", protocolo.DadosDenuncia{Nome: nome, Motivo: motivo})
+}
+
+func (c *Cliente) VerCartas() error {
+	return c.Enviar("VER_CARTAS", nil)
+}
+
+func (c *Cliente) SairDaSala() error {
+	return c.Enviar("SAIR_DA_SALA", nil)
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Envia um PING; o PONG chega em Eventos com o mesmo
+// timestamp, e Latencia já inclui a nova amostra quando ele é entregue
+func (c *Cliente) Ping() error {
+	return c.Enviar("PING", protocolo.DadosPing{Timestamp: time.Now().UnixMilli()})
+}
+
+// BAREMA ITEM 6: LATÊNCIA - RTT, média móvel e jitter da conexão atual
+func (c *Cliente) Latencia() protocolo.ResumoLatencia {
+	return c.latencia.Resumo()
+}
+
+// pingar mede a latência periodicamente (Opcoes.IntervaloPing)
+func (c *Cliente) pingar(intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		agora := time.Now().UnixMilli()
+		c.pingAutomatico.Store(agora)
+		c.Enviar("PING", protocolo.DadosPing{Timestamp: agora}) // Sem conexão, tenta no próximo
+	}
+}
+
+// Enviar codifica e envia qualquer comando do protocolo
+func (c *Cliente) Enviar(comando string, dados any) error {
+	msg := protocolo.Mensagem{Comando: comando}
+	if dados != nil {
+		b, err := json.Marshal(dados)
+		if err != nil {
+			return err
+		}
+		msg.Dados = b
+	}
+	return c.EnviarMensagem(msg)
+}
+
+// EnviarMensagem envia uma mensagem já montada, sem alterá-la
+func (c *Cliente) EnviarMensagem(msg protocolo.Mensagem) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.encoder == nil {
+		return ErrDesconectado
+	}
+	return c.encoder.Encode(msg)
+}
+
+/* ====================== Conexão ====================== */
+
+func (c *Cliente) discar() (net.Conn, error) {
+	if c.opcoes.Discar != nil {
+		ctx, cancelar := context.WithTimeout(c.ctx, c.opcoes.Timeout)
+		defer cancelar()
+		return c.opcoes.Discar(ctx)
+	}
+	return seguranca.Discar(c.opcoes.Endereco, c.opcoes.Timeout, c.opcoes.TLS)
+}
+
+// discarComEspera tenta conectar até o limite de tentativas (0 = sem limite),
+// dobrando a espera entre elas
+func (c *Cliente) discarComEspera(tentativas int) (net.Conn, error) {
+	espera := esperaInicial
+	for i := 1; ; i++ {
+		conn, err := c.discar()
+		if err == nil {
+			return conn, nil
+		}
+		if tentativas > 0 && i >= tentativas {
+			return nil, err
+		}
+		// Variação aleatória evita que muitos clientes reconectem ao mesmo tempo
+		t := time.NewTimer(espera/2 + rand.N(espera))
+		select {
+		case <-t.C:
+		case <-c.ctx.Done():
+			t.Stop()
+			return nil, c.ctx.Err()
+		}
+		espera = min(espera*2, esperaMaxima)
+	}
+}
+
+// usar torna conn a conexão atual; Fechar passa a fechá-la
+func (c *Cliente) usar(conn net.Conn) (pararAoFechar func() bool) {
+	c.mutex.Lock()
+	c.encoder = json.NewEncoder(conn)
+	c.mutex.Unlock()
+	return context.AfterFunc(c.ctx, func() { conn.Close() })
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Lê do servidor até o cliente ser fechado
+func (c *Cliente) receber(conn net.Conn, pararAoFechar func() bool) {
+	defer close(c.eventos)
+	for {
+		err := c.lerAteFalhar(conn)
+
+		c.mutex.Lock()
+		c.encoder = nil
+		nome, senha := c.nome, c.senha
+		canais := slices.Clone(c.canais)
+		c.mutex.Unlock()
+		pararAoFechar()
+		conn.Close()
+
+		if c.ctx.Err() != nil {
+			return // Fechado por quem usa o SDK
+		}
+		c.emitir(Evento{Comando: EventoDesconectado, Erro: err})
+		if !c.opcoes.Reconectar {
+			return
+		}
+
+		if conn, err = c.discarComEspera(0); err != nil {
+			return // Só falha quando o contexto é cancelado
+		}
+		pararAoFechar = c.usar(conn)
+		c.latencia.Zerar() // Outra conexão, outras amostras
+		if nome != "" {
+			c.LoginComSenha(nome, senha)
+		}
+		for _, canal := range canais {
+			c.Enviar("ENTRAR_CANAL", protocolo.DadosCanal{Canal: canal})
+		}
+		c.emitir(Evento{Comando: EventoReconectado})
+	}
+}
+
+func (c *Cliente) lerAteFalhar(conn net.Conn) error {
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg protocolo.Mensagem
+		if err := decoder.Decode(&msg); err != nil {
+			return err
+		}
+		dados, _ := Decodificar(msg) // Dados malformados chegam só em Bruto
+
+		switch d := dados.(type) {
+		// BAREMA ITEM 6: LATÊNCIA - Responde ao PING do servidor para manter a conexão ativa
+		case protocolo.DadosPing:
+			c.Enviar("PONG", protocolo.DadosPong{Timestamp: d.Timestamp})
+			if !c.opcoes.EntregarPings {
+				continue
+			}
+		// BAREMA ITEM 6: LATÊNCIA - Cada PONG é uma amostra de RTT
+		case protocolo.DadosPong:
+			c.latencia.Registrar(time.Duration(time.Now().UnixMilli()-d.Timestamp) * time.Millisecond)
+			if d.Timestamp == c.pingAutomatico.Load() {
+				continue
+			}
+		}
+		c.emitir(Evento{Comando: msg.Comando, Dados: dados, Bruto: msg})
+	}
+}
+
+func (c *Cliente) emitir(e Evento) {
+	select {
+	case c.eventos <- e:
+	case <-c.ctx.Done():
+	}
+}
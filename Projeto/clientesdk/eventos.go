@@ -0,0 +1,86 @@
+package clientesdk
+
+import (
+	"encoding/json"
+	"meujogo/protocolo"
+)
+
+// Eventos locais, gerados pelo próprio SDK e não pelo servidor
+const (
+	EventoDesconectado = "DESCONECTADO" // A conexão caiu; Erro traz a causa
+	EventoReconectado  = "RECONECTADO"  // Nova conexão estabelecida (LOGIN e canais reenviados)
+)
+
+// BAREMA ITEM 3: API REMOTA - Mensagem recebida do servidor (ou evento local)
+// Dados já vem decodificado no tipo do protocolo correspondente ao comando:
+//
+//	PARTIDA_ENCONTRADA          protocolo.DadosPartidaEncontrada
+//	ATUALIZACAO_JOGO            protocolo.DadosAtualizacaoJogo
+//	FIM_DE_JOGO                 protocolo.DadosFimDeJogo
+//	PACOTE_RESULTADO            protocolo.ComprarPacoteResp
+//	RECEBER_CHAT                protocolo.DadosReceberChat
+//	HISTORICO_CANAL             protocolo.DadosHistoricoCanal
+//	LISTA_AMIGOS                protocolo.DadosListaAmigos
+//	PRESENCA_AMIGO              protocolo.DadosPresenca
+//	MENSAGEM_PRIVADA            protocolo.DadosMensagemPrivada
+//	DESAFIO                     protocolo.DadosAmigo
+//	PING                        protocolo.DadosPing (com Opcoes.EntregarPings)
+//	PONG                        protocolo.DadosPong
+//	LATENCIA                    protocolo.DadosLatencia
+//	ATRASO_JOGADOR              protocolo.DadosAtraso
+//	SISTEMA, ERRO, CARTAS_...   protocolo.DadosErro
+//
+// Comandos desconhecidos ficam com Dados nil; Bruto sempre traz a mensagem
+// exatamente como chegou.
+type Evento struct {
+	Comando string
+	Dados   any
+	Bruto   protocolo.Mensagem
+	Erro    error // Só em EventoDesconectado
+}
+
+// Decodificar converte os dados de uma mensagem do servidor no tipo do protocolo
+func Decodificar(msg protocolo.Mensagem) (any, error) {
+	switch msg.Comando {
+	case "PARTIDA_ENCONTRADA":
+		return decodificarComo[protocolo.DadosPartidaEncontrada](msg.Dados)
+	case "ATUALIZACAO_JOGO":
+		return decodificarComo[protocolo.DadosAtualizacaoJogo](msg.Dados)
+	case "FIM_DE_JOGO":
+		return decodificarComo[protocolo.DadosFimDeJogo](msg.Dados)
+	case "PACOTE_RESULTADO":
+		return decodificarComo[protocolo.ComprarPacoteResp](msg.Dados)
+	case "RECEBER_CHAT":
+		return decodificarComo[protocolo.DadosReceberChat](msg.Dados)
+	case "HISTORICO_CANAL":
+		return decodificarComo[protocolo.DadosHistoricoCanal](msg.Dados)
+	case "LISTA_AMIGOS":
+		return decodificarComo[protocolo.DadosListaAmigos](msg.Dados)
+	case "PRESENCA_AMIGO":
+		return decodificarComo[protocolo.DadosPresenca](msg.Dados)
+	case "MENSAGEM_PRIVADA":
+		return decodificarComo[protocolo.DadosMensagemPrivada](msg.Dados)
+	case "DESAFIO":
+		return decodificarComo[protocolo.DadosAmigo](msg.Dados)
+	case "PING":
+		return decodificarComo[protocolo.DadosPing](msg.Dados)
+	case "PONG":
+		return decodificarComo[protocolo.DadosPong](msg.Dados)
+	case "LATENCIA":
+		return decodificarComo[protocolo.DadosLatencia](msg.Dados)
+	case "ATRASO_JOGADOR":
+		return decodificarComo[protocolo.DadosAtraso](msg.Dados)
+	case "SISTEMA", "ERRO", "CARTAS_DETALHADAS":
+		return decodificarComo[protocolo.DadosErro](msg.Dados)
+	}
+	return nil, nil
+}
+
+// Devolve o valor, não um ponteiro, para permitir type switch direto nos tipos do protocolo
+func decodificarComo[T any](dados json.RawMessage) (any, error) {
+	var v T
+	if err := json.Unmarshal(dados, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package protocolo
+
+// ===================== BAREMA ITEM 6: LATÊNCIA =====================
+// Estimador de latência compartilhado pelo cliente e pelo servidor.
+// Cada lado mede o RTT dos próprios PINGs pelo PONG correspondente; o
+// cálculo segue o estimador do TCP (RFC 6298): a média pesa 1/8 para cada
+// amostra nova e o jitter, 1/4 para o desvio da amostra em relação à média.
+
+import (
+	"sync"
+	"time"
+)
+
+// BAREMA ITEM 6: LATÊNCIA - Estatísticas de RTT de uma conexão (o valor zero está pronto para uso)
+type Latencia struct {
+	mutex    sync.Mutex
+	ultima   time.Duration
+	media    time.Duration
+	jitter   time.Duration
+	amostras int
+}
+
+// Registrar acrescenta uma amostra de RTT
+func (l *Latencia) Registrar(rtt time.Duration) {
+	if rtt < 0 {
+		return // Relógio ajustado entre o PING e o PONG
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.ultima = rtt
+	if l.amostras == 0 {
+		l.media, l.jitter = rtt, rtt/2
+	} else {
+		desvio := l.media - rtt
+		if desvio < 0 {
+			desvio = -desvio
+		}
+		l.jitter += (desvio - l.jitter) / 4
+		l.media += (rtt - l.media) / 8
+	}
+	l.amostras++
+}
+
+// Resumo devolve as estatísticas atuais em milissegundos
+func (l *Latencia) Resumo() ResumoLatencia {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return ResumoLatencia{
+		UltimaMs: milissegundos(l.ultima),
+		MediaMs:  milissegundos(l.media),
+		JitterMs: milissegundos(l.jitter),
+		Amostras: l.amostras,
+	}
+}
+
+// Zerar descarta as amostras (nova sessão)
+func (l *Latencia) Zerar() {
+	l.mutex.Lock()
+	l.ultima, l.media, l.jitter, l.amostras = 0, 0, 0, 0
+	l.mutex.Unlock()
+}
+
+func milissegundos(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
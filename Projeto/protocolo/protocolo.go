@@ -45,7 +45,8 @@ type ComprarPacoteResp struct {
 
 // BAREMA ITEM 7: PARTIDAS - Dados para autenticação do jogador
 type DadosLogin struct {
-	Nome string `json:"nome"` // Nome único do jogador no sistema
+	Nome  string `json:"nome"`            // Nome único do jogador no sistema
+	Senha string `json:"senha,omitempty"` // Registra o nome no primeiro uso; depois, prova que ele é seu
 }
 
 // BAREMA ITEM 7: PARTIDAS - Notificação de que uma partida foi encontrada
@@ -57,7 +58,8 @@ type DadosPartidaEncontrada struct {
 
 // BAREMA ITEM 3: API REMOTA - Dados para envio de mensagens de chat
 type DadosEnviarChat struct {
-	Texto string `json:"texto"` // Conteúdo da mensagem de chat
+	Texto string `json:"texto"`           // Conteúdo da mensagem de chat
+	Canal string `json:"canal,omitempty"` // Canal do lobby; vazio fala na sala (ou, fora dela, no último canal em que entrou)
 }
 
 // BAREMA ITEM 3: API REMOTA - Dados para jogada de carta
@@ -69,8 +71,74 @@ type DadosJogarCarta struct {
 // BAREMA ITEM 3: API REMOTA - Dados para recebimento de mensagens de chat
 // Inclui o nome do remetente para identificação
 type DadosReceberChat struct {
-	NomeJogador string `json:"nomeJogador"` // Nome do jogador que enviou a mensagem
-	Texto       string `json:"texto"`       // Conteúdo da mensagem
+	NomeJogador string `json:"nomeJogador"`     // Nome do jogador que enviou a mensagem
+	Texto       string `json:"texto"`           // Conteúdo da mensagem
+	Canal       string `json:"canal,omitempty"` // Canal do lobby ("" para o chat da sala)
+}
+
+// BAREMA ITEM 3: API REMOTA - Canal do lobby em ENTRAR_CANAL e SAIR_CANAL
+type DadosCanal struct {
+	Canal string `json:"canal"` // Nome do canal (ex.: "global", "troca")
+}
+
+// BAREMA ITEM 3: API REMOTA - Resposta a ENTRAR_CANAL com as últimas mensagens do canal
+type DadosHistoricoCanal struct {
+	Canal     string             `json:"canal"`
+	Membros   int                `json:"membros"`   // Inscritos, já contando quem entrou
+	Mensagens []DadosReceberChat `json:"mensagens"` // Da mais antiga para a mais nova
+}
+
+/* ===================== Amigos e mensagens privadas ===================== */
+
+// Presença de um amigo em LISTA_AMIGOS e PRESENCA_AMIGO
+const (
+	PresencaOffline  = "offline"  // Nenhuma sessão com o nome
+	PresencaOnline   = "online"   // No lobby
+	PresencaFila     = "fila"     // Aguardando um oponente
+	PresencaPartida  = "partida"  // Em uma sala
+	PresencaPendente = "pendente" // Adicionado, mas ainda não adicionou de volta (presença oculta)
+)
+
+// BAREMA ITEM 3: API REMOTA - Jogador alvo de ADICIONAR_AMIGO, REMOVER_AMIGO,
+// DESAFIAR, ACEITAR_DESAFIO, RECUSAR_DESAFIO, BLOQUEAR e DESBLOQUEAR (e autor
+// de DESAFIO)
+type DadosAmigo struct {
+	Nome string `json:"nome"`
+}
+
+// BAREMA ITEM 3: API REMOTA - Um amigo e sua presença
+type DadosPresenca struct {
+	Nome     string `json:"nome"`
+	Presenca string `json:"presenca"` // Uma das constantes Presenca*
+}
+
+// BAREMA ITEM 3: API REMOTA - Resposta a LISTAR_AMIGOS (e a cada mudança na lista)
+type DadosListaAmigos struct {
+	Amigos     []DadosPresenca `json:"amigos"`               // Em ordem alfabética
+	Pedidos    []string        `json:"pedidos,omitempty"`    // Quem adicionou o jogador e ainda não foi adicionado de volta
+	Bloqueados []string        `json:"bloqueados,omitempty"` // Jogadores cujas mensagens não chegam ao jogador
+}
+
+// BAREMA ITEM 3: API REMOTA - MENSAGEM_PRIVADA enviada pelo cliente
+type DadosEnviarPrivada struct {
+	Para  string `json:"para"`
+	Texto string `json:"texto"`
+}
+
+// BAREMA ITEM 3: API REMOTA - MENSAGEM_PRIVADA entregue pelo servidor
+type DadosMensagemPrivada struct {
+	De        string `json:"de"`
+	Texto     string `json:"texto"`
+	EnviadaEm int64  `json:"enviadaEm"`          // Unix em milissegundos
+	Guardada  bool   `json:"guardada,omitempty"` // Enviada enquanto o destinatário estava offline
+}
+
+/* ===================== Moderação ===================== */
+
+// BAREMA ITEM 3: API REMOTA - DENUNCIAR: o servidor anexa as últimas mensagens vistas pelo denunciante
+type DadosDenuncia struct {
+	Nome   string `json:"nome"`
+	Motivo string `json:"motivo,omitempty"`
 }
 
 /* ===================== Atualizações de jogo ===================== */
@@ -86,6 +154,7 @@ type DadosAtualizacaoJogo struct {
 	NumeroRodada    int              `json:"numeroRodada"`    // Número da rodada atual (1, 2, 3...)
 	PontosRodada    map[string]int   `json:"pontosRodada"`    // nome -> pontos na rodada atual
 	PontosPartida   map[string]int   `json:"pontosPartida"`   // nome -> rodadas ganhas na partida
+	MinhaMao        []Carta          `json:"minhaMao"`        // Cartas do destinatário, na ordem de VER_CARTAS
 }
 
 // BAREMA ITEM 3: API REMOTA - Notificação de fim de partida
@@ -99,9 +168,22 @@ type DadosFimDeJogo struct {
 // BAREMA ITEM 4: ENCAPSULAMENTO - Estrutura para mensagens de erro
 // Usada para comunicar erros de validação, operações inválidas, etc.
 type DadosErro struct {
-	Mensagem string `json:"mensagem"` // Descrição do erro ocorrido
+	Mensagem string `json:"mensagem"`         // Descrição do erro ocorrido
+	Codigo   string `json:"codigo,omitempty"` // Tipo do erro (ver constantes Erro*), quando aplicável
 }
 
+// BAREMA ITEM 4: ENCAPSULAMENTO - Códigos de erro tipados enviados em DadosErro.Codigo
+// Permitem que o cliente reaja ao tipo do erro sem interpretar o texto
+const (
+	ErroMensagemGrande      = "MENSAGEM_GRANDE"       // Mensagem maior que o limite do servidor
+	ErroJSONInvalido        = "JSON_INVALIDO"         // Mensagem que não é JSON válido
+	ErroAninhamento         = "ANINHAMENTO_EXCESSIVO" // Dados com objetos/listas aninhados demais
+	ErroComandoDesconhecido = "COMANDO_DESCONHECIDO"  // Comando inexistente no protocolo
+	ErroDadosInvalidos      = "DADOS_INVALIDOS"       // Dados com formato errado para o comando
+	ErroCampoLongo          = "CAMPO_LONGO"           // Texto, nome ou ID acima do tamanho permitido
+	ErroLimiteTaxa          = "LIMITE_TAXA"           // Comandos enviados rápido demais
+)
+
 /* ===================== Ping ===================== */
 
 // BAREMA ITEM 6: LATÊNCIA - Estrutura para medição de latência
@@ -115,3 +197,24 @@ type DadosPing struct {
 type DadosPong struct {
 	Timestamp int64 `json:"timestamp"` // Timestamp ecoado do ping original
 }
+
+// BAREMA ITEM 6: LATÊNCIA - Resumo da latência de uma conexão (ver Latencia)
+type ResumoLatencia struct {
+	UltimaMs float64 `json:"ultimaMs"` // RTT da amostra mais recente
+	MediaMs  float64 `json:"mediaMs"`  // Média móvel exponencial do RTT
+	JitterMs float64 `json:"jitterMs"` // Média móvel do desvio do RTT em relação à média
+	Amostras int     `json:"amostras"` // Amostras (PONGs) consideradas
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Aviso de que um jogador da sala parou (ou voltou a) responder aos PINGs
+type DadosAtraso struct {
+	Jogador       string `json:"jogador"`
+	Atrasado      bool   `json:"atrasado"`      // false quando o jogador volta a responder
+	SemRespostaMs int64  `json:"semRespostaMs"` // Tempo desde o último PONG (só quando atrasado)
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Latência dos jogadores da sala, medida pelo servidor
+// Enviada periodicamente aos jogadores em partida, junto com o PING
+type DadosLatencia struct {
+	Jogadores map[string]ResumoLatencia `json:"jogadores"` // nome -> latência com o servidor
+}
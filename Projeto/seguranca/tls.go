@@ -0,0 +1,228 @@
+package seguranca
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Este pacote concentra a configuração TLS compartilhada pelo servidor e pelos
+// clientes: carregamento de certificados, CA customizada, confiança no primeiro
+// uso (TOFU) e certificados de cliente para TLS mútuo.
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpcoesCliente descreve como um cliente deve estabelecer a conexão TLS
+type OpcoesCliente struct {
+	Habilitado      bool   // Usa TLS na conexão com o servidor
+	ArquivoCA       string // CA customizada (PEM) para validar o servidor
+	TOFU            bool   // Confia no certificado visto na primeira conexão (trust-on-first-use)
+	HostsConhecidos string // Arquivo de impressões digitais do TOFU (padrão: ~/.meujogo/hosts_conhecidos)
+	ArquivoCert     string // Certificado do cliente (PEM) para TLS mútuo
+	ArquivoChave    string // Chave privada do cliente (PEM) para TLS mútuo
+	Inseguro        bool   // Não valida o certificado do servidor (apenas para testes)
+}
+
+// RegistrarFlags associa as opções às flags de linha de comando padrão dos clientes
+func (o *OpcoesCliente) RegistrarFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Habilitado, "tls", false, "conecta ao servidor usando TLS")
+	fs.StringVar(&o.ArquivoCA, "tls-ca", "", "CA PEM customizada para validar o servidor")
+	fs.BoolVar(&o.TOFU, "tls-tofu", false, "confia no certificado do servidor visto na primeira conexão")
+	fs.StringVar(&o.HostsConhecidos, "tls-hosts", "", "arquivo de hosts conhecidos do TOFU")
+	fs.StringVar(&o.ArquivoCert, "tls-cert", "", "certificado PEM do cliente (TLS mútuo)")
+	fs.StringVar(&o.ArquivoChave, "tls-chave", "", "chave privada PEM do cliente (TLS mútuo)")
+	fs.BoolVar(&o.Inseguro, "tls-inseguro", false, "não valida o certificado do servidor (apenas testes)")
+}
+
+// OpcoesServidor descreve o certificado do servidor e a política de TLS mútuo
+type OpcoesServidor struct {
+	ArquivoCert      string // Certificado do servidor (PEM)
+	ArquivoChave     string // Chave privada do servidor (PEM)
+	ArquivoCACliente string // CA (PEM) que assina os certificados de cliente aceitos
+	ExigirCliente    bool   // Recusa clientes sem certificado válido
+}
+
+// Habilitado indica se o servidor deve aceitar conexões TLS
+func (o OpcoesServidor) Habilitado() bool { return o.ArquivoCert != "" }
+
+// ConfigServidor monta o tls.Config do listener a partir dos arquivos informados
+func ConfigServidor(o OpcoesServidor) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(o.ArquivoCert, o.ArquivoChave)
+	if err != nil {
+		return nil, fmt.Errorf("carregando certificado do servidor: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - TLS mútuo opcional para a frota de bots
+	if o.ArquivoCACliente != "" {
+		pool, err := carregarCA(o.ArquivoCACliente)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if o.ExigirCliente {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if o.ExigirCliente {
+		return nil, errors.New("exigir certificado de cliente requer uma CA de cliente")
+	}
+	return cfg, nil
+}
+
+// ConfigCliente monta o tls.Config para conectar ao endereço informado (host:porta)
+func ConfigCliente(o OpcoesCliente, endereco string) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(endereco)
+	if err != nil {
+		host = endereco
+	}
+	cfg := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if o.ArquivoCA != "" {
+		pool, err := carregarCA(o.ArquivoCA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.ArquivoCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.ArquivoCert, o.ArquivoChave)
+		if err != nil {
+			return nil, fmt.Errorf("carregando certificado do cliente: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch {
+	case o.Inseguro:
+		cfg.InsecureSkipVerify = true
+	case o.TOFU:
+		// A validação padrão é substituída pela comparação com a impressão
+		// digital registrada na primeira conexão a este host
+		arquivo := o.HostsConhecidos
+		if arquivo == "" {
+			arquivo = arquivoHostsPadrao()
+		}
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("servidor não apresentou certificado")
+			}
+			return verificarTOFU(arquivo, endereco, cs.PeerCertificates[0])
+		}
+	}
+	return cfg, nil
+}
+
+// Discar conecta ao servidor, usando TLS quando habilitado nas opções
+func Discar(endereco string, timeout time.Duration, o OpcoesCliente) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !o.Habilitado {
+		return dialer.Dial("tcp", endereco)
+	}
+	cfg, err := ConfigCliente(o, endereco)
+	if err != nil {
+		return nil, err
+	}
+	return tls.DialWithDialer(dialer, "tcp", endereco, cfg)
+}
+
+// ImpressaoDigital devolve o SHA-256 do certificado em hexadecimal
+func ImpressaoDigital(cert *x509.Certificate) string {
+	soma := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(soma[:])
+}
+
+func carregarCA(arquivo string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("lendo CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("nenhum certificado válido em %s", arquivo)
+	}
+	return pool, nil
+}
+
+/* ====================== Trust-on-first-use ====================== */
+
+// Protege o arquivo de hosts conhecidos contra conexões simultâneas (ex.: bots)
+var hostsMutex sync.Mutex
+
+func arquivoHostsPadrao() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".meujogo_hosts_conhecidos"
+	}
+	return filepath.Join(home, ".meujogo", "hosts_conhecidos")
+}
+
+// verificarTOFU aceita o certificado se ele já é conhecido para o host, ou
+// registra sua impressão digital caso seja a primeira conexão
+func verificarTOFU(arquivo, host string, cert *x509.Certificate) error {
+	hostsMutex.Lock()
+	defer hostsMutex.Unlock()
+
+	impressao := ImpressaoDigital(cert)
+	conhecidos, err := lerHostsConhecidos(arquivo)
+	if err != nil {
+		return err
+	}
+	if registrada, ok := conhecidos[host]; ok {
+		if registrada != impressao {
+			return fmt.Errorf("certificado de %s mudou (esperado %s, recebido %s); remova a entrada em %s se a troca for legítima",
+				host, registrada, impressao, arquivo)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(arquivo), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(arquivo, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s %s\n", host, impressao)
+	return err
+}
+
+func lerHostsConhecidos(arquivo string) (map[string]string, error) {
+	conhecidos := make(map[string]string)
+	f, err := os.Open(arquivo)
+	if errors.Is(err, os.ErrNotExist) {
+		return conhecidos, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		campos := strings.Fields(scanner.Text())
+		if len(campos) == 2 {
+			conhecidos[campos[0]] = campos[1]
+		}
+	}
+	return conhecidos, scanner.Err()
+}
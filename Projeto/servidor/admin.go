@@ -0,0 +1,651 @@
+package main
+
+// ===================== BAREMA ITEM 1: ARQUITETURA =====================
+// API HTTP de administração embutida no servidor.
+// Permite inspecionar clientes, salas, fila e estoque, e executar ações
+// administrativas (expulsar jogador, fechar sala, mensagem global, conceder
+// cartas, drenar o matchmaking, recarregar as regras de jogo, silenciar e
+// banir jogadores, tratar as denúncias do chat). As rotas /api
+// exigem o token de administrador; /metrics é aberto para que o Prometheus
+// possa coletar sem credenciais.
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"meujogo/protocolo"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// BAREMA ITEM 1: ARQUITETURA - Visão de um cliente conectado exposta pela API
+type infoCliente struct {
+	Nome        string `json:"nome"`
+	Endereco    string `json:"endereco"`
+	Sala        string `json:"sala,omitempty"`
+	Cartas      int    `json:"cartas"`
+	PingMs      int64  `json:"pingMs"`   // Média móvel do RTT
+	JitterMs    int64  `json:"jitterMs"` // Variação do RTT
+	Certificado string `json:"certificado,omitempty"`
+}
+
+// BAREMA ITEM 7: PARTIDAS - Visão de uma sala ativa exposta pela API
+type infoSala struct {
+	ID            string         `json:"id"`
+	Estado        string         `json:"estado"`
+	Jogadores     []string       `json:"jogadores"`
+	NumeroRodada  int            `json:"numeroRodada"`
+	PontosRodada  map[string]int `json:"pontosRodada"`
+	PontosPartida map[string]int `json:"pontosPartida"`
+	Prontos       []string       `json:"prontos"`
+	Privada       bool           `json:"privada"` // Criada por desafio entre amigos
+}
+
+// BAREMA ITEM 7: PARTIDAS - Conteúdo da fila de espera e de pedidos de pacote
+type infoFila struct {
+	Aguardando        []string `json:"aguardando"`
+	PedidosPacote     int      `json:"pedidosPacote"`
+	CapacidadePedidos int      `json:"capacidadePedidos"`
+}
+
+// BAREMA ITEM 8: PACOTES - Níveis de estoque por raridade e por shard
+type infoEstoque struct {
+	Total  map[string]int   `json:"total"`
+	Shards []map[string]int `json:"shards"`
+}
+
+// Corpo das requisições administrativas
+type reqMensagemGlobal struct {
+	Texto string `json:"texto"`
+}
+
+type reqNivelLog struct {
+	Nivel string `json:"nivel"` // debug, info, warn ou error
+}
+
+type infoDrenagem struct {
+	Drenando   bool `json:"drenando"`
+	Encerrando bool `json:"encerrando"`
+}
+
+// BAREMA ITEM 8: PACOTES - Regras de jogo vigentes
+type infoRegras struct {
+	Versao      int         `json:"versao"`
+	CarregadoEm time.Time   `json:"carregadoEm"`
+	Arquivo     string      `json:"arquivo,omitempty"`
+	Regras      *RegrasJogo `json:"regras"`
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Silêncio ou banimento aplicado pela API
+type reqPunicao struct {
+	Duracao    string `json:"duracao"` // Ex.: "10m", "24h"
+	Motivo     string `json:"motivo"`
+	EstenderIP bool   `json:"estenderIP"` // Pune também o IP das sessões conectadas com o nome
+}
+
+type infoPunicao struct {
+	Nome   string    `json:"nome"`
+	IP     string    `json:"ip,omitempty"` // Só nas punições estendidas ao IP de uma sessão do nome
+	Ate    time.Time `json:"ate"`
+	Motivo string    `json:"motivo,omitempty"`
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Punições vigentes
+type infoModeracao struct {
+	Silenciados []infoPunicao `json:"silenciados"`
+	Banidos     []infoPunicao `json:"banidos"`
+}
+
+type reqConcederCartas struct {
+	Quantidade int    `json:"quantidade"`
+	Raridade   string `json:"raridade"` // C, U, R ou L (padrão: sorteio normal de pacote)
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Inicia o servidor HTTP de administração
+// Sem token apenas /metrics é servido; as rotas /api ficam desabilitadas
+func (s *Servidor) iniciarAdmin(endereco, token string) error {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/clientes", s.adminListarClientes)
+	api.HandleFunc("GET /api/salas", s.adminListarSalas)
+	api.HandleFunc("GET /api/fila", s.adminFila)
+	api.HandleFunc("GET /api/estoque", s.adminEstoque)
+	api.HandleFunc("POST /api/clientes/{nome}/expulsar", s.adminExpulsar)
+	api.HandleFunc("POST /api/clientes/{nome}/cartas", s.adminConcederCartas)
+	api.HandleFunc("POST /api/salas/{id}/fechar", s.adminFecharSala)
+	api.HandleFunc("POST /api/mensagem", s.adminMensagemGlobal)
+	api.HandleFunc("GET /api/drenar", s.adminDrenagem)
+	api.HandleFunc("POST /api/drenar", s.adminDrenagem)
+	api.HandleFunc("DELETE /api/drenar", s.adminDrenagem)
+	api.HandleFunc("GET /api/regras", s.adminRegras)
+	api.HandleFunc("POST /api/regras/recarregar", s.adminRecarregarRegras)
+	api.HandleFunc("POST /api/clientes/{nome}/silenciar", s.adminSilenciar)
+	api.HandleFunc("DELETE /api/clientes/{nome}/silenciar", s.adminSilenciar)
+	api.HandleFunc("POST /api/clientes/{nome}/banir", s.adminBanir)
+	api.HandleFunc("DELETE /api/clientes/{nome}/banir", s.adminBanir)
+	api.HandleFunc("GET /api/moderacao", s.adminModeracao)
+	api.HandleFunc("GET /api/denuncias", s.adminDenuncias)
+	api.HandleFunc("DELETE /api/denuncias/{id}", s.adminArquivarDenuncia)
+	api.HandleFunc("GET /api/log", adminNivelLog)
+	api.HandleFunc("PUT /api/log", adminDefinirNivelLog)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.handleMetricas)
+	if token != "" {
+		mux.Handle("/api/", exigirToken(token, api))
+	} else {
+		slog.Warn("API de administração desabilitada: defina -admin-token ou ADMIN_TOKEN")
+	}
+
+	listener, err := net.Listen("tcp", endereco)
+	if err != nil {
+		return err
+	}
+	slog.Info("API de administração e métricas ouvindo", "endereco", endereco)
+	s.admin = &http.Server{Handler: mux}
+	go s.admin.Serve(listener)
+	return nil
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Middleware de autenticação por token
+// Aceita "Authorization: Bearer <token>" ou o cabeçalho "X-Admin-Token"
+func exigirToken(token string, proximo http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recebido := r.Header.Get("X-Admin-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			recebido = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(recebido), []byte(token)) != 1 {
+			responderErro(w, http.StatusUnauthorized, "token de administrador inválido")
+			return
+		}
+		proximo.ServeHTTP(w, r)
+	})
+}
+
+/* ====================== Consultas ====================== */
+
+func (s *Servidor) adminListarClientes(w http.ResponseWriter, r *http.Request) {
+	lista := make([]infoCliente, 0)
+	s.paraCadaCliente(func(c *Cliente) {
+		latencia := c.Latencia.Resumo()
+		info := infoCliente{
+			Nome:        c.nome(),
+			PingMs:      int64(latencia.MediaMs + 0.5),
+			JitterMs:    int64(latencia.JitterMs + 0.5),
+			Certificado: c.Certificado,
+		}
+		info.Endereco = c.Conn.RemoteAddr().String()
+		info.Cartas = len(c.cartas())
+		if sala := c.salaAtual(); sala != nil {
+			info.Sala = sala.ID
+			info.Cartas = len(sala.cartasDe(c))
+		}
+		lista = append(lista, info)
+	})
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Nome < lista[j].Nome })
+	responderJSON(w, http.StatusOK, lista)
+}
+
+func (s *Servidor) adminListarSalas(w http.ResponseWriter, r *http.Request) {
+	lista := make([]infoSala, 0)
+	s.salas.Range(func(_, v any) bool {
+		lista = append(lista, v.(*Sala).info())
+		return true
+	})
+	sort.Slice(lista, func(i, j int) bool { return lista[i].ID < lista[j].ID })
+	responderJSON(w, http.StatusOK, lista)
+}
+
+func (s *Servidor) adminFila(w http.ResponseWriter, r *http.Request) {
+	info := infoFila{
+		Aguardando:        make([]string, 0, 1),
+		PedidosPacote:     len(s.packWorkerPool),
+		CapacidadePedidos: cap(s.packWorkerPool),
+	}
+	s.filaMutex.Lock()
+	if s.filaDeEspera != nil {
+		info.Aguardando = append(info.Aguardando, s.filaDeEspera.nome())
+	}
+	s.filaMutex.Unlock()
+	responderJSON(w, http.StatusOK, info)
+}
+
+func (s *Servidor) adminEstoque(w http.ResponseWriter, r *http.Request) {
+	info := infoEstoque{
+		Total:  map[string]int{"C": 0, "U": 0, "R": 0, "L": 0},
+		Shards: make([]map[string]int, len(s.shardedEstoque)),
+	}
+	for i, shard := range s.shardedEstoque {
+		niveis := make(map[string]int, 4)
+		shard.mutex.Lock()
+		for raridade, cartas := range shard.estoque {
+			niveis[raridade] = len(cartas)
+			info.Total[raridade] += len(cartas)
+		}
+		shard.mutex.Unlock()
+		info.Shards[i] = niveis
+	}
+	responderJSON(w, http.StatusOK, info)
+}
+
+// info captura o estado da sala na goroutine da sala
+func (sala *Sala) info() infoSala {
+	info := infoSala{ID: sala.ID, Estado: "ENCERRADA", Jogadores: []string{}, Prontos: []string{}}
+	sala.consultar(func() { info = sala.capturarInfo() })
+	return info
+}
+
+func (sala *Sala) capturarInfo() infoSala {
+	info := infoSala{
+		ID:            sala.ID,
+		Estado:        sala.Estado,
+		Jogadores:     make([]string, 0, len(sala.Jogadores)),
+		NumeroRodada:  sala.NumeroRodada,
+		PontosRodada:  make(map[string]int, len(sala.PontosRodada)),
+		PontosPartida: make(map[string]int, len(sala.PontosPartida)),
+		Prontos:       make([]string, 0, len(sala.Prontos)),
+		Privada:       sala.Privada,
+	}
+	for _, j := range sala.Jogadores {
+		info.Jogadores = append(info.Jogadores, j.nome())
+	}
+	for j, p := range sala.PontosRodada {
+		info.PontosRodada[j.nome()] = p
+	}
+	for j, p := range sala.PontosPartida {
+		info.PontosPartida[j.nome()] = p
+	}
+	for j, pronto := range sala.Prontos {
+		if pronto {
+			info.Prontos = append(info.Prontos, j.nome())
+		}
+	}
+	return info
+}
+
+/* ====================== Ações administrativas ====================== */
+
+// adminExpulsar desconecta todas as sessões com o nome (um nome verificado
+// pode estar conectado em mais de um lugar)
+func (s *Servidor) adminExpulsar(w http.ResponseWriter, r *http.Request) {
+	nome := r.PathValue("nome")
+	msg := protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você foi desconectado por um administrador."}),
+	}
+	desconectados := 0
+	s.paraCadaCliente(func(c *Cliente) {
+		if c.nome() != nome {
+			return
+		}
+		// Dá ao writer a chance de entregar o aviso antes de cancelar a sessão;
+		// a conexão é fechada e o clienteReader faz a limpeza normal
+		s.enviar(c, msg)
+		time.AfterFunc(200*time.Millisecond, c.cancelar)
+		desconectados++
+	})
+	if desconectados == 0 {
+		responderErro(w, http.StatusNotFound, "jogador não encontrado")
+		return
+	}
+	slog.Info("administrador expulsou jogador", "jogador", nome, "sessoes", desconectados)
+	responderJSON(w, http.StatusOK, map[string]any{"expulso": nome, "desconectados": desconectados})
+}
+
+func (s *Servidor) adminFecharSala(w http.ResponseWriter, r *http.Request) {
+	valor, ok := s.salas.Load(r.PathValue("id"))
+	if !ok {
+		responderErro(w, http.StatusNotFound, "sala não encontrada")
+		return
+	}
+	sala := valor.(*Sala)
+	// Sem jogadores, a goroutine da sala encerra e a remove do servidor
+	if !sala.consultar(sala.fechar) {
+		responderErro(w, http.StatusNotFound, "sala não encontrada")
+		return
+	}
+	sala.log().Info("administrador fechou a sala")
+	responderJSON(w, http.StatusOK, map[string]string{"fechada": sala.ID})
+}
+
+func (s *Servidor) adminMensagemGlobal(w http.ResponseWriter, r *http.Request) {
+	var req reqMensagemGlobal
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Texto) == "" {
+		responderErro(w, http.StatusBadRequest, "corpo esperado: {\"texto\": \"...\"}")
+		return
+	}
+	msg := protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[ADMIN] " + req.Texto}),
+	}
+	enviados := 0
+	s.paraCadaCliente(func(c *Cliente) {
+		if s.enviar(c, msg) {
+			enviados++
+		}
+	})
+	responderJSON(w, http.StatusOK, map[string]int{"enviados": enviados})
+}
+
+func (s *Servidor) adminConcederCartas(w http.ResponseWriter, r *http.Request) {
+	var req reqConcederCartas
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Quantidade <= 0 || req.Quantidade > 100 {
+		responderErro(w, http.StatusBadRequest, "corpo esperado: {\"quantidade\": 1..100, \"raridade\": \"C|U|R|L\"}")
+		return
+	}
+	switch req.Raridade {
+	case "", "C", "U", "R", "L":
+	default:
+		responderErro(w, http.StatusBadRequest, "raridade inválida")
+		return
+	}
+	cliente := s.buscarClientePorNome(r.PathValue("nome"))
+	if cliente == nil {
+		responderErro(w, http.StatusNotFound, "jogador não encontrado")
+		return
+	}
+	defer cliente.soltar()
+
+	regras := s.regras.Load()
+	cartas := make([]Carta, 0, req.Quantidade)
+	for i := 0; i < req.Quantidade; i++ {
+		raridade := req.Raridade
+		if raridade == "" {
+			raridade = regras.sampleRaridade()
+		}
+		c, ok := s.takeOneByRarityWithDowngrade(raridade)
+		if !ok {
+			c = s.gerarCartaComumBasica(regras)
+		}
+		cartas = append(cartas, c)
+	}
+	// Fora de uma sala as cartas vão para o inventário; dentro, a mão só é
+	// alterada pela goroutine da sala
+	sala := cliente.guardarNoLobby(cartas)
+	entregue := sala == nil
+	if !entregue {
+		sala.consultar(func() {
+			if sala.membro(cliente) {
+				sala.adicionarCartas(cliente, cartas)
+				entregue = true
+			}
+		})
+	}
+	if !entregue && cliente.guardarNoLobby(cartas) == nil {
+		entregue = true // Saiu da sala enquanto as cartas eram separadas
+	}
+	if !entregue {
+		s.devolverAoEstoque(cartas) // Trocou de sala no meio da concessão
+		responderErro(w, http.StatusConflict, "jogador mudou de sala durante a concessão; tente de novo")
+		return
+	}
+	s.enviar(cliente, protocolo.Mensagem{
+		Comando: "PACOTE_RESULTADO",
+		Dados:   mustJSON(protocolo.ComprarPacoteResp{Cartas: cartas}),
+	})
+	s.enviar(cliente, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] Um administrador concedeu %d cartas a você.", len(cartas))}),
+	})
+	cliente.log().Info("administrador concedeu cartas", "quantidade", len(cartas))
+	responderJSON(w, http.StatusOK, protocolo.ComprarPacoteResp{Cartas: cartas})
+}
+
+// BAREMA ITEM 7: PARTIDAS - Modo de drenagem: POST suspende novas partidas,
+// DELETE volta a formá-las e GET apenas consulta
+func (s *Servidor) adminDrenagem(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.definirDrenagem(true)
+	case http.MethodDelete:
+		if s.encerrando.Load() {
+			responderErro(w, http.StatusConflict, "servidor em desligamento")
+			return
+		}
+		s.definirDrenagem(false)
+	}
+	responderJSON(w, http.StatusOK, infoDrenagem{Drenando: s.drenando.Load(), Encerrando: s.encerrando.Load()})
+}
+
+// BAREMA ITEM 8: PACOTES - Consulta e recarrega as regras de jogo
+func (s *Servidor) adminRegras(w http.ResponseWriter, r *http.Request) {
+	regras := s.regras.Load()
+	responderJSON(w, http.StatusOK, infoRegras{Versao: regras.Versao, CarregadoEm: regras.CarregadoEm, Arquivo: s.cfg.ArquivoRegras, Regras: regras})
+}
+
+func (s *Servidor) adminRecarregarRegras(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.ArquivoRegras == "" {
+		responderErro(w, http.StatusConflict, "nenhum arquivo de regras configurado (-regras)")
+		return
+	}
+	regras, err := s.recarregarRegras("api")
+	if err != nil {
+		// As regras anteriores continuam valendo
+		responderErro(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	responderJSON(w, http.StatusOK, infoRegras{Versao: regras.Versao, CarregadoEm: regras.CarregadoEm, Arquivo: s.cfg.ArquivoRegras, Regras: regras})
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Consulta e altera o nível de log em tempo de execução
+func adminNivelLog(w http.ResponseWriter, r *http.Request) {
+	responderJSON(w, http.StatusOK, reqNivelLog{Nivel: nivelLog.Level().String()})
+}
+
+func adminDefinirNivelLog(w http.ResponseWriter, r *http.Request) {
+	var req reqNivelLog
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		responderErro(w, http.StatusBadRequest, "corpo esperado: {\"nivel\": \"debug|info|warn|error\"}")
+		return
+	}
+	if err := definirNivelLog(req.Nivel); err != nil {
+		responderErro(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slog.Warn("nível de log alterado pela API de administração", "nivel", nivelLog.Level().String())
+	responderJSON(w, http.StatusOK, reqNivelLog{Nivel: nivelLog.Level().String()})
+}
+
+/* ====================== Moderação ====================== */
+
+// BAREMA ITEM 2: COMUNICAÇÃO - POST silencia o jogador por um prazo; DELETE retira o silêncio
+func (s *Servidor) adminSilenciar(w http.ResponseWriter, r *http.Request) {
+	nome := r.PathValue("nome")
+	if r.Method == http.MethodDelete {
+		if !s.retirarPunicao(w, s.moderacao.silencios(), nome) {
+			return
+		}
+		s.avisarNome(nome, "[SISTEMA] Um administrador retirou o seu silêncio.")
+		slog.Info("administrador retirou silêncio", "jogador", nome)
+		responderJSON(w, http.StatusOK, map[string]string{"liberado": nome})
+		return
+	}
+	p, ok := s.aplicarPunicao(w, r, s.moderacao.silencios(), nome)
+	if !ok {
+		return
+	}
+	s.avisarNome(nome, "[SISTEMA] Você foi silenciado por um administrador "+descreverPunicao(p)+".")
+	slog.Info("administrador silenciou jogador", "jogador", nome, "ate", p.Ate, "motivo", p.Motivo)
+	responderJSON(w, http.StatusOK, infoPunicao{Nome: nome, Ate: p.Ate, Motivo: p.Motivo})
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - POST bane o nome por um prazo e encerra suas
+// sessões; DELETE retira o banimento
+func (s *Servidor) adminBanir(w http.ResponseWriter, r *http.Request) {
+	nome := r.PathValue("nome")
+	if r.Method == http.MethodDelete {
+		if !s.retirarPunicao(w, s.moderacao.banimentos(), nome) {
+			return
+		}
+		slog.Info("administrador retirou banimento", "jogador", nome)
+		responderJSON(w, http.StatusOK, map[string]string{"liberado": nome})
+		return
+	}
+	p, ok := s.aplicarPunicao(w, r, s.moderacao.banimentos(), nome)
+	if !ok {
+		return
+	}
+	msg := protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você foi banido deste servidor " + descreverPunicao(p) + "."}),
+	}
+	desconectados := 0
+	s.paraCadaCliente(func(c *Cliente) {
+		if c.nome() != nome {
+			return
+		}
+		// Como em adminExpulsar: o aviso sai antes de a sessão ser cancelada
+		s.enviar(c, msg)
+		time.AfterFunc(200*time.Millisecond, c.cancelar)
+		desconectados++
+	})
+	slog.Info("administrador baniu jogador", "jogador", nome, "ate", p.Ate, "motivo", p.Motivo, "sessoes", desconectados)
+	responderJSON(w, http.StatusOK, map[string]any{"banido": infoPunicao{Nome: nome, Ate: p.Ate, Motivo: p.Motivo}, "desconectados": desconectados})
+}
+
+// aplicarPunicao lê o prazo e o motivo do corpo e registra a punição, também
+// nos IPs das sessões conectadas com o nome se o pedido traz estenderIP
+func (s *Servidor) aplicarPunicao(w http.ResponseWriter, r *http.Request, t tipoPunicao, nome string) (punicao, bool) {
+	var req reqPunicao
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		responderErro(w, http.StatusBadRequest, "corpo esperado: {\"duracao\": \"10m\", \"motivo\": \"...\"}")
+		return punicao{}, false
+	}
+	duracao, err := time.ParseDuration(req.Duracao)
+	if err != nil || duracao <= 0 {
+		responderErro(w, http.StatusBadRequest, "duracao deve ser um prazo positivo, ex.: \"10m\" ou \"24h\"")
+		return punicao{}, false
+	}
+	if nome == "" || utf8.RuneCountInString(nome) > s.cfg.MaxNome {
+		responderErro(w, http.StatusBadRequest, "nome de jogador inválido")
+		return punicao{}, false
+	}
+	if utf8.RuneCountInString(req.Motivo) > s.cfg.MaxTextoChat {
+		responderErro(w, http.StatusBadRequest, fmt.Sprintf("motivo maior que %d caracteres", s.cfg.MaxTextoChat))
+		return punicao{}, false
+	}
+	p := punicao{Ate: time.Now().Add(duracao), Motivo: strings.TrimSpace(req.Motivo)}
+	s.moderacao.mutex.Lock()
+	t.nomes[nome] = p
+	s.moderacao.mutex.Unlock()
+	if !req.EstenderIP {
+		return p, true
+	}
+	s.paraCadaCliente(func(c *Cliente) {
+		if c.nome() == nome {
+			s.moderacao.vincular(t, ipDe(c.Conn.RemoteAddr()), nome, p)
+		}
+	})
+	return p, true
+}
+
+// retirarPunicao libera o nome e os IPs aos quais a punição dele se estendeu;
+// responde 404 se o nome não tinha punição vigente
+func (s *Servidor) retirarPunicao(w http.ResponseWriter, t tipoPunicao, nome string) bool {
+	if _, ok := s.moderacao.vigente(t.nomes, nome); !ok {
+		responderErro(w, http.StatusNotFound, "jogador sem punição vigente")
+		return false
+	}
+	s.moderacao.mutex.Lock()
+	delete(t.nomes, nome)
+	s.moderacao.desvincular(t, nome)
+	s.moderacao.mutex.Unlock()
+	return true
+}
+
+// avisarNome envia uma mensagem de SISTEMA a todas as sessões com o nome
+func (s *Servidor) avisarNome(nome, texto string) {
+	s.paraCadaCliente(func(c *Cliente) {
+		if c.nome() == nome {
+			s.avisar(c, texto)
+		}
+	})
+}
+
+func (s *Servidor) adminModeracao(w http.ResponseWriter, r *http.Request) {
+	info := infoModeracao{Silenciados: []infoPunicao{}, Banidos: []infoPunicao{}}
+	m := s.moderacao
+	m.mutex.Lock()
+	m.expirar(time.Now())
+	for nome, p := range m.Silenciados {
+		info.Silenciados = append(info.Silenciados, infoPunicao{Nome: nome, Ate: p.Ate, Motivo: p.Motivo})
+	}
+	for nome, p := range m.Banidos {
+		info.Banidos = append(info.Banidos, infoPunicao{Nome: nome, Ate: p.Ate, Motivo: p.Motivo})
+	}
+	for ip, p := range m.SilenciosIP {
+		info.Silenciados = append(info.Silenciados, infoPunicao{Nome: p.Nome, IP: ip, Ate: p.Ate, Motivo: p.Motivo})
+	}
+	for ip, p := range m.BanidosIP {
+		info.Banidos = append(info.Banidos, infoPunicao{Nome: p.Nome, IP: ip, Ate: p.Ate, Motivo: p.Motivo})
+	}
+	m.mutex.Unlock()
+	porNome := func(a, b infoPunicao) int {
+		return cmp.Or(strings.Compare(a.Nome, b.Nome), strings.Compare(a.IP, b.IP))
+	}
+	slices.SortFunc(info.Silenciados, porNome)
+	slices.SortFunc(info.Banidos, porNome)
+	responderJSON(w, http.StatusOK, info)
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Fila de denúncias, da mais antiga para a mais nova
+func (s *Servidor) adminDenuncias(w http.ResponseWriter, r *http.Request) {
+	s.moderacao.mutex.Lock()
+	lista := append([]denuncia{}, s.moderacao.Denuncias...)
+	s.moderacao.mutex.Unlock()
+	responderJSON(w, http.StatusOK, lista)
+}
+
+// adminArquivarDenuncia retira da fila uma denúncia já analisada
+func (s *Servidor) adminArquivarDenuncia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		responderErro(w, http.StatusBadRequest, "id de denúncia inválido")
+		return
+	}
+	m := s.moderacao
+	m.mutex.Lock()
+	i := slices.IndexFunc(m.Denuncias, func(d denuncia) bool { return d.ID == id })
+	if i >= 0 {
+		m.Denuncias = slices.Delete(m.Denuncias, i, i+1)
+	}
+	m.mutex.Unlock()
+	if i < 0 {
+		responderErro(w, http.StatusNotFound, "denúncia não encontrada")
+		return
+	}
+	slog.Info("administrador arquivou denúncia", "id", id)
+	responderJSON(w, http.StatusOK, map[string]int{"arquivada": id})
+}
+
+/* ====================== Utilidades HTTP ====================== */
+
+// buscarClientePorNome devolve o cliente retido; quem chama deve soltá-lo
+func (s *Servidor) buscarClientePorNome(nome string) *Cliente {
+	var encontrado *Cliente
+	s.paraCadaCliente(func(c *Cliente) {
+		if encontrado == nil && c.nome() == nome {
+			c.reter()
+			encontrado = c
+		}
+	})
+	return encontrado
+}
+
+func responderJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func responderErro(w http.ResponseWriter, status int, mensagem string) {
+	responderJSON(w, status, protocolo.DadosErro{Mensagem: mensagem})
+}
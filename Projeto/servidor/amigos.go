@@ -0,0 +1,806 @@
+package main
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Amigos, presença, mensagens privadas e desafios diretos.
+// A rede só aceita identidades verificadas: o nome do certificado, com TLS
+// mútuo, ou um nome registrado com senha (o primeiro LOGIN com senha registra
+// o nome, e os seguintes precisam da mesma senha). Os dois tipos não se
+// misturam: um certificado não assume um nome com senha, e um nome já visto
+// em certificado nunca recebe senha. Quem entra só com o nome é anônimo:
+// joga e conversa no lobby, mas fica fora da rede e não pode usar um nome
+// registrado ou de certificado. Um nome com sessão ativa recusa outro LOGIN, a não ser
+// que as duas sessões sejam verificadas (o mesmo dono em dois lugares). A
+// amizade vale quando os dois jogadores se adicionaram: só então um vê a
+// presença do outro (online, na fila ou em partida, enviada a cada mudança),
+// troca mensagens privadas e pode desafiá-lo para uma sala privada. Mensagens
+// para um amigo offline ficam guardadas (até -mensagens-guardadas) e são
+// entregues no próximo LOGIN. Cada jogador também tem uma lista de bloqueio:
+// bloquear desfaz a amizade, e as mensagens do bloqueado deixam de chegar em
+// qualquer chat. Contas, listas e mensagens guardadas são gravadas em
+// -arquivo-amigos no desligamento e lidas no boot.
+//
+// Todo o estado fica sob o mutex da rede, que é sempre o último a ser obtido
+// (depois de filaMutex, por exemplo). Sob ele só se enfileiram mensagens, e
+// as sessões registradas seguem vivas até desconectarDaRede, chamada antes de
+// o objeto Cliente voltar ao pool, então o envio dispensa reter o cliente.
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"meujogo/protocolo"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Prazo para o amigo desafiado aceitar
+const validadeDesafio = time.Minute
+
+// Rodadas de SHA-256 no resumo das senhas: encarecem cada tentativa de adivinhá-las
+const rodadasSenha = 20000
+
+// Motivos de recusa do LOGIN
+var (
+	errNomeEmUso      = errors.New("o nome já está em uso por outra sessão; escolha outro")
+	errNomeRegistrado = errors.New("o nome está registrado; entre com a senha dele ou escolha outro")
+	errSenhaIncorreta = errors.New("senha incorreta para este nome")
+	errNomeComSenha   = errors.New("o nome do certificado já está registrado com senha")
+	errNomeDoCert     = errors.New("o nome pertence a um certificado de cliente; escolha outro")
+)
+
+// Ordem das presenças quando o mesmo nome tem várias sessões: vale a mais ocupada
+var pesoPresenca = map[string]int{
+	protocolo.PresencaOnline:  1,
+	protocolo.PresencaFila:    2,
+	protocolo.PresencaPartida: 3,
+}
+
+// desafio identifica um convite pendente de de para para
+type desafio struct{ de, para string }
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Grafo de amizades e sessões conectadas
+type redeAmigos struct {
+	mutex     sync.Mutex
+	amigos    map[string][]string                         // Nome -> nomes que ele adicionou, em ordem
+	recebidos map[string][]string                         // Índice inverso: nome -> quem o adicionou
+	bloqueios map[string][]string                         // Nome -> nomes que ele bloqueou, em ordem
+	caixas    map[string][]protocolo.DadosMensagemPrivada // Mensagens guardadas por destinatário
+	contas    map[string]contaJogador                     // Nomes registrados com senha
+	cns       map[string]bool                             // Nomes já verificados por certificado (nunca recebem senha)
+	sessoes   map[string]map[uint64]*sessaoRede           // Nome -> sessões verificadas conectadas com ele
+	anonimos  map[string]uint64                           // Nome -> sessão que o usa sem verificação
+	nomes     map[uint64]string                           // Sessão -> nome do LOGIN (verificada ou anônima)
+	anunciada map[string]string                           // Última presença enviada aos amigos (ausente = offline)
+	desafios  map[desafio]time.Time                       // Convites pendentes e seu vencimento
+}
+
+type sessaoRede struct {
+	c        *Cliente
+	presenca string
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Conteúdo de -arquivo-amigos
+type amigosPersistidos struct {
+	SalvoEm   time.Time                                   `json:"salvoEm"`
+	Amigos    map[string][]string                         `json:"amigos"`
+	Bloqueios map[string][]string                         `json:"bloqueios"`
+	Caixas    map[string][]protocolo.DadosMensagemPrivada `json:"caixas"`
+	Contas    map[string]contaJogador                     `json:"contas,omitempty"`
+	CNs       []string                                    `json:"cns,omitempty"`
+}
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Senha de um nome registrado, guardada só como resumo com sal
+type contaJogador struct {
+	Sal    string `json:"sal"`    // Hexadecimal, sorteado no registro
+	Resumo string `json:"resumo"` // Hexadecimal de resumirSenha
+}
+
+func novaConta(senha string) contaJogador {
+	sal := make([]byte, 16)
+	rand.Read(sal)
+	return contaJogador{Sal: hex.EncodeToString(sal), Resumo: hex.EncodeToString(resumirSenha(sal, senha))}
+}
+
+// confere compara a senha com o resumo em tempo constante
+func (conta contaJogador) confere(senha string) bool {
+	sal, err := hex.DecodeString(conta.Sal)
+	if err != nil {
+		return false
+	}
+	esperado, err := hex.DecodeString(conta.Resumo)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(resumirSenha(sal, senha), esperado) == 1
+}
+
+func resumirSenha(sal []byte, senha string) []byte {
+	resumo := sha256.Sum256(append(slices.Clone(sal), senha...))
+	for i := 1; i < rodadasSenha; i++ {
+		resumo = sha256.Sum256(append(resumo[:], sal...))
+	}
+	return resumo[:]
+}
+
+// listaBloqueio é a cópia ordenada e imutável dos nomes que a sessão
+// bloqueou, trocada sob o mutex da rede e lida sem lock por salas e canais
+type listaBloqueio struct {
+	nomes atomic.Pointer[[]string]
+}
+
+func (l *listaBloqueio) definir(nomes []string) {
+	if len(nomes) == 0 {
+		l.nomes.Store(nil)
+		return
+	}
+	copia := slices.Clone(nomes)
+	l.nomes.Store(&copia)
+}
+
+// bloqueou diz se as mensagens de nome não devem chegar a esta sessão
+func (c *Cliente) bloqueou(nome string) bool {
+	nomes := c.bloqueados.nomes.Load()
+	if nomes == nil {
+		return false
+	}
+	_, ok := slices.BinarySearch(*nomes, nome)
+	return ok
+}
+
+func novaRedeAmigos() *redeAmigos {
+	return &redeAmigos{
+		amigos:    make(map[string][]string),
+		recebidos: make(map[string][]string),
+		bloqueios: make(map[string][]string),
+		caixas:    make(map[string][]protocolo.DadosMensagemPrivada),
+		contas:    make(map[string]contaJogador),
+		cns:       make(map[string]bool),
+		sessoes:   make(map[string]map[uint64]*sessaoRede),
+		anonimos:  make(map[string]uint64),
+		nomes:     make(map[uint64]string),
+		anunciada: make(map[string]string),
+		desafios:  make(map[desafio]time.Time),
+	}
+}
+
+// carregarAmigos lê as listas e mensagens guardadas no último desligamento
+func carregarAmigos(arquivo string) (*redeAmigos, error) {
+	r := novaRedeAmigos()
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		return r, err
+	}
+	var p amigosPersistidos
+	if err := json.Unmarshal(conteudo, &p); err != nil {
+		return r, fmt.Errorf("amigos %s: %w", arquivo, err)
+	}
+	for nome, lista := range p.Amigos {
+		for _, amigo := range lista {
+			r.adicionar(nome, amigo)
+		}
+	}
+	for nome, lista := range p.Bloqueios {
+		for _, alvo := range lista {
+			r.bloqueios[nome] = inserirOrdenado(r.bloqueios[nome], alvo)
+		}
+	}
+	for nome, caixa := range p.Caixas {
+		r.caixas[nome] = caixa
+	}
+	for nome, conta := range p.Contas {
+		r.contas[nome] = conta
+	}
+	for _, nome := range p.CNs {
+		r.cns[nome] = true
+	}
+	slog.Info("amigos restaurados", "arquivo", arquivo, "jogadores", len(r.amigos), "contas", len(r.contas), "salvoEm", p.SalvoEm)
+	return r, nil
+}
+
+// salvar grava as listas e as mensagens ainda não entregues
+func (r *redeAmigos) salvar(arquivo string) error {
+	r.mutex.Lock()
+	conteudo, err := json.Marshal(amigosPersistidos{SalvoEm: time.Now(), Amigos: r.amigos, Bloqueios: r.bloqueios, Caixas: r.caixas, Contas: r.contas, CNs: chavesOrdenadas(r.cns)})
+	r.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return gravarArquivo(arquivo, conteudo)
+}
+
+/* ====================== Estado (sob r.mutex) ====================== */
+
+func (r *redeAmigos) adicionar(nome, amigo string) {
+	r.amigos[nome] = inserirOrdenado(r.amigos[nome], amigo)
+	r.recebidos[amigo] = inserirOrdenado(r.recebidos[amigo], nome)
+}
+
+func (r *redeAmigos) remover(nome, amigo string) {
+	r.amigos[nome] = removerNome(r.amigos[nome], amigo)
+	r.recebidos[amigo] = removerNome(r.recebidos[amigo], nome)
+	if len(r.amigos[nome]) == 0 {
+		delete(r.amigos, nome)
+	}
+	if len(r.recebidos[amigo]) == 0 {
+		delete(r.recebidos, amigo)
+	}
+}
+
+func (r *redeAmigos) adicionou(nome, amigo string) bool {
+	_, ok := slices.BinarySearch(r.amigos[nome], amigo)
+	return ok
+}
+
+func (r *redeAmigos) mutuos(a, b string) bool {
+	return r.adicionou(a, b) && r.adicionou(b, a)
+}
+
+func (r *redeAmigos) bloqueia(nome, alvo string) bool {
+	_, ok := slices.BinarySearch(r.bloqueios[nome], alvo)
+	return ok
+}
+
+// atualizarBloqueios publica a lista de bloqueio do nome em todas as suas sessões
+func (r *redeAmigos) atualizarBloqueios(nome string) {
+	for _, sr := range r.sessoes[nome] {
+		sr.c.bloqueados.definir(r.bloqueios[nome])
+	}
+}
+
+// presenca resume as sessões do nome, valendo a mais ocupada
+func (r *redeAmigos) presenca(nome string) string {
+	p := protocolo.PresencaOffline
+	for _, sr := range r.sessoes[nome] {
+		if pesoPresenca[sr.presenca] > pesoPresenca[p] {
+			p = sr.presenca
+		}
+	}
+	return p
+}
+
+// lista monta LISTA_AMIGOS: amigos mútuos com presença, os demais pendentes
+func (r *redeAmigos) lista(nome string) protocolo.DadosListaAmigos {
+	lista := protocolo.DadosListaAmigos{Amigos: make([]protocolo.DadosPresenca, 0, len(r.amigos[nome]))}
+	for _, amigo := range r.amigos[nome] {
+		p := protocolo.PresencaPendente
+		if r.adicionou(amigo, nome) {
+			p = r.presenca(amigo)
+		}
+		lista.Amigos = append(lista.Amigos, protocolo.DadosPresenca{Nome: amigo, Presenca: p})
+	}
+	for _, quem := range r.recebidos[nome] {
+		if !r.adicionou(nome, quem) {
+			lista.Pedidos = append(lista.Pedidos, quem)
+		}
+	}
+	lista.Bloqueados = slices.Clone(r.bloqueios[nome])
+	return lista
+}
+
+// paraSessoes envia a mensagem a todas as sessões conectadas com o nome
+func (s *Servidor) paraSessoes(nome string, msg protocolo.Mensagem) {
+	for _, sr := range s.rede.sessoes[nome] {
+		s.enviar(sr.c, msg)
+	}
+}
+
+// enviarLista atualiza a lista de amigos em todas as sessões do nome
+func (s *Servidor) enviarLista(nome string) {
+	s.paraSessoes(nome, protocolo.Mensagem{Comando: "LISTA_AMIGOS", Dados: mustJSON(s.rede.lista(nome))})
+}
+
+// anunciarPresenca envia PRESENCA_AMIGO aos amigos mútuos quando a presença do nome muda
+func (s *Servidor) anunciarPresenca(nome string) {
+	r := s.rede
+	p := r.presenca(nome)
+	if r.anunciada[nome] == p || (p == protocolo.PresencaOffline && r.anunciada[nome] == "") {
+		return
+	}
+	if p == protocolo.PresencaOffline {
+		delete(r.anunciada, nome)
+	} else {
+		r.anunciada[nome] = p
+	}
+	msg := protocolo.Mensagem{
+		Comando: "PRESENCA_AMIGO",
+		Dados:   mustJSON(protocolo.DadosPresenca{Nome: nome, Presenca: p}),
+	}
+	for _, amigo := range r.amigos[nome] {
+		if r.adicionou(amigo, nome) {
+			s.paraSessoes(amigo, msg)
+		}
+	}
+	s.metricas.presencasAnunciadas.Add(1)
+}
+
+/* ====================== Sessões ====================== */
+
+// autenticar confere a senha do nome fora do mutex da rede, já que o resumo
+// é lento de propósito. Devolve se o nome fica verificado e, para um nome
+// ainda livre com senha informada, a conta a registrar. Nomes de certificado
+// e nomes com senha são espaços separados: um nunca entra como o outro
+func (r *redeAmigos) autenticar(nome, senha string, certificado bool) (bool, *contaJogador, error) {
+	r.mutex.Lock()
+	conta, registrada := r.contas[nome]
+	doCert := r.cns[nome]
+	r.mutex.Unlock()
+	switch {
+	case certificado && registrada:
+		return false, nil, errNomeComSenha
+	case certificado:
+		return true, nil, nil
+	case doCert:
+		return false, nil, errNomeDoCert
+	case registrada && senha == "":
+		return false, nil, errNomeRegistrado
+	case registrada && !conta.confere(senha):
+		return false, nil, errSenhaIncorreta
+	case registrada:
+		return true, nil, nil
+	case senha != "":
+		nova := novaConta(senha)
+		return true, &nova, nil
+	}
+	return false, nil, nil
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Reserva o nome do LOGIN para a sessão. Só uma
+// sessão verificada (certificado ou senha) entra na rede e recebe as
+// mensagens guardadas e a lista de amigos; as demais ficam anônimas
+func (s *Servidor) conectarNaRede(c *Cliente, nome, senha string, certificado bool) error {
+	verificada, nova, err := s.rede.autenticar(nome, senha, certificado)
+	if err != nil {
+		return err
+	}
+	presenca := s.presencaAtual(c) // Antes do mutex da rede, que é o último da ordem
+
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// Um nome em uso só aceita outra sessão verificada do mesmo dono
+	if id, ok := r.anonimos[nome]; ok && id != c.Sessao {
+		return errNomeEmUso
+	}
+	for id := range r.sessoes[nome] {
+		if id != c.Sessao && !verificada {
+			return errNomeEmUso
+		}
+	}
+	// Outra sessão pode ter registrado o nome enquanto a senha era resumida
+	if _, ok := r.contas[nome]; ok && certificado {
+		return errNomeComSenha
+	}
+	if nova != nil {
+		if _, ok := r.contas[nome]; ok {
+			return errNomeRegistrado
+		}
+		if r.cns[nome] {
+			return errNomeDoCert
+		}
+		r.contas[nome] = *nova
+	}
+	if certificado {
+		r.cns[nome] = true
+	}
+	if anterior, ok := r.nomes[c.Sessao]; ok {
+		if _, naRede := r.sessoes[anterior][c.Sessao]; anterior == nome && naRede == verificada {
+			return nil // LOGIN repetido com o mesmo nome
+		}
+		s.sairDaRede(c, anterior) // Trocou de nome (ou passou a ser verificada) nesta sessão
+	}
+	r.nomes[c.Sessao] = nome
+	if !verificada {
+		r.anonimos[nome] = c.Sessao
+		return nil
+	}
+	if r.sessoes[nome] == nil {
+		r.sessoes[nome] = make(map[uint64]*sessaoRede)
+	}
+	r.sessoes[nome][c.Sessao] = &sessaoRede{c: c, presenca: presenca}
+	c.bloqueados.definir(r.bloqueios[nome])
+	s.anunciarPresenca(nome)
+
+	for _, m := range r.caixas[nome] {
+		if r.bloqueia(nome, m.De) {
+			continue // Guardada antes do bloqueio
+		}
+		s.lembrarChat(c, linhaChat{De: m.De, Onde: "privada", Texto: m.Texto, Em: time.UnixMilli(m.EnviadaEm)})
+		s.enviar(c, protocolo.Mensagem{Comando: "MENSAGEM_PRIVADA", Dados: mustJSON(m)})
+	}
+	delete(r.caixas, nome)
+	if len(r.amigos[nome]) > 0 || len(r.recebidos[nome]) > 0 || len(r.bloqueios[nome]) > 0 {
+		s.enviar(c, protocolo.Mensagem{Comando: "LISTA_AMIGOS", Dados: mustJSON(r.lista(nome))})
+	}
+	return nil
+}
+
+// desconectarDaRede remove a sessão que está terminando
+func (s *Servidor) desconectarDaRede(c *Cliente) {
+	s.rede.mutex.Lock()
+	defer s.rede.mutex.Unlock()
+	if nome, ok := s.rede.nomes[c.Sessao]; ok {
+		s.sairDaRede(c, nome)
+	}
+}
+
+// sairDaRede desfaz o registro da sessão com o nome (sob r.mutex)
+func (s *Servidor) sairDaRede(c *Cliente, nome string) {
+	r := s.rede
+	delete(r.nomes, c.Sessao)
+	if id, ok := r.anonimos[nome]; ok && id == c.Sessao {
+		delete(r.anonimos, nome)
+		return // Sessões anônimas não chegaram a entrar na rede
+	}
+	delete(r.sessoes[nome], c.Sessao)
+	c.bloqueados.definir(nil)
+	if len(r.sessoes[nome]) == 0 {
+		delete(r.sessoes, nome)
+		for d := range r.desafios {
+			if d.de == nome {
+				delete(r.desafios, d) // Convites de quem saiu perdem o sentido
+			}
+		}
+	}
+	s.anunciarPresenca(nome)
+}
+
+// BAREMA ITEM 7: PARTIDAS - Atualiza a presença da sessão (fila, partida ou lobby)
+// Sessões sem LOGIN não estão na rede e são ignoradas
+func (s *Servidor) presencaMudou(c *Cliente, presenca string) {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := r.nomes[c.Sessao]
+	if !ok {
+		return
+	}
+	if sr := r.sessoes[nome][c.Sessao]; sr != nil && sr.c == c {
+		sr.presenca = presenca
+		s.anunciarPresenca(nome)
+	}
+}
+
+// presencaAtual calcula a presença da sessão; não pode ser chamada com filaMutex
+func (s *Servidor) presencaAtual(c *Cliente) string {
+	if c.salaAtual() != nil {
+		return protocolo.PresencaPartida
+	}
+	s.filaMutex.Lock()
+	defer s.filaMutex.Unlock()
+	if s.filaDeEspera == c {
+		return protocolo.PresencaFila
+	}
+	return protocolo.PresencaOnline
+}
+
+// nomeDaSessao devolve o nome do último LOGIN aceito na sessão ("" se ainda não houve)
+func (s *Servidor) nomeDaSessao(c *Cliente) string {
+	s.rede.mutex.Lock()
+	defer s.rede.mutex.Unlock()
+	return s.rede.nomes[c.Sessao]
+}
+
+// registrado devolve o nome com que a sessão entrou na rede, avisando quem
+// ainda não fez LOGIN ou entrou sem verificar o nome (sob r.mutex)
+func (s *Servidor) registrado(c *Cliente) (string, bool) {
+	nome, ok := s.rede.nomes[c.Sessao]
+	if !ok {
+		s.avisar(c, "[SISTEMA] Faça LOGIN antes de usar a lista de amigos.")
+		return "", false
+	}
+	if id, anonima := s.rede.anonimos[nome]; anonima && id == c.Sessao {
+		s.avisar(c, "[SISTEMA] Amigos, mensagens privadas e desafios exigem um nome registrado: faça LOGIN com senha (a primeira registra o nome) ou use um certificado de cliente.")
+		return "", false
+	}
+	return nome, true
+}
+
+/* ====================== Comandos dos clientes ====================== */
+
+// BAREMA ITEM 3: API REMOTA - ADICIONAR_AMIGO: adiciona ou aceita um pedido de amizade
+func (s *Servidor) adicionarAmigo(c *Cliente, amigo string) {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	switch {
+	case !ok:
+		return
+	case amigo == nome:
+		s.avisar(c, "[SISTEMA] Você não pode adicionar a si mesmo.")
+		return
+	case r.bloqueia(nome, amigo):
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Você bloqueou %s. Use /desbloquear %s antes de adicionar.", amigo, amigo))
+		return
+	case r.bloqueia(amigo, nome):
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Não é possível adicionar %s.", amigo))
+		return
+	case r.adicionou(nome, amigo):
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s já está na sua lista de amigos.", amigo))
+		return
+	case len(r.amigos[nome]) >= s.cfg.MaxAmigos:
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Sua lista de amigos está cheia (%d). Remova alguém antes.", s.cfg.MaxAmigos))
+		return
+	}
+	r.adicionar(nome, amigo)
+	if r.mutuos(nome, amigo) {
+		s.paraSessoes(amigo, protocolo.Mensagem{
+			Comando: "SISTEMA",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] %s aceitou seu pedido de amizade.", nome)}),
+		})
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Você e %s agora são amigos.", amigo))
+	} else {
+		s.paraSessoes(amigo, protocolo.Mensagem{
+			Comando: "SISTEMA",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] %s quer ser seu amigo. Use /amigo %s para aceitar.", nome, nome)}),
+		})
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Pedido de amizade enviado a %s. A presença aparece quando for aceito.", amigo))
+	}
+	s.enviarLista(amigo)
+	s.enviarLista(nome)
+}
+
+// BAREMA ITEM 3: API REMOTA - REMOVER_AMIGO
+func (s *Servidor) removerAmigo(c *Cliente, amigo string) {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	if !ok {
+		return
+	}
+	if !r.adicionou(nome, amigo) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s não está na sua lista de amigos.", amigo))
+		return
+	}
+	r.remover(nome, amigo)
+	delete(r.desafios, desafio{nome, amigo})
+	delete(r.desafios, desafio{amigo, nome})
+	s.enviarLista(amigo)
+	s.enviarLista(nome)
+}
+
+// BAREMA ITEM 3: API REMOTA - BLOQUEAR: desfaz a amizade e deixa de receber as mensagens do jogador
+func (s *Servidor) bloquear(c *Cliente, alvo string) {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	switch {
+	case !ok:
+		return
+	case alvo == nome:
+		s.avisar(c, "[SISTEMA] Você não pode bloquear a si mesmo.")
+		return
+	case r.bloqueia(nome, alvo):
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Você já bloqueou %s.", alvo))
+		return
+	case len(r.bloqueios[nome]) >= s.cfg.MaxAmigos:
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Sua lista de bloqueio está cheia (%d). Desbloqueie alguém antes.", s.cfg.MaxAmigos))
+		return
+	}
+	r.bloqueios[nome] = inserirOrdenado(r.bloqueios[nome], alvo)
+	r.atualizarBloqueios(nome)
+	if r.adicionou(nome, alvo) {
+		r.remover(nome, alvo)
+	}
+	if r.adicionou(alvo, nome) {
+		r.remover(alvo, nome)
+		s.enviarLista(alvo) // O bloqueado só vê a amizade sumir da lista
+	}
+	delete(r.desafios, desafio{nome, alvo})
+	delete(r.desafios, desafio{alvo, nome})
+	s.avisar(c, fmt.Sprintf("[SISTEMA] As mensagens de %s não chegarão mais a você. Use /desbloquear %s para desfazer.", alvo, alvo))
+	s.enviarLista(nome)
+}
+
+// BAREMA ITEM 3: API REMOTA - DESBLOQUEAR (a amizade desfeita não volta sozinha)
+func (s *Servidor) desbloquear(c *Cliente, alvo string) {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	if !ok {
+		return
+	}
+	if !r.bloqueia(nome, alvo) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Você não bloqueou %s.", alvo))
+		return
+	}
+	r.bloqueios[nome] = removerNome(r.bloqueios[nome], alvo)
+	if len(r.bloqueios[nome]) == 0 {
+		delete(r.bloqueios, nome)
+	}
+	r.atualizarBloqueios(nome)
+	s.avisar(c, fmt.Sprintf("[SISTEMA] Você desbloqueou %s.", alvo))
+	s.enviarLista(nome)
+}
+
+// BAREMA ITEM 3: API REMOTA - LISTAR_AMIGOS
+func (s *Servidor) listarAmigos(c *Cliente) {
+	s.rede.mutex.Lock()
+	defer s.rede.mutex.Unlock()
+	if nome, ok := s.registrado(c); ok {
+		s.enviar(c, protocolo.Mensagem{Comando: "LISTA_AMIGOS", Dados: mustJSON(s.rede.lista(nome))})
+	}
+}
+
+// BAREMA ITEM 3: API REMOTA - MENSAGEM_PRIVADA: entrega às sessões do amigo ou guarda para depois
+func (s *Servidor) mensagemPrivada(c *Cliente, para, texto string) {
+	texto, permitida := s.moderarChat(c, texto)
+	if !permitida {
+		return
+	}
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	if !ok {
+		return
+	}
+	if !r.mutuos(nome, para) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Mensagens privadas só podem ser enviadas a amigos: você e %s precisam ter se adicionado.", para))
+		return
+	}
+	agora := time.Now()
+	m := protocolo.DadosMensagemPrivada{De: nome, Texto: texto, EnviadaEm: agora.UnixMilli()}
+	linha := linhaChat{De: nome, Onde: "privada", Texto: texto, Em: agora}
+	s.lembrarChat(c, linha)
+	if len(r.sessoes[para]) > 0 {
+		for _, sr := range r.sessoes[para] {
+			s.lembrarChat(sr.c, linha)
+		}
+		s.paraSessoes(para, protocolo.Mensagem{Comando: "MENSAGEM_PRIVADA", Dados: mustJSON(m)})
+		s.metricas.privadasEntregues.Add(1)
+		return
+	}
+	switch {
+	case s.cfg.MensagensGuardadas == 0:
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s está offline e este servidor não guarda mensagens; a sua não foi enviada.", para))
+		return
+	case len(r.caixas[para]) >= s.cfg.MensagensGuardadas:
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s está offline e já tem %d mensagens guardadas; a sua não foi enviada.", para, s.cfg.MensagensGuardadas))
+		return
+	}
+	m.Guardada = true
+	r.caixas[para] = append(r.caixas[para], m)
+	s.metricas.privadasGuardadas.Add(1)
+	s.avisar(c, fmt.Sprintf("[SISTEMA] %s está offline; a mensagem será entregue no próximo login.", para))
+}
+
+// BAREMA ITEM 7: PARTIDAS - DESAFIAR: convida um amigo no lobby para uma sala privada
+func (s *Servidor) desafiar(c *Cliente, amigo string) {
+	emSala := c.salaAtual() != nil
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	switch {
+	case !ok:
+		return
+	case !r.mutuos(nome, amigo):
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Só é possível desafiar amigos; %s não é seu amigo.", amigo))
+		return
+	case emSala:
+		s.avisar(c, "[SISTEMA] Saia da partida atual (/sair) antes de desafiar alguém.")
+		return
+	}
+	switch r.presenca(amigo) {
+	case protocolo.PresencaOffline:
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s está offline.", amigo))
+		return
+	case protocolo.PresencaPartida:
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s está em uma partida. Tente quando terminar.", amigo))
+		return
+	}
+
+	agora := time.Now()
+	for d, vence := range r.desafios {
+		if agora.After(vence) {
+			delete(r.desafios, d)
+		}
+	}
+	r.desafios[desafio{nome, amigo}] = agora.Add(validadeDesafio)
+	s.paraSessoes(amigo, protocolo.Mensagem{Comando: "DESAFIO", Dados: mustJSON(protocolo.DadosAmigo{Nome: nome})})
+	s.avisar(c, fmt.Sprintf("[SISTEMA] Desafio enviado a %s, que tem %v para aceitar.", amigo, validadeDesafio))
+}
+
+// BAREMA ITEM 7: PARTIDAS - RECUSAR_DESAFIO
+func (s *Servidor) recusarDesafio(c *Cliente, de string) {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	if !ok {
+		return
+	}
+	if _, pendente := r.desafios[desafio{de, nome}]; !pendente {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Não há desafio pendente de %s.", de))
+		return
+	}
+	delete(r.desafios, desafio{de, nome})
+	s.paraSessoes(de, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] %s recusou seu desafio.", nome)}),
+	})
+}
+
+// BAREMA ITEM 7: PARTIDAS - ACEITAR_DESAFIO: cria a sala privada com quem desafiou
+func (s *Servidor) aceitarDesafio(c *Cliente, de string) {
+	if s.drenando.Load() {
+		s.avisarManutencao(c)
+		return
+	}
+	if c.salaAtual() != nil {
+		s.avisar(c, "[SISTEMA] Saia da partida atual (/sair) antes de aceitar um desafio.")
+		return
+	}
+	oponente := s.retirarDesafio(c, de)
+	if oponente == nil {
+		return
+	}
+	defer oponente.soltar()
+
+	s.filaMutex.Lock()
+	defer s.filaMutex.Unlock()
+	// Sob filaMutex ninguém mais coloca os dois em uma sala
+	if !c.ativa() || !oponente.ativa() || c.salaAtual() != nil || oponente.salaAtual() != nil {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] %s não está mais disponível para a partida.", de))
+		return
+	}
+	s.tirarDaFila(c)
+	s.tirarDaFila(oponente)
+	c.log().Info("desafio aceito; criando sala privada", "oponente", de)
+	s.metricas.desafiosAceitos.Add(1)
+	s.criarSala(oponente, c, true)
+}
+
+// retirarDesafio consome o convite e devolve, já retida, uma sessão de quem
+// desafiou que ainda esteja fora de partida
+func (s *Servidor) retirarDesafio(c *Cliente, de string) *Cliente {
+	r := s.rede
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nome, ok := s.registrado(c)
+	if !ok {
+		return nil
+	}
+	vence, pendente := r.desafios[desafio{de, nome}]
+	delete(r.desafios, desafio{de, nome})
+	if !pendente || time.Now().After(vence) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Não há desafio pendente de %s (os desafios valem por %v).", de, validadeDesafio))
+		return nil
+	}
+	for id, sr := range r.sessoes[de] {
+		if sr.presenca != protocolo.PresencaPartida && sr.c.reterSessao(id) {
+			return sr.c
+		}
+	}
+	s.avisar(c, fmt.Sprintf("[SISTEMA] %s não está mais disponível para a partida.", de))
+	return nil
+}
+
+/* ====================== Utilidades ====================== */
+
+func inserirOrdenado(lista []string, nome string) []string {
+	i, ok := slices.BinarySearch(lista, nome)
+	if ok {
+		return lista
+	}
+	return slices.Insert(lista, i, nome)
+}
+
+func removerNome(lista []string, nome string) []string {
+	if i, ok := slices.BinarySearch(lista, nome); ok {
+		return slices.Delete(lista, i, i+1)
+	}
+	return lista
+}
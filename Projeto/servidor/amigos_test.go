@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"meujogo/clientesdk"
+	"meujogo/protocolo"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BAREMA ITEM 9: TESTES - Só nomes verificados recebem amigos e mensagens guardadas
+// Ana e Bia registram os nomes com senha e ficam amigas; com Ana offline, Bia
+// deixa uma mensagem para ela. Quem tenta entrar como Ana sem a senha, ou com
+// a senha errada, não recebe nada, e a mensagem continua guardada até a Ana
+// de verdade voltar. Nomes sem senha também não podem ser usados por duas
+// sessões ao mesmo tempo.
+func TestIdentidadeNaRede(t *testing.T) {
+	s, transporte := iniciarServidorMemoria(t)
+	rede := s.rede
+	naRede := func(f func() bool) bool {
+		return esperarAte(5*time.Second, func() bool {
+			rede.mutex.Lock()
+			defer rede.mutex.Unlock()
+			return f()
+		})
+	}
+
+	ana := conectarConta(t, transporte, "ana", "senha-da-ana")
+	bia := conectarConta(t, transporte, "bia", "senha-da-bia")
+	ana.AdicionarAmigo("bia")
+	bia.AdicionarAmigo("ana")
+	if !naRede(func() bool { return rede.mutuos("ana", "bia") }) {
+		t.Fatal("ana e bia não ficaram amigas")
+	}
+	ana.Fechar()
+	if !naRede(func() bool { return len(rede.sessoes["ana"]) == 0 }) {
+		t.Fatal("sessão da ana continua na rede")
+	}
+	bia.MensagemPrivada("ana", "guardei para você")
+	if !naRede(func() bool { return len(rede.caixas["ana"]) == 1 }) {
+		t.Fatal("mensagem para a ana não foi guardada")
+	}
+
+	// Sem senha ou com a senha errada: nada de lista nem de mensagens guardadas
+	semSenha := conectarMemoria(t, transporte, "ana")
+	esperarAviso(t, semSenha, "registrado", "LISTA_AMIGOS", "MENSAGEM_PRIVADA")
+	semSenha.AdicionarAmigo("bia")
+	esperarAviso(t, semSenha, "Faça LOGIN", "LISTA_AMIGOS")
+	senhaErrada := conectarConta(t, transporte, "ana", "chute")
+	esperarAviso(t, senhaErrada, "senha incorreta", "LISTA_AMIGOS", "MENSAGEM_PRIVADA")
+	if esperarEvento(senhaErrada, "LISTA_AMIGOS", 5*time.Second) {
+		t.Fatal("sessão com senha errada recebeu a lista de amigos")
+	}
+	if !naRede(func() bool { return len(rede.caixas["ana"]) == 1 && len(rede.sessoes["ana"]) == 0 }) {
+		t.Fatal("tentativa sem a senha alterou a rede da ana")
+	}
+
+	// A Ana de verdade recebe a mensagem guardada, mesmo em duas sessões
+	ana = conectarConta(t, transporte, "ana", "senha-da-ana")
+	if !esperarEvento(ana, "MENSAGEM_PRIVADA", 5*time.Second) {
+		t.Fatal("ana não recebeu a mensagem guardada")
+	}
+	outraAna := conectarConta(t, transporte, "ana", "senha-da-ana")
+	if !esperarEvento(outraAna, "LISTA_AMIGOS", 5*time.Second) {
+		t.Fatal("segunda sessão verificada da ana foi recusada")
+	}
+
+	// Sem senha: o nome fica com a primeira sessão, que não entra na rede
+	caio := conectarMemoria(t, transporte, "caio")
+	if !naRede(func() bool { _, ok := rede.anonimos["caio"]; return ok }) {
+		t.Fatal("caio não reservou o nome")
+	}
+	esperarAviso(t, conectarMemoria(t, transporte, "caio"), "em uso", "LISTA_AMIGOS")
+	esperarAviso(t, conectarConta(t, transporte, "caio", "registrar"), "em uso", "LISTA_AMIGOS")
+	caio.AdicionarAmigo("bia")
+	esperarAviso(t, caio, "nome registrado", "LISTA_AMIGOS")
+	caio.MensagemPrivada("bia", "oi")
+	esperarAviso(t, caio, "nome registrado")
+	if !naRede(func() bool { return len(rede.recebidos["caio"]) == 0 && len(rede.amigos["caio"]) == 0 }) {
+		t.Fatal("sessão sem senha entrou no grafo de amizades")
+	}
+}
+
+// BAREMA ITEM 9: TESTES - Nomes de certificado e nomes com senha não se misturam
+// O certificado chega pelo handshake TLS, que o transporte em memória não
+// tem, então as sessões com certificado entram direto por conectarNaRede
+func TestCertificadoESenhaSeparados(t *testing.T) {
+	s, transporte := iniciarServidorMemoria(t)
+	sessaoCertificada := func() *Cliente {
+		c := clientePool.Get().(*Cliente)
+		c.iniciarSessao(context.Background())
+		c.Mailbox.capacidade = s.cfg.MaxFilaSaida
+		t.Cleanup(func() {
+			s.desconectarDaRede(c)
+			c.cancelar()
+			c.soltar()
+		})
+		return c
+	}
+
+	// Um certificado não assume um nome registrado com senha
+	conectarConta(t, transporte, "ana", "senha-da-ana")
+	if !esperarAte(5*time.Second, func() bool {
+		s.rede.mutex.Lock()
+		defer s.rede.mutex.Unlock()
+		_, ok := s.rede.contas["ana"]
+		return ok
+	}) {
+		t.Fatal("ana não registrou o nome")
+	}
+	if err := s.conectarNaRede(sessaoCertificada(), "ana", "", true); !errors.Is(err, errNomeComSenha) {
+		t.Fatalf("certificado com o nome de uma conta: %v, esperado %v", err, errNomeComSenha)
+	}
+
+	// E um nome já visto em certificado não recebe senha nem uso anônimo
+	if err := s.conectarNaRede(sessaoCertificada(), "cris", "", true); err != nil {
+		t.Fatalf("certificado da cris recusado: %v", err)
+	}
+	esperarAviso(t, conectarConta(t, transporte, "cris", "roubar"), "certificado", "LISTA_AMIGOS")
+	esperarAviso(t, conectarMemoria(t, transporte, "cris"), "certificado", "LISTA_AMIGOS")
+	s.rede.mutex.Lock()
+	_, registrada := s.rede.contas["cris"]
+	s.rede.mutex.Unlock()
+	if registrada {
+		t.Fatal("senha registrada para o nome de um certificado")
+	}
+
+	// A separação sobrevive ao reinício
+	arquivo := filepath.Join(t.TempDir(), "amigos.json")
+	if err := s.rede.salvar(arquivo); err != nil {
+		t.Fatal(err)
+	}
+	r, err := carregarAmigos(arquivo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.cns["cris"] {
+		t.Fatal("nome de certificado perdido depois de recarregado")
+	}
+}
+
+// esperarAviso espera um SISTEMA contendo trecho e falha se antes dele chegar
+// algum dos comandos proibidos
+func esperarAviso(t *testing.T, cliente *clientesdk.Cliente, trecho string, proibidos ...string) {
+	t.Helper()
+	limite := time.NewTimer(5 * time.Second)
+	defer limite.Stop()
+	for {
+		select {
+		case ev, ok := <-cliente.Eventos():
+			if !ok || ev.Comando == clientesdk.EventoDesconectado {
+				t.Fatalf("conexão encerrada antes do aviso %q", trecho)
+			}
+			if slices.Contains(proibidos, ev.Comando) {
+				t.Fatalf("%s entregue antes do aviso %q: %s", ev.Comando, trecho, ev.Bruto.Dados)
+			}
+			if d, ok := ev.Dados.(protocolo.DadosErro); ok && ev.Comando == "SISTEMA" && strings.Contains(d.Mensagem, trecho) {
+				return
+			}
+		case <-limite.C:
+			t.Fatalf("aviso %q não chegou", trecho)
+		}
+	}
+}
+
+func TestContasPersistidas(t *testing.T) {
+	arquivo := filepath.Join(t.TempDir(), "amigos.json")
+	r := novaRedeAmigos()
+	r.contas["ana"] = novaConta("senha-da-ana")
+	if err := r.salvar(arquivo); err != nil {
+		t.Fatal(err)
+	}
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(conteudo, []byte("senha-da-ana")) {
+		t.Fatal("senha gravada em texto puro")
+	}
+
+	r, err = carregarAmigos(arquivo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conta, ok := r.contas["ana"]
+	if !ok || !conta.confere("senha-da-ana") {
+		t.Fatal("conta da ana não confere depois de recarregada")
+	}
+	if conta.confere("senha-da-bia") || conta.confere("") {
+		t.Fatal("conta da ana aceitou outra senha")
+	}
+}
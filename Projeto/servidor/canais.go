@@ -0,0 +1,224 @@
+package main
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Canais de chat do lobby.
+// Os canais (-canais, ex.: global e troca) são criados no boot e nunca mudam,
+// então o mapa do servidor é lido sem lock. Cada canal guarda as últimas
+// -historico-canal mensagens, enviadas a quem entra. A lista de membros é
+// uma cópia imutável trocada a cada entrada ou saída (sob o mutex do próprio
+// canal); a difusão só lê a cópia vigente, sem lock, e serializa os dados
+// da mensagem uma única vez (o envelope ainda é codificado pelo writer de
+// cada inscrito). Só quem já fez LOGIN publica. Quem está em partida continua
+// recebendo os canais em que entrou. A mensagem não volta ao autor nem chega
+// a quem o bloqueou.
+
+import (
+	"fmt"
+	"meujogo/protocolo"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tamanho máximo do nome de um canal
+const maxNomeCanal = 32
+
+// membroCanal identifica a sessão inscrita; o objeto Cliente pode ser reciclado
+// para outra sessão enquanto uma difusão antiga ainda o percorre
+type membroCanal struct {
+	c      *Cliente
+	sessao uint64
+}
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Canal de chat com histórico limitado
+type canal struct {
+	nome      string
+	mutex     sync.Mutex                    // Protege historico e a troca da lista de membros
+	historico []protocolo.DadosReceberChat  // Últimas mensagens, da mais antiga para a mais nova
+	maxHist   int                           // Tamanho máximo de historico
+	membros   atomic.Pointer[[]membroCanal] // Cópia imutável lida pela difusão
+	mensagens atomic.Uint64                 // Mensagens publicadas (métricas)
+}
+
+func novoCanal(nome string, maxHist int) *canal {
+	c := &canal{nome: nome, maxHist: maxHist}
+	c.membros.Store(&[]membroCanal{})
+	return c
+}
+
+// nomesCanais interpreta a lista de -canais ("global,troca")
+func nomesCanais(lista string) []string {
+	var nomes []string
+	for _, nome := range strings.Split(lista, ",") {
+		if nome = strings.TrimSpace(nome); nome != "" {
+			nomes = append(nomes, nome)
+		}
+	}
+	return nomes
+}
+
+// entrar inscreve a sessão e devolve uma cópia do histórico
+func (ca *canal) entrar(c *Cliente) []protocolo.DadosReceberChat {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+	atuais := *ca.membros.Load()
+	novos := make([]membroCanal, len(atuais), len(atuais)+1)
+	copy(novos, atuais)
+	novos = append(novos, membroCanal{c: c, sessao: c.Sessao})
+	ca.membros.Store(&novos)
+	return append([]protocolo.DadosReceberChat(nil), ca.historico...)
+}
+
+// sair remove a sessão da lista de membros
+func (ca *canal) sair(c *Cliente) {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+	atuais := *ca.membros.Load()
+	novos := make([]membroCanal, 0, len(atuais))
+	for _, m := range atuais {
+		if m.c != c || m.sessao != c.Sessao {
+			novos = append(novos, m)
+		}
+	}
+	ca.membros.Store(&novos)
+}
+
+// quantidade devolve o número de inscritos
+func (ca *canal) quantidade() int {
+	return len(*ca.membros.Load())
+}
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Guarda a mensagem no histórico e a entrega aos
+// outros inscritos, salvo os que bloquearam o autor
+func (ca *canal) publicar(s *Servidor, autor *Cliente, dados protocolo.DadosReceberChat) {
+	ca.mutex.Lock()
+	if ca.maxHist > 0 {
+		if len(ca.historico) == ca.maxHist {
+			ca.historico = append(ca.historico[:0], ca.historico[1:]...)
+		}
+		ca.historico = append(ca.historico, dados)
+	}
+	ca.mutex.Unlock()
+	ca.mensagens.Add(1)
+
+	linha := linhaChat{De: dados.NomeJogador, Onde: "#" + ca.nome, Texto: dados.Texto, Em: time.Now()}
+	s.lembrarChat(autor, linha)
+	msg := protocolo.Mensagem{Comando: "RECEBER_CHAT", Dados: mustJSON(dados)}
+	for _, m := range *ca.membros.Load() {
+		if m.c == autor || !m.c.reterSessao(m.sessao) {
+			continue // O próprio autor, ou sessão já encerrada
+		}
+		if !m.c.bloqueou(dados.NomeJogador) {
+			s.lembrarChat(m.c, linha)
+			s.enviar(m.c, msg)
+		}
+		m.c.soltar()
+	}
+}
+
+/* ====================== Comandos dos clientes ====================== */
+
+// listaCanais descreve os canais disponíveis para as mensagens de erro
+func (s *Servidor) listaCanais() string {
+	return strings.Join(chavesOrdenadas(s.canais), ", ")
+}
+
+// avisar envia uma mensagem de SISTEMA a um cliente
+func (s *Servidor) avisar(c *Cliente, texto string) {
+	s.enviar(c, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: texto}),
+	})
+}
+
+// inscrito diz se a sessão está no canal; c.canais só é usado pela goroutine de leitura
+func (c *Cliente) inscrito(nome string) bool {
+	for _, n := range c.canais {
+		if n == nome {
+			return true
+		}
+	}
+	return false
+}
+
+// BAREMA ITEM 3: API REMOTA - ENTRAR_CANAL: inscreve e envia o histórico
+func (s *Servidor) entrarCanal(c *Cliente, nome string) {
+	ca, ok := s.canais[nome]
+	if !ok {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Canal desconhecido: %q. Canais disponíveis: %s.", nome, s.listaCanais()))
+		return
+	}
+	if c.inscrito(nome) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Você já está no canal %s.", nome))
+		return
+	}
+	c.canais = append(c.canais, nome)
+	historico := slices.DeleteFunc(ca.entrar(c), func(d protocolo.DadosReceberChat) bool {
+		return c.bloqueou(d.NomeJogador)
+	})
+	s.enviar(c, protocolo.Mensagem{
+		Comando: "HISTORICO_CANAL",
+		Dados: mustJSON(protocolo.DadosHistoricoCanal{
+			Canal:     nome,
+			Membros:   ca.quantidade(),
+			Mensagens: historico,
+		}),
+	})
+}
+
+// BAREMA ITEM 3: API REMOTA - SAIR_CANAL
+func (s *Servidor) sairCanal(c *Cliente, nome string) {
+	if !c.inscrito(nome) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Você não está no canal %s.", nome))
+		return
+	}
+	s.canais[nome].sair(c)
+	for i, n := range c.canais {
+		if n == nome {
+			c.canais = append(c.canais[:i], c.canais[i+1:]...)
+			break
+		}
+	}
+	s.avisar(c, fmt.Sprintf("[SISTEMA] Você saiu do canal %s.", nome))
+}
+
+// sairDosCanais cancela todas as inscrições da sessão que está terminando
+func (s *Servidor) sairDosCanais(c *Cliente) {
+	for _, nome := range c.canais {
+		s.canais[nome].sair(c)
+	}
+	c.canais = c.canais[:0]
+}
+
+// BAREMA ITEM 3: API REMOTA - ENVIAR_CHAT para um canal; sem canal, vale o
+// último em que o jogador entrou
+func (s *Servidor) chatCanal(c *Cliente, nome, texto string) {
+	// Antes do LOGIN o nome da sessão é o endereço remoto, que não pode ir aos inscritos
+	autor := s.nomeDaSessao(c)
+	if autor == "" {
+		s.avisar(c, "[SISTEMA] Faça LOGIN antes de conversar nos canais.")
+		return
+	}
+	if nome == "" {
+		if len(c.canais) == 0 {
+			s.avisar(c, fmt.Sprintf("[SISTEMA] Você não está em uma partida nem em um canal. Entre em um canal (%s) para conversar no lobby.", s.listaCanais()))
+			return
+		}
+		nome = c.canais[len(c.canais)-1]
+	}
+	if !c.inscrito(nome) {
+		s.avisar(c, fmt.Sprintf("[SISTEMA] Entre no canal %s antes de enviar mensagens a ele.", nome))
+		return
+	}
+	texto, ok := s.moderarChat(c, texto)
+	if !ok {
+		return
+	}
+	s.canais[nome].publicar(s, c, protocolo.DadosReceberChat{
+		NomeJogador: autor,
+		Texto:       texto,
+		Canal:       nome,
+	})
+}
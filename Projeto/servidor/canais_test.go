@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// BAREMA ITEM 9: TESTES - Só quem fez LOGIN publica nos canais
+// Antes do LOGIN o nome da sessão é o endereço remoto; ele não pode chegar
+// aos inscritos como autor de uma mensagem.
+func TestCanalExigeLogin(t *testing.T) {
+	_, transporte := iniciarServidorMemoria(t)
+	ouvinte := conectarMemoria(t, transporte, "ouvinte")
+	entrarNoCanal(t, ouvinte, "global")
+
+	semLogin := conectarDe(t, transporte, "10.0.0.5:40001", "")
+	entrarNoCanal(t, semLogin, "global")
+	semLogin.ChatCanal("global", "sem login")
+	esperarAviso(t, semLogin, "Faça LOGIN")
+
+	semLogin.Login("ana")
+	semLogin.ChatCanal("global", "com login")
+	if texto := proximoChat(t, ouvinte); texto != "com login" {
+		t.Fatalf("o ouvinte recebeu %q de uma sessão sem LOGIN", texto)
+	}
+}
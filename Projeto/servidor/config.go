@@ -0,0 +1,416 @@
+package main
+
+// ===================== BAREMA ITEM 1: ARQUITETURA =====================
+// Configuração do servidor.
+// Todos os parâmetros ficam em Config e podem vir, em ordem crescente de
+// prioridade, dos valores padrão, de um arquivo JSON (-config), de variáveis
+// de ambiente (JOGO_<FLAG>) e das flags de linha de comando. Cada parâmetro é
+// registrado uma única vez como flag; o arquivo e o ambiente são aplicados
+// através das mesmas flags, garantindo a mesma conversão e validação.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"meujogo/seguranca"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BAREMA ITEM 1: ARQUITETURA - Parâmetros de execução do servidor
+type Config struct {
+	Endereco       string        // Endereço do socket do jogo
+	EnderecoWS     string        // Endereço do gateway WebSocket ("" desabilita)
+	OrigensWS      string        // Origens aceitas no upgrade WebSocket, separadas por vírgula ("" = só o próprio host)
+	EnderecoAdmin  string        // Endereço da API de administração e /metrics ("" desabilita)
+	TokenAdmin     string        // Token da API de administração ("" desabilita /api)
+	MaxConexoes    int           // BAREMA ITEM 5: CONCORRÊNCIA - Tamanho do semáforo de conexões
+	PackSize       int           // BAREMA ITEM 8: PACOTES - Cartas por pacote
+	PackWorkers    int           // BAREMA ITEM 5: CONCORRÊNCIA - Workers que processam compras
+	PackFila       int           // BAREMA ITEM 5: CONCORRÊNCIA - Buffer do packWorkerPool
+	ShardsEstoque  int           // BAREMA ITEM 5: CONCORRÊNCIA - Número de shards do estoque
+	IntervaloPing  time.Duration // BAREMA ITEM 6: LATÊNCIA - Intervalo entre PINGs do servidor
+	ICMP           bool          // BAREMA ITEM 6: LATÊNCIA - Responde a ping ICMP (exige raw socket)
+	TimeoutLeitura time.Duration // Prazo máximo sem receber nada do cliente (a pulsação costuma agir antes)
+	TimeoutEscrita time.Duration // Prazo de escrita de cada mensagem para o cliente
+	TLS            seguranca.OpcoesServidor
+	LogNivel       string // debug, info, warn ou error
+	LogFormato     string // texto ou json
+
+	PrazoDesligamento time.Duration // Tempo dado às partidas em andamento ao desligar
+	ArquivoEstado     string        // Arquivo onde o estado é salvo no desligamento ("" desabilita)
+
+	ArquivoRegras   string        // BAREMA ITEM 8: PACOTES - Regras de jogo recarregáveis ("" usa o padrão)
+	IntervaloRegras time.Duration // Intervalo de verificação de mudanças no arquivo de regras
+
+	Limites           string  // BAREMA ITEM 5: CONCORRÊNCIA - Limites por comando ("COMANDO=taxa:rajada,...")
+	LimiteEstrangular int     // Infrações até o leitor do cliente passar a ser estrangulado
+	LimiteDesconectar int     // Infrações até o cliente ser desconectado
+	MaxConexoesPorIP  int     // Conexões simultâneas por IP de origem (0 desabilita)
+	ConexoesIPTaxa    float64 // Novas conexões por segundo por IP (0 desabilita)
+	ConexoesIPRajada  int     // Rajada de novas conexões por IP
+
+	MaxMensagem  int // BAREMA ITEM 4: ENCAPSULAMENTO - Bytes máximos de uma mensagem recebida
+	MaxTextoChat int // Caracteres máximos de uma mensagem de chat
+	MaxNome      int // Caracteres máximos do nome do jogador
+	MaxViolacoes int // Entradas inválidas até o cliente ser desconectado
+
+	MaxFilaSaida int // BAREMA ITEM 2: COMUNICAÇÃO - Mensagens pendentes por prioridade na fila de saída
+
+	PingsAtraso  int           // BAREMA ITEM 6: LATÊNCIA - PINGs sem resposta até o oponente ser avisado do atraso
+	PingsPartida int           // PINGs sem resposta, em partida, até aplicar QuedaPartida
+	PingsLobby   int           // PINGs sem resposta, fora de partida, até desconectar
+	QuedaPartida string        // "desconectar" ou "abandonar" (sai da sala e segue conectado)
+	OciosoLobby  time.Duration // Tempo no lobby sem comandos até desconectar (0 desabilita)
+
+	Canais         string // BAREMA ITEM 2: COMUNICAÇÃO - Canais de chat do lobby, separados por vírgula
+	HistoricoCanal int    // Mensagens guardadas por canal e enviadas a quem entra
+
+	ArquivoAmigos      string // BAREMA ITEM 2: COMUNICAÇÃO - Listas de amigos e mensagens guardadas ("" não persiste)
+	MaxAmigos          int    // Nomes que cada jogador pode adicionar
+	MensagensGuardadas int    // Mensagens privadas guardadas por jogador offline
+
+	ArquivoModeracao  string // BAREMA ITEM 2: COMUNICAÇÃO - Silêncios, banimentos e denúncias pendentes ("" não persiste)
+	HistoricoDenuncia int    // Últimas mensagens de chat de cada sessão anexadas a uma denúncia
+}
+
+// configPadrao reproduz os valores históricos do servidor
+func configPadrao() Config {
+	return Config{
+		Endereco:       ":65432",
+		EnderecoWS:     ":8080",
+		EnderecoAdmin:  ":8081",
+		MaxConexoes:    30000,
+		PackSize:       5,
+		PackWorkers:    1000,
+		PackFila:       100000,
+		ShardsEstoque:  32,
+		IntervaloPing:  10 * time.Second,
+		TimeoutLeitura: 90 * time.Second,
+		TimeoutEscrita: 5 * time.Second,
+		LogNivel:       "info",
+		LogFormato:     "texto",
+
+		PrazoDesligamento: 60 * time.Second,
+		ArquivoEstado:     "estado_servidor.json",
+
+		IntervaloRegras: 5 * time.Second,
+
+		Limites:           limitesPadrao,
+		LimiteEstrangular: 5,
+		LimiteDesconectar: 20,
+		MaxConexoesPorIP:  100,
+		ConexoesIPTaxa:    20,
+		ConexoesIPRajada:  50,
+
+		MaxMensagem:  8 * 1024,
+		MaxTextoChat: 256,
+		MaxNome:      32,
+		MaxViolacoes: 5,
+
+		MaxFilaSaida: 64,
+
+		PingsAtraso:  2,
+		PingsPartida: 3,
+		PingsLobby:   6,
+		QuedaPartida: quedaDesconectar,
+		OciosoLobby:  15 * time.Minute,
+
+		Canais:         "global,troca",
+		HistoricoCanal: 50,
+
+		ArquivoAmigos:      "amigos.json",
+		MaxAmigos:          100,
+		MensagensGuardadas: 50,
+
+		ArquivoModeracao:  "moderacao.json",
+		HistoricoDenuncia: 20,
+	}
+}
+
+// registrarFlags associa cada campo da configuração a uma flag
+func (c *Config) registrarFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Endereco, "endereco", c.Endereco, "endereço do socket do jogo")
+	fs.StringVar(&c.EnderecoWS, "ws", c.EnderecoWS, "endereço do gateway WebSocket e do cliente web (vazio desabilita)")
+	fs.StringVar(&c.OrigensWS, "ws-origens", c.OrigensWS, "origens (ex.: https://jogo.exemplo.com) aceitas no upgrade WebSocket, separadas por vírgula; vazio aceita só páginas do próprio host e * aceita qualquer uma")
+	fs.StringVar(&c.EnderecoAdmin, "admin", c.EnderecoAdmin, "endereço da API HTTP de administração e de /metrics (vazio desabilita)")
+	fs.StringVar(&c.TokenAdmin, "admin-token", c.TokenAdmin, "token exigido pela API de administração")
+	fs.IntVar(&c.MaxConexoes, "max-conexoes", c.MaxConexoes, "máximo de conexões simultâneas")
+	fs.IntVar(&c.PackSize, "pacote-tamanho", c.PackSize, "cartas por pacote")
+	fs.IntVar(&c.PackWorkers, "pacote-workers", c.PackWorkers, "workers que processam compras de pacotes")
+	fs.IntVar(&c.PackFila, "pacote-fila", c.PackFila, "capacidade da fila de pedidos de pacote")
+	fs.IntVar(&c.ShardsEstoque, "shards-estoque", c.ShardsEstoque, "número de shards do estoque de cartas")
+	fs.DurationVar(&c.IntervaloPing, "intervalo-ping", c.IntervaloPing, "intervalo entre PINGs enviados aos clientes")
+	fs.BoolVar(&c.ICMP, "icmp", c.ICMP, "responde a ping ICMP (exige privilégio de raw socket)")
+	fs.DurationVar(&c.TimeoutLeitura, "timeout-leitura", c.TimeoutLeitura, "prazo máximo sem receber nenhuma mensagem do cliente")
+	fs.DurationVar(&c.TimeoutEscrita, "timeout-escrita", c.TimeoutEscrita, "prazo de escrita de cada mensagem para o cliente")
+	fs.StringVar(&c.TLS.ArquivoCert, "tls-cert", c.TLS.ArquivoCert, "certificado PEM do servidor (habilita TLS)")
+	fs.StringVar(&c.TLS.ArquivoChave, "tls-chave", c.TLS.ArquivoChave, "chave privada PEM do servidor")
+	fs.StringVar(&c.TLS.ArquivoCACliente, "tls-ca-cliente", c.TLS.ArquivoCACliente, "CA PEM dos certificados de cliente aceitos (TLS mútuo)")
+	fs.BoolVar(&c.TLS.ExigirCliente, "tls-exigir-cliente", c.TLS.ExigirCliente, "recusa clientes sem certificado válido")
+	fs.StringVar(&c.LogNivel, "log-nivel", c.LogNivel, "nível de log: debug, info, warn ou error")
+	fs.StringVar(&c.LogFormato, "log-formato", c.LogFormato, "formato do log: texto ou json")
+	fs.DurationVar(&c.PrazoDesligamento, "prazo-desligamento", c.PrazoDesligamento, "tempo para as partidas em andamento terminarem ao desligar")
+	fs.StringVar(&c.ArquivoEstado, "arquivo-estado", c.ArquivoEstado, "arquivo de estado salvo no desligamento e restaurado no boot (vazio desabilita)")
+	fs.StringVar(&c.ArquivoRegras, "regras", c.ArquivoRegras, "arquivo JSON com as regras de jogo, relido ao mudar ou com SIGHUP")
+	fs.DurationVar(&c.IntervaloRegras, "regras-intervalo", c.IntervaloRegras, "intervalo de verificação de mudanças no arquivo de regras")
+	fs.StringVar(&c.Limites, "limites", c.Limites, "limites de taxa por comando: COMANDO=taxa:rajada,... (\"*\" para os demais)")
+	fs.IntVar(&c.LimiteEstrangular, "limite-estrangular", c.LimiteEstrangular, "infrações de taxa até o cliente ser estrangulado")
+	fs.IntVar(&c.LimiteDesconectar, "limite-desconectar", c.LimiteDesconectar, "infrações de taxa até o cliente ser desconectado")
+	fs.IntVar(&c.MaxConexoesPorIP, "max-conexoes-ip", c.MaxConexoesPorIP, "conexões simultâneas por IP de origem (0 desabilita)")
+	fs.Float64Var(&c.ConexoesIPTaxa, "conexoes-ip-taxa", c.ConexoesIPTaxa, "novas conexões por segundo por IP (0 desabilita)")
+	fs.IntVar(&c.ConexoesIPRajada, "conexoes-ip-rajada", c.ConexoesIPRajada, "rajada de novas conexões por IP")
+	fs.IntVar(&c.MaxMensagem, "max-mensagem", c.MaxMensagem, "tamanho máximo em bytes de uma mensagem recebida")
+	fs.IntVar(&c.MaxTextoChat, "max-texto-chat", c.MaxTextoChat, "caracteres máximos de uma mensagem de chat")
+	fs.IntVar(&c.MaxNome, "max-nome", c.MaxNome, "caracteres máximos do nome do jogador")
+	fs.IntVar(&c.MaxViolacoes, "max-violacoes", c.MaxViolacoes, "entradas inválidas até o cliente ser desconectado")
+	fs.IntVar(&c.MaxFilaSaida, "fila-saida", c.MaxFilaSaida, "mensagens pendentes por prioridade antes de descartar (ou desconectar, se críticas)")
+	fs.IntVar(&c.PingsAtraso, "pings-atraso", c.PingsAtraso, "PINGs seguidos sem resposta até o oponente ser avisado de que o jogador está atrasado")
+	fs.IntVar(&c.PingsPartida, "pings-partida", c.PingsPartida, "PINGs seguidos sem resposta, em partida, até aplicar -queda-partida")
+	fs.IntVar(&c.PingsLobby, "pings-lobby", c.PingsLobby, "PINGs seguidos sem resposta, fora de partida, até desconectar")
+	fs.StringVar(&c.QuedaPartida, "queda-partida", c.QuedaPartida, "o que fazer com quem para de responder em partida: desconectar ou abandonar (sai da sala e segue conectado)")
+	fs.DurationVar(&c.OciosoLobby, "ocioso-lobby", c.OciosoLobby, "tempo no lobby sem enviar comandos até desconectar (0 desabilita)")
+	fs.StringVar(&c.Canais, "canais", c.Canais, "canais de chat do lobby, separados por vírgula")
+	fs.IntVar(&c.HistoricoCanal, "historico-canal", c.HistoricoCanal, "mensagens guardadas por canal e enviadas a quem entra (0 desabilita)")
+	fs.StringVar(&c.ArquivoAmigos, "arquivo-amigos", c.ArquivoAmigos, "arquivo com as listas de amigos e as mensagens privadas guardadas, gravado no desligamento (vazio não persiste)")
+	fs.IntVar(&c.MaxAmigos, "max-amigos", c.MaxAmigos, "nomes que cada jogador pode adicionar à lista de amigos")
+	fs.IntVar(&c.MensagensGuardadas, "mensagens-guardadas", c.MensagensGuardadas, "mensagens privadas guardadas por jogador offline (0 só entrega a quem está online)")
+	fs.StringVar(&c.ArquivoModeracao, "arquivo-moderacao", c.ArquivoModeracao, "arquivo com os silêncios, banimentos e denúncias pendentes, gravado no desligamento (vazio não persiste)")
+	fs.IntVar(&c.HistoricoDenuncia, "historico-denuncia", c.HistoricoDenuncia, "últimas mensagens de chat de cada sessão anexadas a uma denúncia (0 não guarda)")
+}
+
+// carregarConfig monta a configuração: padrão < arquivo < ambiente < flags
+func carregarConfig(args []string) (Config, error) {
+	cfg := configPadrao()
+	fs := flag.NewFlagSet("servidor", flag.ExitOnError)
+	arquivo := fs.String("config", os.Getenv("JOGO_CONFIG"), "arquivo de configuração JSON (chaves com os nomes das flags)")
+	cfg.registrarFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	// Guarda as flags informadas explicitamente para reaplicá-las por último
+	explicitas := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) { explicitas[f.Name] = f.Value.String() })
+
+	if *arquivo != "" {
+		if err := aplicarArquivoConfig(fs, *arquivo); err != nil {
+			return cfg, err
+		}
+	}
+
+	var errEnv error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		if valor, ok := os.LookupEnv(nomeVariavelAmbiente(f.Name)); ok {
+			if err := fs.Set(f.Name, valor); err != nil && errEnv == nil {
+				errEnv = fmt.Errorf("variável %s: %w", nomeVariavelAmbiente(f.Name), err)
+			}
+		}
+	})
+	if errEnv != nil {
+		return cfg, errEnv
+	}
+
+	for nome, valor := range explicitas {
+		fs.Set(nome, valor)
+	}
+	return cfg, cfg.Validar()
+}
+
+// nomeVariavelAmbiente converte "pacote-workers" em "JOGO_PACOTE_WORKERS"
+func nomeVariavelAmbiente(flag string) string {
+	return "JOGO_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// aplicarArquivoConfig lê um objeto JSON cujas chaves são nomes de flags
+// (ex.: {"pacote-workers": 200, "intervalo-ping": "5s"})
+func aplicarArquivoConfig(fs *flag.FlagSet, arquivo string) error {
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		return fmt.Errorf("lendo configuração: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(conteudo))
+	dec.UseNumber()
+	var valores map[string]any
+	if err := dec.Decode(&valores); err != nil {
+		return fmt.Errorf("configuração %s: %w", arquivo, err)
+	}
+
+	chaves := make([]string, 0, len(valores))
+	for chave := range valores {
+		chaves = append(chaves, chave)
+	}
+	sort.Strings(chaves)
+	for _, chave := range chaves {
+		if chave == "config" || fs.Lookup(chave) == nil {
+			return fmt.Errorf("configuração %s: chave desconhecida %q", arquivo, chave)
+		}
+		if err := fs.Set(chave, fmt.Sprint(valores[chave])); err != nil {
+			return fmt.Errorf("configuração %s: chave %q: %w", arquivo, chave, err)
+		}
+	}
+	return nil
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Valida a configuração antes de iniciar qualquer componente
+func (c Config) Validar() error {
+	var erros []error
+	if c.Endereco == "" {
+		erros = append(erros, errors.New("endereco não pode ser vazio"))
+	}
+	positivos := []struct {
+		nome  string
+		valor int
+	}{
+		{"max-conexoes", c.MaxConexoes},
+		{"pacote-tamanho", c.PackSize},
+		{"pacote-workers", c.PackWorkers},
+		{"pacote-fila", c.PackFila},
+		{"shards-estoque", c.ShardsEstoque},
+		{"max-texto-chat", c.MaxTextoChat},
+		{"max-nome", c.MaxNome},
+		{"max-violacoes", c.MaxViolacoes},
+		{"fila-saida", c.MaxFilaSaida},
+		{"max-amigos", c.MaxAmigos},
+	}
+	for _, p := range positivos {
+		if p.valor <= 0 {
+			erros = append(erros, fmt.Errorf("%s deve ser positivo (recebido %d)", p.nome, p.valor))
+		}
+	}
+	if c.IntervaloPing <= 0 || c.TimeoutLeitura <= 0 || c.TimeoutEscrita <= 0 {
+		erros = append(erros, errors.New("intervalo-ping, timeout-leitura e timeout-escrita devem ser positivos"))
+	}
+	if c.PingsAtraso <= 0 || c.PingsPartida <= c.PingsAtraso || c.PingsLobby <= 0 {
+		erros = append(erros, fmt.Errorf("pings-partida (%d) deve ser maior que pings-atraso (%d), e este e pings-lobby (%d) positivos", c.PingsPartida, c.PingsAtraso, c.PingsLobby))
+	}
+	if limite := c.IntervaloPing * time.Duration(max(c.PingsPartida, c.PingsLobby)); c.IntervaloPing > 0 && c.TimeoutLeitura <= limite {
+		// Sem tráfego, o PONG de cada PING é o que renova o prazo de leitura; ele
+		// não pode expirar antes de a política de pulsação decidir
+		erros = append(erros, fmt.Errorf("timeout-leitura (%v) deve ser maior que intervalo-ping × max(pings-partida, pings-lobby) (%v)", c.TimeoutLeitura, limite))
+	}
+	switch c.QuedaPartida {
+	case quedaDesconectar, quedaAbandonar:
+	default:
+		erros = append(erros, fmt.Errorf("queda-partida inválida: %q (use desconectar ou abandonar)", c.QuedaPartida))
+	}
+	if c.OciosoLobby < 0 {
+		erros = append(erros, fmt.Errorf("ocioso-lobby não pode ser negativo (recebido %v)", c.OciosoLobby))
+	}
+	for _, nome := range nomesCanais(c.Canais) {
+		if len(nome) > maxNomeCanal || strings.IndexFunc(nome, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+		}) >= 0 {
+			erros = append(erros, fmt.Errorf("canais: nome inválido %q (use até %d letras minúsculas, dígitos, - ou _)", nome, maxNomeCanal))
+		}
+	}
+	if c.HistoricoCanal < 0 || c.HistoricoCanal > 1000 {
+		erros = append(erros, fmt.Errorf("historico-canal deve estar entre 0 e 1000 (recebido %d)", c.HistoricoCanal))
+	}
+	if c.MensagensGuardadas < 0 {
+		erros = append(erros, fmt.Errorf("mensagens-guardadas não pode ser negativo (recebido %d)", c.MensagensGuardadas))
+	}
+	if c.HistoricoDenuncia < 0 || c.HistoricoDenuncia > 200 {
+		erros = append(erros, fmt.Errorf("historico-denuncia deve estar entre 0 e 200 (recebido %d)", c.HistoricoDenuncia))
+	}
+	if c.PrazoDesligamento < 0 {
+		erros = append(erros, fmt.Errorf("prazo-desligamento não pode ser negativo (recebido %v)", c.PrazoDesligamento))
+	}
+	if c.IntervaloRegras <= 0 {
+		erros = append(erros, fmt.Errorf("regras-intervalo deve ser positivo (recebido %v)", c.IntervaloRegras))
+	}
+	if c.MaxMensagem < 512 {
+		erros = append(erros, fmt.Errorf("max-mensagem deve ser pelo menos 512 bytes (recebido %d)", c.MaxMensagem))
+	}
+	if _, err := parseLimites(c.Limites); err != nil {
+		erros = append(erros, fmt.Errorf("limites: %w", err))
+	}
+	if c.LimiteEstrangular <= 0 || c.LimiteDesconectar <= c.LimiteEstrangular {
+		erros = append(erros, fmt.Errorf("limite-desconectar (%d) deve ser maior que limite-estrangular (%d), e este positivo", c.LimiteDesconectar, c.LimiteEstrangular))
+	}
+	if c.MaxConexoesPorIP < 0 || c.ConexoesIPTaxa < 0 {
+		erros = append(erros, errors.New("max-conexoes-ip e conexoes-ip-taxa não podem ser negativos"))
+	}
+	if c.ConexoesIPTaxa > 0 && c.ConexoesIPRajada < 1 {
+		erros = append(erros, errors.New("conexoes-ip-rajada deve ser pelo menos 1"))
+	}
+	if (c.TLS.ArquivoCert == "") != (c.TLS.ArquivoChave == "") {
+		erros = append(erros, errors.New("tls-cert e tls-chave devem ser informados juntos"))
+	}
+	if c.TLS.ExigirCliente && c.TLS.ArquivoCACliente == "" {
+		erros = append(erros, errors.New("tls-exigir-cliente requer tls-ca-cliente"))
+	}
+	var nivel slog.Level
+	if err := nivel.UnmarshalText([]byte(c.LogNivel)); err != nil {
+		erros = append(erros, fmt.Errorf("log-nivel inválido: %q", c.LogNivel))
+	}
+	switch strings.ToLower(c.LogFormato) {
+	case "", "texto", "text", "json":
+	default:
+		erros = append(erros, fmt.Errorf("log-formato inválido: %q", c.LogFormato))
+	}
+	return errors.Join(erros...)
+}
+
+// LogValue permite registrar a configuração no boot sem expor o token
+func (c Config) LogValue() slog.Value {
+	token := ""
+	if c.TokenAdmin != "" {
+		token = "***"
+	}
+	return slog.GroupValue(
+		slog.String("endereco", c.Endereco),
+		slog.String("ws", c.EnderecoWS),
+		slog.String("wsOrigens", c.OrigensWS),
+		slog.String("admin", c.EnderecoAdmin),
+		slog.String("adminToken", token),
+		slog.Int("maxConexoes", c.MaxConexoes),
+		slog.Int("pacoteTamanho", c.PackSize),
+		slog.Int("pacoteWorkers", c.PackWorkers),
+		slog.Int("pacoteFila", c.PackFila),
+		slog.Int("shardsEstoque", c.ShardsEstoque),
+		slog.Duration("intervaloPing", c.IntervaloPing),
+		slog.Bool("icmp", c.ICMP),
+		slog.Duration("timeoutLeitura", c.TimeoutLeitura),
+		slog.Int("pingsAtraso", c.PingsAtraso),
+		slog.Int("pingsPartida", c.PingsPartida),
+		slog.Int("pingsLobby", c.PingsLobby),
+		slog.String("quedaPartida", c.QuedaPartida),
+		slog.Duration("ociosoLobby", c.OciosoLobby),
+		slog.String("canais", c.Canais),
+		slog.Int("historicoCanal", c.HistoricoCanal),
+		slog.String("arquivoAmigos", c.ArquivoAmigos),
+		slog.Int("maxAmigos", c.MaxAmigos),
+		slog.Int("mensagensGuardadas", c.MensagensGuardadas),
+		slog.String("arquivoModeracao", c.ArquivoModeracao),
+		slog.Int("historicoDenuncia", c.HistoricoDenuncia),
+		slog.Duration("timeoutEscrita", c.TimeoutEscrita),
+		slog.Bool("tls", c.TLS.Habilitado()),
+		slog.Bool("tlsMutuo", c.TLS.ArquivoCACliente != ""),
+		slog.String("logNivel", c.LogNivel),
+		slog.String("logFormato", c.LogFormato),
+		slog.Duration("prazoDesligamento", c.PrazoDesligamento),
+		slog.String("arquivoEstado", c.ArquivoEstado),
+		slog.String("regras", c.ArquivoRegras),
+		slog.Duration("regrasIntervalo", c.IntervaloRegras),
+		slog.String("limites", c.Limites),
+		slog.Int("limiteEstrangular", c.LimiteEstrangular),
+		slog.Int("limiteDesconectar", c.LimiteDesconectar),
+		slog.Int("maxConexoesIP", c.MaxConexoesPorIP),
+		slog.Float64("conexoesIPTaxa", c.ConexoesIPTaxa),
+		slog.Int("conexoesIPRajada", c.ConexoesIPRajada),
+		slog.Int("maxMensagem", c.MaxMensagem),
+		slog.Int("maxTextoChat", c.MaxTextoChat),
+		slog.Int("maxNome", c.MaxNome),
+		slog.Int("maxViolacoes", c.MaxViolacoes),
+		slog.Int("filaSaida", c.MaxFilaSaida),
+	)
+}
@@ -0,0 +1,283 @@
+package main
+
+// ===================== BAREMA ITEM 1: ARQUITETURA =====================
+// Desligamento gracioso, modo de drenagem e persistência de estado.
+// Ao receber SIGINT/SIGTERM o servidor para de aceitar conexões, avisa os
+// jogadores, dá às partidas em andamento um prazo para terminar e grava em
+// disco o estoque e as cartas que ainda estavam em jogo. No boot seguinte o
+// estoque é restaurado a partir desse arquivo.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"meujogo/protocolo"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// BAREMA ITEM 1: ARQUITETURA - Conteúdo do arquivo de estado
+type estadoPersistido struct {
+	SalvoEm               time.Time            `json:"salvoEm"`
+	Estoque               []map[string][]Carta `json:"estoque"`               // Estoque de cada shard
+	Maos                  map[string][]Carta   `json:"maos"`                  // Cartas nas mãos dos jogadores conectados
+	PartidasInterrompidas []infoSala           `json:"partidasInterrompidas"` // Partidas que não terminaram no prazo
+}
+
+// BAREMA ITEM 7: PARTIDAS - Modo de drenagem: novas partidas deixam de ser formadas
+// As salas existentes continuam funcionando normalmente
+func (s *Servidor) definirDrenagem(ativo bool) {
+	if s.drenando.Swap(ativo) == ativo {
+		return
+	}
+	slog.Warn("modo de drenagem alterado", "ativo", ativo)
+	if !ativo {
+		return
+	}
+
+	// Quem estava aguardando um oponente sai da fila
+	s.filaMutex.Lock()
+	aguardando := s.filaDeEspera // A referência da fila passa para cá
+	s.filaDeEspera = nil
+	s.filaMutex.Unlock()
+	if aguardando != nil {
+		s.presencaMudou(aguardando, protocolo.PresencaOnline)
+		s.avisarManutencao(aguardando)
+		aguardando.soltar()
+	}
+}
+
+func (s *Servidor) avisarManutencao(cliente *Cliente) {
+	s.enviar(cliente, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Servidor em manutenção: novas partidas estão suspensas no momento."}),
+	})
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Sequência de desligamento gracioso
+// Chamada por main ao receber o sinal de desligamento
+func (s *Servidor) desligar() {
+	s.encerrando.Store(true)
+	s.definirDrenagem(true)
+	prazo := s.cfg.PrazoDesligamento
+	slog.Warn("desligamento iniciado", "prazoPartidas", prazo)
+
+	// 1. Nenhuma conexão nova em nenhum transporte
+	s.fecharTransportes()
+
+	// 2. Aviso de manutenção para todos os conectados
+	s.avisarTodos(fmt.Sprintf("[SISTEMA] O servidor entrará em manutenção. Partidas em andamento têm até %v para terminar; novas partidas estão suspensas.", prazo))
+
+	// 3. Partidas em andamento têm até o prazo para chegar ao FIM_DE_JOGO
+	if restantes := s.aguardarPartidas(prazo); restantes > 0 {
+		slog.Warn("prazo de desligamento esgotado", "partidasInterrompidas", restantes)
+	}
+
+	// 4. Grava o estoque e as cartas que ainda estavam em jogo, as listas de
+	// amigos e a moderação
+	if s.cfg.ArquivoEstado != "" {
+		if err := s.salvarEstado(s.cfg.ArquivoEstado); err != nil {
+			slog.Error("erro ao salvar estado", "arquivo", s.cfg.ArquivoEstado, "erro", err)
+		} else {
+			slog.Info("estado salvo", "arquivo", s.cfg.ArquivoEstado)
+		}
+	}
+	if s.cfg.ArquivoAmigos != "" {
+		if err := s.rede.salvar(s.cfg.ArquivoAmigos); err != nil {
+			slog.Error("erro ao salvar amigos", "arquivo", s.cfg.ArquivoAmigos, "erro", err)
+		} else {
+			slog.Info("amigos salvos", "arquivo", s.cfg.ArquivoAmigos)
+		}
+	}
+	if s.cfg.ArquivoModeracao != "" {
+		if err := s.moderacao.salvar(s.cfg.ArquivoModeracao); err != nil {
+			slog.Error("erro ao salvar moderação", "arquivo", s.cfg.ArquivoModeracao, "erro", err)
+		} else {
+			slog.Info("moderação salva", "arquivo", s.cfg.ArquivoModeracao)
+		}
+	}
+
+	// 5. Despede-se e cancela o contexto do servidor: todas as sessões são
+	// encerradas (cada clienteReader faz a limpeza normal) e os workers param
+	s.avisarTodos("[SISTEMA] Servidor desligando. Até logo!")
+	time.Sleep(200 * time.Millisecond) // Dá aos writers a chance de entregar o aviso
+	s.encerrarTudo()
+	if !esperarAte(5*time.Second, func() bool { return s.metricas.conexoesAtivas.Load() == 0 }) {
+		slog.Warn("conexões ainda abertas ao fim do desligamento", "conexoes", s.metricas.conexoesAtivas.Load())
+	}
+
+	// 6. Espera o pool de workers de pacotes terminar
+	s.workers.Wait()
+
+	// 7. Por último a API de administração (mantém /metrics disponível durante a drenagem)
+	if s.admin != nil {
+		ctx, cancelar := context.WithTimeout(context.Background(), 2*time.Second)
+		s.admin.Shutdown(ctx)
+		cancelar()
+	}
+	slog.Info("servidor encerrado")
+}
+
+func (s *Servidor) avisarTodos(texto string) {
+	msg := protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: texto}),
+	}
+	s.paraCadaCliente(func(c *Cliente) {
+		s.enviar(c, msg)
+	})
+}
+
+// aguardarPartidas espera até que nenhuma sala esteja JOGANDO ou o prazo acabe,
+// devolvendo quantas partidas ainda estavam em andamento
+func (s *Servidor) aguardarPartidas(prazo time.Duration) int {
+	emAndamento := func() int {
+		n := 0
+		s.salas.Range(func(_, v any) bool {
+			if v.(*Sala).estadoAtual() == "JOGANDO" {
+				n++
+			}
+			return true
+		})
+		return n
+	}
+	esperarAte(prazo, func() bool { return emAndamento() == 0 })
+	return emAndamento()
+}
+
+// esperarAte consulta a condição periodicamente até ela valer ou o prazo acabar
+func esperarAte(prazo time.Duration, condicao func() bool) bool {
+	limite := time.Now().Add(prazo)
+	for !condicao() {
+		if time.Now().After(limite) {
+			return false
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return true
+}
+
+/* ====================== Persistência ====================== */
+
+// BAREMA ITEM 8: PACOTES - Grava o estado no arquivo (escrita atômica via renomeação)
+func (s *Servidor) salvarEstado(arquivo string) error {
+	estado := estadoPersistido{
+		SalvoEm:               time.Now(),
+		Estoque:               make([]map[string][]Carta, len(s.shardedEstoque)),
+		Maos:                  make(map[string][]Carta),
+		PartidasInterrompidas: make([]infoSala, 0),
+	}
+	for i, shard := range s.shardedEstoque {
+		shard.mutex.Lock()
+		copia := make(map[string][]Carta, len(shard.estoque))
+		for raridade, cartas := range shard.estoque {
+			copia[raridade] = append([]Carta(nil), cartas...)
+		}
+		shard.mutex.Unlock()
+		estado.Estoque[i] = copia
+	}
+	s.paraCadaCliente(func(c *Cliente) {
+		cartas := c.cartas()
+		if sala := c.salaAtual(); sala != nil {
+			cartas = sala.cartasDe(c)
+		}
+		if len(cartas) > 0 {
+			nome := c.nome()
+			estado.Maos[nome] = append(estado.Maos[nome], cartas...)
+		}
+	})
+	s.salas.Range(func(_, v any) bool {
+		if info := v.(*Sala).info(); info.Estado == "JOGANDO" {
+			estado.PartidasInterrompidas = append(estado.PartidasInterrompidas, info)
+		}
+		return true
+	})
+
+	conteudo, err := json.Marshal(estado)
+	if err != nil {
+		return err
+	}
+	return gravarArquivo(arquivo, conteudo)
+}
+
+// gravarArquivo escreve em um arquivo temporário e o renomeia, para nunca deixar um arquivo pela metade
+func gravarArquivo(arquivo string, conteudo []byte) error {
+	if dir := filepath.Dir(arquivo); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	temporario := arquivo + ".tmp"
+	if err := os.WriteFile(temporario, conteudo, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporario, arquivo)
+}
+
+// BAREMA ITEM 8: PACOTES - Restaura o estoque salvo no último desligamento
+// As cartas que estavam nas mãos dos jogadores voltam ao estoque, já que as
+// partidas interrompidas não são retomadas. O arquivo é renomeado após a
+// leitura para que um reinício sem desligamento gracioso não duplique cartas.
+func carregarEstado(arquivo string, numShards int) ([]map[string][]Carta, error) {
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		return nil, err
+	}
+	var estado estadoPersistido
+	if err := json.Unmarshal(conteudo, &estado); err != nil {
+		return nil, fmt.Errorf("estado %s: %w", arquivo, err)
+	}
+
+	estoques := make([]map[string][]Carta, numShards)
+	for i := range estoques {
+		estoques[i] = map[string][]Carta{"C": {}, "U": {}, "R": {}, "L": {}}
+	}
+	// Redistribui em rodízio: o número de shards pode ter mudado entre execuções
+	proximo := 0
+	devolver := func(cartas []Carta) {
+		for _, c := range cartas {
+			shard := estoques[proximo%numShards]
+			shard[c.Raridade] = append(shard[c.Raridade], c)
+			proximo++
+			avancarIDs(c.ID)
+		}
+	}
+	for _, shard := range estado.Estoque {
+		for _, cartas := range shard {
+			devolver(cartas)
+		}
+	}
+	for _, cartas := range estado.Maos {
+		devolver(cartas)
+	}
+	if proximo == 0 {
+		return nil, errors.New("estado sem cartas no estoque")
+	}
+
+	if err := os.Rename(arquivo, arquivo+".carregado"); err != nil {
+		return nil, err
+	}
+	slog.Info("estoque restaurado", "arquivo", arquivo, "cartas", proximo, "salvoEm", estado.SalvoEm,
+		"partidasInterrompidas", len(estado.PartidasInterrompidas))
+	return estoques, nil
+}
+
+// avancarIDs garante que novoID não repita os IDs das cartas restauradas
+func avancarIDs(id string) {
+	n, err := strconv.ParseInt(strings.TrimPrefix(id, "c"), 10, 64)
+	if err != nil {
+		return
+	}
+	for {
+		atual := atomic.LoadInt64(&idSeq)
+		if n <= atual || atomic.CompareAndSwapInt64(&idSeq, atual, n) {
+			return
+		}
+	}
+}
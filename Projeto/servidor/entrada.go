@@ -0,0 +1,259 @@
+package main
+
+// ===================== BAREMA ITEM 4: ENCAPSULAMENTO =====================
+// Proteção contra entradas malformadas ou grandes demais.
+// O decodificador JSON de cada cliente lê através de um leitor com limite de
+// bytes por mensagem; cada mensagem decodificada é validada (comando
+// conhecido, profundidade dos dados, tamanho de textos, nomes e IDs) antes de
+// ser processada. Violações recebem um ERRO com código tipado e, repetidas,
+// levam à desconexão. JSON sintaticamente inválido ou acima do limite de
+// tamanho encerra a conexão de imediato, pois o fluxo não pode ser
+// ressincronizado.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"meujogo/protocolo"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Profundidade máxima de objetos/listas aninhados no campo Dados
+const maxAninhamento = 4
+
+// Tamanho máximo do ID de carta aceito em JOGAR_CARTA
+const maxIDCarta = 32
+
+// Tamanho máximo da senha do LOGIN, em bytes
+const maxSenha = 128
+
+var errMensagemGrande = errors.New("mensagem acima do tamanho máximo")
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Leitor que limita os bytes de uma única mensagem
+// O json.Decoder lê à frente; após cada mensagem o contador é reposto para
+// os bytes que já estão no buffer do decodificador, de modo que o limite vale
+// para cada valor JSON e não para a conexão inteira.
+type leitorLimitado struct {
+	r     io.Reader
+	lidos int
+	max   int
+}
+
+func (l *leitorLimitado) Read(p []byte) (int, error) {
+	if l.lidos >= l.max {
+		return 0, errMensagemGrande
+	}
+	if len(p) > l.max-l.lidos {
+		p = p[:l.max-l.lidos]
+	}
+	n, err := l.r.Read(p)
+	l.lidos += n
+	return n, err
+}
+
+// reiniciar começa a contagem da próxima mensagem
+func (l *leitorLimitado) reiniciar(dec *json.Decoder) {
+	pendentes, _ := io.Copy(io.Discard, dec.Buffered())
+	l.lidos = int(pendentes)
+}
+
+// tratarErroLeitura informa o cliente sobre entradas que tornam o fluxo
+// irrecuperável e diz se a leitura deve terminar
+func (s *Servidor) tratarErroLeitura(c *Cliente, err error) bool {
+	var erroSintaxe *json.SyntaxError
+	var erroTipo *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, errMensagemGrande):
+		s.metricas.observarEntradaInvalida(protocolo.ErroMensagemGrande)
+		c.log().Warn("mensagem acima do tamanho máximo; desconectando", "limite", s.cfg.MaxMensagem)
+		s.despedir(c, protocolo.Mensagem{
+			Comando: "ERRO",
+			Dados: mustJSON(protocolo.DadosErro{
+				Mensagem: fmt.Sprintf("Mensagem maior que %d bytes.", s.cfg.MaxMensagem),
+				Codigo:   protocolo.ErroMensagemGrande,
+			}),
+		})
+		return true
+	case errors.As(err, &erroSintaxe):
+		s.metricas.observarEntradaInvalida(protocolo.ErroJSONInvalido)
+		c.log().Warn("JSON inválido; desconectando", "erro", err)
+		s.despedir(c, protocolo.Mensagem{
+			Comando: "ERRO",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "Mensagem não é um JSON válido.", Codigo: protocolo.ErroJSONInvalido}),
+		})
+		return true
+	case errors.As(err, &erroTipo):
+		// O valor foi consumido por inteiro; o fluxo continua utilizável
+		c.leitor.reiniciar(c.Decoder)
+		return s.registrarViolacao(c, protocolo.ErroDadosInvalidos, "Mensagem com campos de tipo inválido.")
+	}
+	return true // EOF, prazo de leitura esgotado ou conexão fechada
+}
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Valida comando, profundidade e tamanhos dos campos
+// Devolve o código do erro e uma descrição, ou "" se a mensagem é válida
+func (s *Servidor) validarMensagem(msg *protocolo.Mensagem) (string, string) {
+	if !comandosConhecidos[msg.Comando] {
+		return protocolo.ErroComandoDesconhecido, fmt.Sprintf("Comando desconhecido: %.32q.", msg.Comando)
+	}
+	if profundidadeJSON(msg.Dados) > maxAninhamento {
+		return protocolo.ErroAninhamento, "Dados aninhados demais."
+	}
+
+	switch msg.Comando {
+	case "LOGIN":
+		var d protocolo.DadosLogin
+		if json.Unmarshal(msg.Dados, &d) != nil || strings.TrimSpace(d.Nome) == "" || !textoImprimivel(d.Nome) {
+			return protocolo.ErroDadosInvalidos, "LOGIN requer um nome sem caracteres de controle."
+		}
+		if utf8.RuneCountInString(d.Nome) > s.cfg.MaxNome {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Nome com mais de %d caracteres.", s.cfg.MaxNome)
+		}
+		if !textoImprimivel(d.Senha) {
+			return protocolo.ErroDadosInvalidos, "Senha com caracteres de controle."
+		}
+		if len(d.Senha) > maxSenha {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Senha com mais de %d bytes.", maxSenha)
+		}
+	case "ENVIAR_CHAT":
+		var d protocolo.DadosEnviarChat
+		if json.Unmarshal(msg.Dados, &d) != nil || strings.TrimSpace(d.Texto) == "" || !textoImprimivel(d.Texto) {
+			return protocolo.ErroDadosInvalidos, "ENVIAR_CHAT requer um texto sem caracteres de controle."
+		}
+		if utf8.RuneCountInString(d.Texto) > s.cfg.MaxTextoChat {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Mensagem de chat com mais de %d caracteres.", s.cfg.MaxTextoChat)
+		}
+		if len(d.Canal) > maxNomeCanal {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Nome de canal com mais de %d caracteres.", maxNomeCanal)
+		}
+	case "ENTRAR_CANAL", "SAIR_CANAL":
+		var d protocolo.DadosCanal
+		if json.Unmarshal(msg.Dados, &d) != nil || d.Canal == "" {
+			return protocolo.ErroDadosInvalidos, msg.Comando + " requer o nome de um canal."
+		}
+		if len(d.Canal) > maxNomeCanal {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Nome de canal com mais de %d caracteres.", maxNomeCanal)
+		}
+	case "ADICIONAR_AMIGO", "REMOVER_AMIGO", "DESAFIAR", "ACEITAR_DESAFIO", "RECUSAR_DESAFIO", "BLOQUEAR", "DESBLOQUEAR":
+		var d protocolo.DadosAmigo
+		if json.Unmarshal(msg.Dados, &d) != nil || strings.TrimSpace(d.Nome) == "" || !textoImprimivel(d.Nome) {
+			return protocolo.ErroDadosInvalidos, msg.Comando + " requer o nome de um jogador."
+		}
+		if utf8.RuneCountInString(d.Nome) > s.cfg.MaxNome {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Nome com mais de %d caracteres.", s.cfg.MaxNome)
+		}
+	case "MENSAGEM_PRIVADA":
+		var d protocolo.DadosEnviarPrivada
+		if json.Unmarshal(msg.Dados, &d) != nil || d.Para == "" || !textoImprimivel(d.Para) || strings.TrimSpace(d.Texto) == "" || !textoImprimivel(d.Texto) {
+			return protocolo.ErroDadosInvalidos, "MENSAGEM_PRIVADA requer o destinatário e um texto sem caracteres de controle."
+		}
+		if utf8.RuneCountInString(d.Para) > s.cfg.MaxNome {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Nome com mais de %d caracteres.", s.cfg.MaxNome)
+		}
+		if utf8.RuneCountInString(d.Texto) > s.cfg.MaxTextoChat {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Mensagem privada com mais de %d caracteres.", s.cfg.MaxTextoChat)
+		}
+	case "DENUNCIAR":
+		var d protocolo.DadosDenuncia
+		if json.Unmarshal(msg.Dados, &d) != nil || strings.TrimSpace(d.Nome) == "" || !textoImprimivel(d.Nome) || !textoImprimivel(d.Motivo) {
+			return protocolo.ErroDadosInvalidos, "DENUNCIAR requer o nome de um jogador e um motivo sem caracteres de controle."
+		}
+		if utf8.RuneCountInString(d.Nome) > s.cfg.MaxNome {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Nome com mais de %d caracteres.", s.cfg.MaxNome)
+		}
+		if utf8.RuneCountInString(d.Motivo) > s.cfg.MaxTextoChat {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("Motivo com mais de %d caracteres.", s.cfg.MaxTextoChat)
+		}
+	case "JOGAR_CARTA":
+		var d protocolo.DadosJogarCarta
+		if json.Unmarshal(msg.Dados, &d) != nil || d.CartaID == "" {
+			return protocolo.ErroDadosInvalidos, "JOGAR_CARTA requer o ID de uma carta."
+		}
+		if len(d.CartaID) > maxIDCarta {
+			return protocolo.ErroCampoLongo, fmt.Sprintf("ID de carta com mais de %d caracteres.", maxIDCarta)
+		}
+	case "PING", "PONG":
+		var d protocolo.DadosPing
+		if json.Unmarshal(msg.Dados, &d) != nil {
+			return protocolo.ErroDadosInvalidos, msg.Comando + " requer um timestamp."
+		}
+	case "COMPRAR_PACOTE":
+		// Dados opcionais
+		if len(msg.Dados) > 0 && !bytes.Equal(msg.Dados, []byte("null")) {
+			var d protocolo.ComprarPacoteReq
+			if json.Unmarshal(msg.Dados, &d) != nil {
+				return protocolo.ErroDadosInvalidos, "COMPRAR_PACOTE com dados inválidos."
+			}
+		}
+	}
+	return "", ""
+}
+
+// registrarViolacao envia o erro tipado e diz se o cliente deve ser desconectado
+func (s *Servidor) registrarViolacao(c *Cliente, codigo, descricao string) bool {
+	c.violacoes++
+	s.metricas.observarEntradaInvalida(codigo)
+	erro := protocolo.Mensagem{
+		Comando: "ERRO",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: descricao, Codigo: codigo}),
+	}
+	if c.violacoes >= s.cfg.MaxViolacoes {
+		c.log().Warn("cliente desconectado por entradas inválidas", "codigo", codigo, "violacoes", c.violacoes)
+		s.despedir(c, erro)
+		return true
+	}
+	s.enviar(c, erro)
+	return false
+}
+
+// despedir envia uma última mensagem e dá ao writer a chance de entregá-la
+// antes que o clienteReader retorne e a conexão seja fechada
+func (s *Servidor) despedir(c *Cliente, msg protocolo.Mensagem) {
+	if s.enviar(c, msg) {
+		esperar(c.ctx, 200*time.Millisecond)
+	}
+}
+
+// profundidadeJSON mede o maior aninhamento de objetos/listas em um valor JSON
+func profundidadeJSON(dados []byte) int {
+	profundidade, maior := 0, 0
+	emString, escape := false, false
+	for _, b := range dados {
+		switch {
+		case escape:
+			escape = false
+		case emString:
+			if b == '\\' {
+				escape = true
+			} else if b == '"' {
+				emString = false
+			}
+		case b == '"':
+			emString = true
+		case b == '{' || b == '[':
+			profundidade++
+			maior = max(maior, profundidade)
+		case b == '}' || b == ']':
+			profundidade--
+		}
+	}
+	return maior
+}
+
+// textoImprimivel rejeita UTF-8 inválido e caracteres de controle
+func textoImprimivel(texto string) bool {
+	if !utf8.ValidString(texto) {
+		return false
+	}
+	for _, r := range texto {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
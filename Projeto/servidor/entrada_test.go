@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"meujogo/protocolo"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BAREMA ITEM 9: TESTES - Fuzzing da leitura e validação das mensagens
+// O fluxo de bytes passa pelo mesmo caminho do clienteReader: leitorLimitado,
+// json.Decoder, profundidadeJSON e validarMensagem. Nada pode entrar em
+// pânico, nenhuma mensagem aceita pode ter mais bytes que o limite, e tudo o
+// que a validação deixa passar respeita os limites dos campos de texto.
+// Sementes em testdata/fuzz/FuzzEntrada; para explorar:
+//
+//	go test -run '^$' -fuzz FuzzEntrada ./servidor
+func FuzzEntrada(f *testing.F) {
+	f.Add([]byte(`{"comando":"LOGIN","dados":{"nome":"ana"}}{"comando":"ENVIAR_CHAT","dados":{"texto":"oi"}}`))
+	f.Add([]byte(`{"comando":"ENVIAR_CHAT","dados":{"texto":"\u001b[2J"}}`))
+	f.Add([]byte(`{"comando":"PING","dados":{"a":[[[[[1]]]]]}}`))
+
+	s := &Servidor{cfg: configPadrao()}
+	s.cfg.MaxMensagem = 512 // Pequeno para que o fuzzer alcance o limite
+	f.Fuzz(func(t *testing.T, dados []byte) {
+		leitor := &leitorLimitado{r: bytes.NewReader(dados), max: s.cfg.MaxMensagem}
+		dec := json.NewDecoder(leitor)
+		for {
+			anterior := dec.InputOffset()
+			var msg protocolo.Mensagem
+			if err := dec.Decode(&msg); err != nil {
+				var erroTipo *json.UnmarshalTypeError
+				if errors.As(err, &erroTipo) {
+					leitor.reiniciar(dec)
+					continue
+				}
+				return // Fim dos dados, JSON inválido ou mensagem grande demais
+			}
+			if lidos := dec.InputOffset() - anterior; lidos > int64(s.cfg.MaxMensagem) {
+				t.Fatalf("mensagem de %d bytes aceita com limite de %d", lidos, s.cfg.MaxMensagem)
+			}
+			leitor.reiniciar(dec)
+
+			if obtida, esperada := profundidadeJSON(msg.Dados), profundidadeReferencia(t, msg.Dados); obtida != esperada {
+				t.Fatalf("profundidadeJSON(%s) = %d, esperado %d", msg.Dados, obtida, esperada)
+			}
+			if codigo, _ := s.validarMensagem(&msg); codigo == "" {
+				conferirAceita(t, s.cfg, &msg)
+			}
+		}
+	})
+}
+
+// profundidadeReferencia mede o aninhamento com os tokens do encoding/json
+func profundidadeReferencia(t *testing.T, dados []byte) int {
+	dec := json.NewDecoder(bytes.NewReader(dados))
+	profundidade, maior := 0, 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("json.RawMessage inválido após Decode: %v", err)
+			}
+			return maior
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			profundidade++
+			maior = max(maior, profundidade)
+		case json.Delim('}'), json.Delim(']'):
+			profundidade--
+		}
+	}
+}
+
+// conferirAceita verifica os limites de uma mensagem que passou na validação
+func conferirAceita(t *testing.T, cfg Config, msg *protocolo.Mensagem) {
+	t.Helper()
+	if !comandosConhecidos[msg.Comando] {
+		t.Fatalf("comando desconhecido aceito: %q", msg.Comando)
+	}
+	if p := profundidadeJSON(msg.Dados); p > maxAninhamento {
+		t.Fatalf("dados com aninhamento %d aceitos", p)
+	}
+	texto := func(campo, valor string, vazio bool, limite int) {
+		if !vazio && strings.TrimSpace(valor) == "" {
+			t.Fatalf("%s %s vazio aceito", msg.Comando, campo)
+		}
+		if strings.ContainsFunc(valor, unicode.IsControl) {
+			t.Fatalf("%s %s com caractere de controle aceito: %q", msg.Comando, campo, valor)
+		}
+		if utf8.RuneCountInString(valor) > limite {
+			t.Fatalf("%s %s com %d caracteres aceito", msg.Comando, campo, utf8.RuneCountInString(valor))
+		}
+	}
+	switch msg.Comando {
+	case "LOGIN":
+		var d protocolo.DadosLogin
+		json.Unmarshal(msg.Dados, &d)
+		texto("nome", d.Nome, false, cfg.MaxNome)
+		if !textoImprimivel(d.Senha) || len(d.Senha) > maxSenha {
+			t.Fatalf("LOGIN com senha inválida aceito: %q", d.Senha)
+		}
+	case "ENVIAR_CHAT":
+		var d protocolo.DadosEnviarChat
+		json.Unmarshal(msg.Dados, &d)
+		texto("texto", d.Texto, false, cfg.MaxTextoChat)
+	case "MENSAGEM_PRIVADA":
+		var d protocolo.DadosEnviarPrivada
+		json.Unmarshal(msg.Dados, &d)
+		texto("para", d.Para, false, cfg.MaxNome)
+		texto("texto", d.Texto, false, cfg.MaxTextoChat)
+	case "DENUNCIAR":
+		var d protocolo.DadosDenuncia
+		json.Unmarshal(msg.Dados, &d)
+		texto("nome", d.Nome, false, cfg.MaxNome)
+		texto("motivo", d.Motivo, true, cfg.MaxTextoChat)
+	}
+}
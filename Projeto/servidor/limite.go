@@ -0,0 +1,263 @@
+package main
+
+// ===================== BAREMA ITEM 5: CONCORRÊNCIA =====================
+// Limitação de taxa e proteção contra flood.
+// Cada cliente tem um balde de tokens por classe de comando (configurável
+// por comando em -limites). Excessos escalam: primeiro um aviso, depois o
+// leitor do cliente é estrangulado e, persistindo, a conexão é encerrada.
+// Por IP de origem há um limite de conexões simultâneas e de novas conexões
+// por segundo, aplicado antes do semáforo global.
+
+import (
+	"fmt"
+	"log/slog"
+	"meujogo/protocolo"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limites padrão por comando; "*" vale para os comandos sem entrada própria
+const limitesPadrao = "ENVIAR_CHAT=2:5,MENSAGEM_PRIVADA=2:5,DENUNCIAR=0.2:3,COMPRAR_PACOTE=1:3,PING=1:3,PONG=1:3,JOGAR_CARTA=5:10,*=10:20"
+
+// Após este tempo sem excessos o histórico de infrações do cliente é zerado
+const janelaInfracoes = 10 * time.Second
+
+// Pausa aplicada ao leitor de um cliente estrangulado
+const pausaEstrangulamento = time.Second
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Taxa sustentada (tokens/s) e rajada máxima de um balde
+type limite struct {
+	taxa   float64
+	rajada float64
+}
+
+// parseLimites interpreta "COMANDO=taxa:rajada,..." (ex.: "ENVIAR_CHAT=2:5,*=10:20")
+func parseLimites(spec string) (map[string]limite, error) {
+	limites := make(map[string]limite)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		comando, valores, ok := strings.Cut(item, "=")
+		taxaTxt, rajadaTxt, ok2 := strings.Cut(valores, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("limite %q: formato esperado COMANDO=taxa:rajada", item)
+		}
+		comando = strings.ToUpper(strings.TrimSpace(comando))
+		if comando != "*" && !comandosConhecidos[comando] {
+			return nil, fmt.Errorf("limite %q: comando desconhecido", item)
+		}
+		taxa, err1 := strconv.ParseFloat(taxaTxt, 64)
+		rajada, err2 := strconv.ParseFloat(rajadaTxt, 64)
+		if err1 != nil || err2 != nil || taxa <= 0 || rajada < 1 {
+			return nil, fmt.Errorf("limite %q: taxa deve ser positiva e rajada pelo menos 1", item)
+		}
+		limites[comando] = limite{taxa: taxa, rajada: rajada}
+	}
+	if _, ok := limites["*"]; !ok {
+		limites["*"] = limite{taxa: 10, rajada: 20}
+	}
+	return limites, nil
+}
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Balde de tokens
+// Não é seguro para uso concorrente: o balde de um cliente só é usado pelo
+// seu clienteReader e os baldes por IP ficam sob o mutex de limitadorIP
+type balde struct {
+	tokens float64
+	ultimo time.Time
+}
+
+func (b *balde) permitir(l limite, agora time.Time) bool {
+	if b.ultimo.IsZero() {
+		b.tokens = l.rajada
+	} else {
+		b.tokens = min(l.rajada, b.tokens+agora.Sub(b.ultimo).Seconds()*l.taxa)
+	}
+	b.ultimo = agora
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// cheio indica se o balde já teria recuperado toda a rajada
+func (b *balde) cheio(l limite, agora time.Time) bool {
+	return b.tokens+agora.Sub(b.ultimo).Seconds()*l.taxa >= l.rajada
+}
+
+/* ====================== Limites por cliente ====================== */
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Estado de limitação de um cliente
+type limitadorCliente struct {
+	baldes         map[string]*balde // Classe ("COMANDO" ou "*") -> balde
+	infracoes      int               // Excessos dentro da janela atual
+	ultimaInfracao time.Time
+}
+
+// Ações resultantes da verificação de limite
+const (
+	limitePermitir = iota
+	limiteDescartar
+	limiteDesconectar
+)
+
+// aplicarLimite consome um token da classe do comando e, em caso de excesso,
+// escala a resposta: aviso -> estrangulamento -> desconexão
+func (s *Servidor) aplicarLimite(c *Cliente, comando string) int {
+	if comando == "QUIT" {
+		return limitePermitir // Sair nunca é limitado
+	}
+	if comando == "PONG" && c.responderPing() {
+		return limitePermitir // Respostas acumuladas durante um atraso chegam juntas
+	}
+	classe := comando
+	if _, ok := s.limites[classe]; !ok {
+		classe = "*"
+	}
+	l := s.limites[classe]
+
+	lc := &c.limitador
+	if lc.baldes == nil {
+		lc.baldes = make(map[string]*balde)
+	}
+	b, ok := lc.baldes[classe]
+	if !ok {
+		b = &balde{}
+		lc.baldes[classe] = b
+	}
+	agora := time.Now()
+	if b.permitir(l, agora) {
+		return limitePermitir
+	}
+
+	s.metricas.comandosLimitados.Add(1)
+	if agora.Sub(lc.ultimaInfracao) > janelaInfracoes {
+		lc.infracoes = 0
+	}
+	lc.infracoes++
+	lc.ultimaInfracao = agora
+
+	switch {
+	case lc.infracoes >= s.cfg.LimiteDesconectar:
+		s.metricas.desconexoesAbuso.Add(1)
+		c.log().Warn("cliente desconectado por flood", "comando", comando, "infracoes", lc.infracoes)
+		s.despedir(c, protocolo.Mensagem{
+			Comando: "ERRO",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "Você foi desconectado por enviar comandos em excesso.", Codigo: protocolo.ErroLimiteTaxa}),
+		})
+		return limiteDesconectar
+	case lc.infracoes >= s.cfg.LimiteEstrangular:
+		if lc.infracoes == s.cfg.LimiteEstrangular {
+			c.log().Warn("cliente estrangulado por excesso de comandos", "comando", comando)
+			s.enviar(c, protocolo.Mensagem{
+				Comando: "ERRO",
+				Dados: mustJSON(protocolo.DadosErro{
+					Mensagem: "Comandos em excesso: suas mensagens serão processadas mais devagar. Persistindo, você será desconectado.",
+					Codigo:   protocolo.ErroLimiteTaxa,
+				}),
+			})
+		}
+		// Segura o leitor: o buffer TCP enche e o próprio cliente é desacelerado.
+		// A pausa não recarrega o balde, então quem continua enviando no mesmo
+		// ritmo acumula infrações até ser desconectado
+		if !esperar(c.ctx, pausaEstrangulamento) {
+			return limiteDesconectar // Sessão encerrada durante a pausa
+		}
+		b.ultimo = time.Now()
+	case lc.infracoes == 1:
+		s.enviar(c, protocolo.Mensagem{
+			Comando: "ERRO",
+			Dados: mustJSON(protocolo.DadosErro{
+				Mensagem: fmt.Sprintf("Você está enviando %s rápido demais. Aguarde um pouco.", comando),
+				Codigo:   protocolo.ErroLimiteTaxa,
+			}),
+		})
+	}
+	return limiteDescartar
+}
+
+/* ====================== Limites por IP ====================== */
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Conexões por IP de origem
+type estadoIP struct {
+	conexoes int
+	novas    balde // Novas conexões por segundo
+}
+
+type limitadorIP struct {
+	maxConexoes int    // Conexões simultâneas por IP (0 desabilita)
+	taxa        limite // Novas conexões por segundo (taxa 0 desabilita)
+	mutex       sync.Mutex
+	ips         map[string]*estadoIP
+	admissoes   int // Contador para a limpeza periódica do mapa
+}
+
+func novoLimitadorIP(cfg Config) *limitadorIP {
+	return &limitadorIP{
+		maxConexoes: cfg.MaxConexoesPorIP,
+		taxa:        limite{taxa: cfg.ConexoesIPTaxa, rajada: float64(cfg.ConexoesIPRajada)},
+		ips:         make(map[string]*estadoIP),
+	}
+}
+
+// admitir registra uma nova conexão do IP, ou a recusa informando o motivo
+func (l *limitadorIP) admitir(ip string) (bool, string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	agora := time.Now()
+	if l.admissoes++; l.admissoes%1024 == 0 {
+		l.limpar(agora)
+	}
+	e, ok := l.ips[ip]
+	if !ok {
+		e = &estadoIP{}
+		l.ips[ip] = e
+	}
+	if l.maxConexoes > 0 && e.conexoes >= l.maxConexoes {
+		return false, "conexoes"
+	}
+	if l.taxa.taxa > 0 && !e.novas.permitir(l.taxa, agora) {
+		return false, "taxa"
+	}
+	e.conexoes++
+	return true, ""
+}
+
+func (l *limitadorIP) liberar(ip string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if e, ok := l.ips[ip]; ok && e.conexoes > 0 {
+		e.conexoes--
+	}
+}
+
+// limpar remove IPs sem conexões cujo balde já se recuperou
+func (l *limitadorIP) limpar(agora time.Time) {
+	for ip, e := range l.ips {
+		if e.conexoes == 0 && (l.taxa.taxa <= 0 || e.novas.cheio(l.taxa, agora)) {
+			delete(l.ips, ip)
+		}
+	}
+}
+
+// ipDe extrai o IP do endereço remoto de uma conexão
+func ipDe(endereco net.Addr) string {
+	host, _, err := net.SplitHostPort(endereco.String())
+	if err != nil {
+		return endereco.String()
+	}
+	return host
+}
+
+func registrarRecusaIP(ip, motivo string) {
+	if amostraRecusaIP.permitir() {
+		slog.Warn("conexão recusada pelo limite por IP", "ip", ip, "motivo", motivo)
+	}
+}
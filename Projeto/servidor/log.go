@@ -0,0 +1,83 @@
+package main
+
+// ===================== BAREMA ITEM 6: LATÊNCIA =====================
+// Logging estruturado com log/slog.
+// Cada entrada carrega atributos de contexto ("jogador", "sala"), o nível é
+// ajustável em tempo de execução pela API de administração e eventos
+// ruidosos dos caminhos quentes passam por amostragem.
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// BAREMA ITEM 6: LATÊNCIA - Nível global do log, alterável sem reiniciar o servidor
+var nivelLog = new(slog.LevelVar)
+
+// configurarLog instala o logger padrão no formato ("texto" ou "json") e nível pedidos
+func configurarLog(formato, nivel string) error {
+	if err := definirNivelLog(nivel); err != nil {
+		return err
+	}
+	opcoes := &slog.HandlerOptions{Level: nivelLog}
+
+	var handler slog.Handler
+	switch strings.ToLower(formato) {
+	case "", "texto", "text":
+		handler = slog.NewTextHandler(os.Stdout, opcoes)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opcoes)
+	default:
+		return fmt.Errorf("formato de log desconhecido: %q (use texto ou json)", formato)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// definirNivelLog aceita debug, info, warn ou error
+func definirNivelLog(nivel string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(nivel)); err != nil {
+		return fmt.Errorf("nível de log inválido: %q", nivel)
+	}
+	nivelLog.Set(l)
+	return nil
+}
+
+// log devolve o logger com o contexto do jogador e da sala em que ele está
+func (c *Cliente) log() *slog.Logger {
+	c.mutex.Lock()
+	nome, sala := c.Nome, c.Sala
+	c.mutex.Unlock()
+	if sala != nil {
+		return slog.With("jogador", nome, "sessao", c.Sessao, "sala", sala.ID)
+	}
+	return slog.With("jogador", nome, "sessao", c.Sessao)
+}
+
+// log devolve o logger com o contexto da sala
+func (sala *Sala) log() *slog.Logger {
+	return slog.With("sala", sala.ID)
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Amostragem de eventos ruidosos
+// Com 10k bots, eventos como cada PONG dominariam a CPU se fossem todos
+// registrados; o amostrador deixa passar 1 a cada n ocorrências.
+type amostrador struct {
+	n        uint64
+	contador atomic.Uint64
+}
+
+func (a *amostrador) permitir() bool {
+	return a.n <= 1 || a.contador.Add(1)%a.n == 1
+}
+
+var (
+	amostraPong         = &amostrador{n: 100} // Latência medida a cada PONG
+	amostraPedidoPacote = &amostrador{n: 100} // Pedidos de pacote enfileirados
+	amostraMailboxCheia = &amostrador{n: 50}  // Descartes na fila de saída
+	amostraRecusaIP     = &amostrador{n: 100} // Conexões recusadas pelo limite por IP
+)
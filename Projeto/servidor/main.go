@@ -7,39 +7,69 @@ package main
 // lógica do jogo, e comunicação entre jogadores.
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"meujogo/protocolo"
+	"meujogo/seguranca"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic" // BAREMA ITEM 5: CONCORRÊNCIA - Usar pacote atomic para contadores thread-safe
+	"syscall"
 	"time"
 )
 
 // BAREMA ITEM 5: CONCORRÊNCIA - Configurações de sharding para distribuir carga
-// O sharding divide o estoque e operações em múltiplas partições para reduzir contenção
+// O sharding divide operações em múltiplas partições para reduzir contenção.
+// O número de shards do estoque vem de Config.ShardsEstoque.
 const (
-	numFilaShards    = 32 // Número de shards para distribuir operações de fila
-	numEstoqueShards = 32 // Número de shards para distribuir operações de estoque
+	numFilaShards = 32 // Número de shards para distribuir operações de fila
 )
 
 /* ====================== Tipos e Pools de Otimização ====================== */
 type Carta = protocolo.Carta
 
+// BAREMA ITEM 6: LATÊNCIA - Mesmo estimador de RTT usado pelos clientes
+type Latencia = protocolo.Latencia
+
 // BAREMA ITEM 1: ARQUITETURA - Estrutura que representa um cliente conectado
-// Cada cliente possui sua própria conexão TCP, inventário de cartas e estado de jogo
+// Cada cliente possui sua própria conexão TCP e estado de jogo. Fora de uma
+// partida as cartas ficam no Inventario; ao entrar em uma sala elas passam
+// para a mão do jogador nela (Sala.Maos)
 type Cliente struct {
-	Conn       net.Conn                // Conexão TCP com o cliente
-	Nome       string                  // Nome único do jogador
-	Encoder    *json.Encoder           // Codificador JSON para envio de mensagens
-	Decoder    *json.Decoder           // Decodificador JSON para recebimento de mensagens
-	Mailbox    chan protocolo.Mensagem // Canal para envio assíncrono de mensagens
-	Sala       *Sala                   // Referência para a sala onde o jogador está
-	Inventario []Carta                 // Cartas que o jogador possui
-	UltimoPing time.Time               // BAREMA ITEM 6: LATÊNCIA - Timestamp do último ping
-	PingMs     int64                   // BAREMA ITEM 6: LATÊNCIA - Latência medida em milissegundos
+	Conn        net.Conn         // Conexão TCP com o cliente
+	Nome        string           // Nome único do jogador
+	Encoder     *json.Encoder    // Codificador JSON para envio de mensagens
+	Decoder     *json.Decoder    // Decodificador JSON para recebimento de mensagens
+	Mailbox     *caixaSaida      // BAREMA ITEM 2: COMUNICAÇÃO - Fila de saída com prioridades
+	Sala        *Sala            // Referência para a sala onde o jogador está
+	Inventario  []Carta          // Cartas compradas no lobby, fora de uma sala
+	UltimoPong  atomic.Int64     // BAREMA ITEM 6: LATÊNCIA - Chegada do último PONG (UnixNano)
+	UltimaAcao  atomic.Int64     // Último comando recebido, sem contar PING/PONG (UnixNano)
+	Latencia    Latencia         // BAREMA ITEM 6: LATÊNCIA - RTT, média e jitter medidos pelos PONGs
+	Certificado string           // CN do certificado de cliente validado via TLS mútuo ("" se não houver)
+	limitador   limitadorCliente // BAREMA ITEM 5: CONCORRÊNCIA - Baldes de tokens por classe de comando
+	leitor      *leitorLimitado  // BAREMA ITEM 4: ENCAPSULAMENTO - Limita o tamanho de cada mensagem recebida
+	violacoes   int              // Entradas inválidas recebidas nesta conexão
+	canais      []string         // BAREMA ITEM 2: COMUNICAÇÃO - Canais do lobby em que está inscrito (só o leitor usa)
+	bloqueados  listaBloqueio    // Quem o jogador bloqueou, lido sem lock na entrega do chat
+	recentes    recentesChat     // Últimas linhas de chat, anexadas às denúncias
+	atrasado    atomic.Bool      // BAREMA ITEM 6: LATÊNCIA - Já anunciado ao oponente como atrasado
+	pendentes   atomic.Int32     // PINGs enviados ainda sem PONG (ver responderPing)
+	mutex       sync.Mutex       // BAREMA ITEM 5: CONCORRÊNCIA - Protege Nome, Sala e Inventario
+	Sessao      uint64           // Identificador único da conexão atual do objeto
+	ctx         context.Context  // BAREMA ITEM 5: CONCORRÊNCIA - Cancelado quando a sessão termina
+	cancelar    context.CancelFunc
+	refs        atomic.Int32 // Referências vivas; na última o objeto volta ao pool
 }
 
 // BAREMA ITEM 5: CONCORRÊNCIA - Pool de objetos para reutilização e otimização de memória
@@ -47,26 +77,69 @@ type Cliente struct {
 var clientePool = sync.Pool{
 	New: func() interface{} {
 		return &Cliente{
-			Mailbox:    make(chan protocolo.Mensagem, 32), // Canal com buffer para mensagens
-			Inventario: make([]Carta, 0, 64),              // Slice pré-alocado para cartas
+			Mailbox:    novaCaixaSaida(),     // Filas de saída por prioridade
+			Inventario: make([]Carta, 0, 64), // Slice pré-alocado para cartas
 		}
 	},
 }
 
-// BAREMA ITEM 7: PARTIDAS - Estrutura que representa uma sala de jogo
-// Gerencia o estado de uma partida entre dois jogadores
-type Sala struct {
-	ID              string           // Identificador único da sala
-	Jogadores       []*Cliente       // Lista dos jogadores na sala (sempre 2)
-	Estado          string           // Estado atual: "AGUARDANDO_COMPRA" | "JOGANDO" | "FINALIZADO"
-	CartasNaMesa    map[string]Carta // Cartas jogadas na jogada atual (nome -> carta)
-	PontosRodada    map[string]int   // Pontos de cada jogador na rodada atual
-	PontosPartida   map[string]int   // Rodadas ganhas por cada jogador na partida
-	NumeroRodada    int              // Número da rodada atual (1, 2, 3...)
-	JogadasNaRodada int              // Quantas jogadas foram feitas na rodada atual (0, 1, 2)
-	Prontos         map[string]bool  // Quais jogadores já compraram pacotes para esta partida
-	srv             *Servidor        // Referência para o servidor principal
-	mutex           sync.Mutex       // BAREMA ITEM 5: CONCORRÊNCIA - Mutex para proteger acesso concorrente
+// BAREMA ITEM 5: CONCORRÊNCIA - Acesso ao estado compartilhado do cliente
+// Nome, sala e inventário também são lidos e alterados por salas, workers de
+// pacotes e pela API de administração, por isso passam pelo mutex do cliente
+func (c *Cliente) nome() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.Nome
+}
+
+func (c *Cliente) definirNome(nome string) {
+	c.mutex.Lock()
+	c.Nome = nome
+	c.mutex.Unlock()
+}
+
+func (c *Cliente) salaAtual() *Sala {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.Sala
+}
+
+// entrarNaSala associa o cliente à sala e devolve as cartas do lobby, que
+// passam a ser a mão dele na sala
+func (c *Cliente) entrarNaSala(sala *Sala) []Carta {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Sala = sala
+	cartas := slices.Clone(c.Inventario)
+	c.Inventario = c.Inventario[:0] // Limpa o slice mantendo capacidade
+	return cartas
+}
+
+// guardarNoLobby junta as cartas ao inventário se o cliente está fora de uma
+// sala; senão não as guarda e devolve a sala, dona da mão dele
+func (c *Cliente) guardarNoLobby(cartas []Carta) *Sala {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.Sala == nil {
+		c.Inventario = append(c.Inventario, cartas...)
+	}
+	return c.Sala
+}
+
+// cartas devolve uma cópia do inventário
+func (c *Cliente) cartas() []Carta {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return slices.Clone(c.Inventario)
+}
+
+// sairDaSala desfaz a associação apenas se o cliente ainda estiver nesta sala
+func (c *Cliente) sairDaSala(sala *Sala) {
+	c.mutex.Lock()
+	if c.Sala == sala {
+		c.Sala = nil
+	}
+	c.mutex.Unlock()
 }
 
 // BAREMA ITEM 5: CONCORRÊNCIA - Shard para operações de fila de espera
@@ -86,44 +159,91 @@ type estoqueShard struct {
 // BAREMA ITEM 1: ARQUITETURA - Estrutura principal do servidor
 // Centraliza todas as operações do servidor: conexões, salas, estoque, etc.
 type Servidor struct {
-	clientes       sync.Map        // BAREMA ITEM 5: CONCORRÊNCIA - Map thread-safe para clientes conectados
-	salas          sync.Map        // BAREMA ITEM 5: CONCORRÊNCIA - Map thread-safe para salas ativas
-	filaDeEspera   *Cliente        // BAREMA ITEM 7: PARTIDAS - Cliente aguardando matchmaking
-	filaMutex      sync.Mutex      // BAREMA ITEM 5: CONCORRÊNCIA - Mutex para proteger fila de espera
-	shardedEstoque []*estoqueShard // BAREMA ITEM 8: PACOTES - Array de shards do estoque
-	packSize       int             // Número de cartas por pacote
-	packWorkers    int             // Número de workers para processar compras
-	packWorkerPool chan packReq    // BAREMA ITEM 5: CONCORRÊNCIA - Canal para fila de requisições de pacotes
+	cfg            Config                     // BAREMA ITEM 1: ARQUITETURA - Configuração validada no boot
+	clientes       sync.Map                   // BAREMA ITEM 5: CONCORRÊNCIA - Map thread-safe para clientes conectados
+	salas          sync.Map                   // BAREMA ITEM 5: CONCORRÊNCIA - Map thread-safe para salas ativas
+	filaDeEspera   *Cliente                   // BAREMA ITEM 7: PARTIDAS - Cliente aguardando matchmaking
+	filaMutex      sync.Mutex                 // BAREMA ITEM 5: CONCORRÊNCIA - Mutex para proteger fila de espera
+	shardedEstoque []*estoqueShard            // BAREMA ITEM 8: PACOTES - Array de shards do estoque
+	packWorkers    int                        // Número de workers para processar compras
+	packWorkerPool chan packReq               // BAREMA ITEM 5: CONCORRÊNCIA - Canal para fila de requisições de pacotes
+	semaforo       chan struct{}              // BAREMA ITEM 5: CONCORRÊNCIA - Limita conexões simultâneas (todos os transportes)
+	metricas       metricas                   // BAREMA ITEM 6: LATÊNCIA - Contadores expostos em /metrics
+	regras         atomic.Pointer[RegrasJogo] // BAREMA ITEM 8: PACOTES - Regras de jogo vigentes (recarregáveis)
+	regrasMutex    sync.Mutex                 // Serializa recargas vindas do arquivo, SIGHUP e API
+	limites        map[string]limite          // BAREMA ITEM 5: CONCORRÊNCIA - Limite de taxa por classe de comando
+	limitesIP      *limitadorIP               // BAREMA ITEM 5: CONCORRÊNCIA - Conexões por IP de origem
+	workers        sync.WaitGroup             // BAREMA ITEM 5: CONCORRÊNCIA - Workers de pacotes em execução
+	ctx            context.Context            // BAREMA ITEM 5: CONCORRÊNCIA - Pai dos contextos das sessões e dos workers
+	encerrarTudo   context.CancelFunc         // Cancela ctx no desligamento
+	drenando       atomic.Bool                // BAREMA ITEM 7: PARTIDAS - Novas partidas suspensas (drenagem)
+	encerrando     atomic.Bool                // BAREMA ITEM 1: ARQUITETURA - Desligamento em andamento
+	admin          *http.Server               // Servidor HTTP da API de administração (nil se desabilitada)
+	transportes    []Transporte               // BAREMA ITEM 2: COMUNICAÇÃO - Origens de conexões em uso
+	transpMutex    sync.Mutex                 // Protege transportes
+	canais         map[string]*canal          // BAREMA ITEM 2: COMUNICAÇÃO - Canais de chat do lobby (fixos desde o boot)
+	rede           *redeAmigos                // BAREMA ITEM 2: COMUNICAÇÃO - Amigos, presença e mensagens privadas
+	moderacao      *moderacao                 // BAREMA ITEM 2: COMUNICAÇÃO - Silêncios, banimentos e denúncias
 }
 
 // BAREMA ITEM 8: PACOTES - Estrutura para requisição de compra de pacote
 // Usada para enviar requisições para o pool de workers
 type packReq struct {
-	cli        *Cliente // Cliente que solicitou a compra
-	quantidade int      // Quantidade de pacotes solicitados
+	ctx        context.Context // Contexto da sessão que pediu; cancelado o pedido é descartado
+	cli        *Cliente        // Cliente que solicitou a compra
+	quantidade int             // Quantidade de pacotes solicitados
 }
 
 /* ====================== Servidor / bootstrap ====================== */
 
 // BAREMA ITEM 1: ARQUITETURA - Inicialização do servidor com todas as configurações
 // Configura pools de workers, shards de estoque e outras estruturas de concorrência
-func novoServidor() *Servidor {
+func novoServidor(cfg Config, regras *RegrasJogo) *Servidor {
 	s := &Servidor{
-		packSize:       5,                                       // 5 cartas por pacote
-		packWorkers:    1000,                                    // BAREMA ITEM 5: CONCORRÊNCIA - 1000 workers para processar compras
-		packWorkerPool: make(chan packReq, 100000),              // Canal com buffer grande para requisições
-		shardedEstoque: make([]*estoqueShard, numEstoqueShards), // Inicializa array de shards
+		cfg:            cfg,
+		packWorkers:    cfg.PackWorkers,                          // BAREMA ITEM 5: CONCORRÊNCIA - Workers para processar compras
+		packWorkerPool: make(chan packReq, cfg.PackFila),         // Canal com buffer grande para requisições
+		shardedEstoque: make([]*estoqueShard, cfg.ShardsEstoque), // Inicializa array de shards
+		semaforo:       make(chan struct{}, cfg.MaxConexoes),     // Semáforo para limitar conexões simultâneas
+		limitesIP:      novoLimitadorIP(cfg),
+		canais:         make(map[string]*canal),
 	}
-
-	// BAREMA ITEM 8: PACOTES - Gera estoque inicial distribuído entre os shards
-	estoquesIniciais := gerarEstoquesIniciais()
-	for i := 0; i < numEstoqueShards; i++ {
+	for _, nome := range nomesCanais(cfg.Canais) {
+		s.canais[nome] = novoCanal(nome, cfg.HistoricoCanal)
+	}
+	s.metricas.rtt = novoHistograma(bucketsRTT)
+	// BAREMA ITEM 2: COMUNICAÇÃO - Listas de amigos do último desligamento
+	rede, err := carregarAmigos(cfg.ArquivoAmigos)
+	if err != nil && cfg.ArquivoAmigos != "" && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("amigos salvos ignorados", "arquivo", cfg.ArquivoAmigos, "erro", err)
+	}
+	s.rede = rede
+	moderacao, err := carregarModeracao(cfg.ArquivoModeracao)
+	if err != nil && cfg.ArquivoModeracao != "" && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("moderação salva ignorada", "arquivo", cfg.ArquivoModeracao, "erro", err)
+	}
+	s.moderacao = moderacao
+	s.ctx, s.encerrarTudo = context.WithCancel(context.Background())
+	s.limites, _ = parseLimites(cfg.Limites) // Já validado em Config.Validar
+	regras.CarregadoEm = time.Now()
+	s.regras.Store(regras)
+
+	// BAREMA ITEM 8: PACOTES - Restaura o estoque do último desligamento ou gera um novo
+	estoquesIniciais, err := carregarEstado(cfg.ArquivoEstado, cfg.ShardsEstoque)
+	if err != nil {
+		if cfg.ArquivoEstado != "" && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("estado salvo ignorado; gerando estoque novo", "arquivo", cfg.ArquivoEstado, "erro", err)
+		}
+		estoquesIniciais = gerarEstoquesIniciais(cfg.ShardsEstoque)
+	}
+	for i := 0; i < cfg.ShardsEstoque; i++ {
 		s.shardedEstoque[i] = &estoqueShard{estoque: estoquesIniciais[i]}
 	}
 
 	// BAREMA ITEM 5: CONCORRÊNCIA - Inicia pool de workers para processar compras
+	s.workers.Add(s.packWorkers)
 	for i := 0; i < s.packWorkers; i++ {
-		go s.packWorker()
+		go s.packWorker(s.ctx)
 	}
 	return s
 }
@@ -131,73 +251,121 @@ func novoServidor() *Servidor {
 // BAREMA ITEM 2: COMUNICAÇÃO - Função principal do servidor
 // Inicia o servidor TCP e aceita conexões de clientes
 func main() {
-	servidor := novoServidor()
-
-	// BAREMA ITEM 2: COMUNICAÇÃO - Cria listener TCP na porta 65432
-	listener, err := net.Listen("tcp", ":65432")
+	// BAREMA ITEM 1: ARQUITETURA - Configuração: padrão < arquivo < ambiente < flags
+	cfg, err := carregarConfig(os.Args[1:])
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "configuração inválida:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	// BAREMA ITEM 6: LATÊNCIA - Logging estruturado (nível ajustável depois via API de administração)
+	if err := configurarLog(cfg.LogFormato, cfg.LogNivel); err != nil {
 		panic(err)
 	}
-	defer listener.Close()
-	fmt.Println("[SERVIDOR] ouvindo :65432 (otimização final)")
+	slog.Info("configuração carregada", "config", cfg)
 
-	// BAREMA ITEM 6: LATÊNCIA - Inicia servidor ICMP para medição de latência
-	go servidor.startICMPPingServer()
+	// BAREMA ITEM 8: PACOTES - Regras de jogo iniciais (depois recarregáveis)
+	regras, err := lerRegras(cfg.ArquivoRegras, cfg.PackSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "regras de jogo inválidas:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - TLS opcional no socket do jogo (e no gateway WebSocket)
+	var configTLS *tls.Config
+	if cfg.TLS.Habilitado() {
+		if configTLS, err = seguranca.ConfigServidor(cfg.TLS); err != nil {
+			panic(err)
+		}
+	}
 
-	// BAREMA ITEM 5: CONCORRÊNCIA - Semáforo para limitar conexões simultâneas
-	// Evita sobrecarga do sistema com muitas conexões simultâneas
-	semaphore := make(chan struct{}, 30000)
+	servidor := novoServidor(cfg, regras)
 
-	// BAREMA ITEM 2: COMUNICAÇÃO - Loop principal para aceitar conexões
-	for {
-		conn, err := listener.Accept()
+	// BAREMA ITEM 1: ARQUITETURA - SIGINT/SIGTERM disparam o desligamento gracioso
+	ctx, pararSinais := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer pararSinais()
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Transporte TCP (com TLS opcional) no endereço configurado
+	var jogo Transporte
+	if jogo, err = novoTransporteTCP(cfg.Endereco); err != nil {
+		panic(err)
+	}
+	if configTLS != nil {
+		jogo = novoTransporteTLS(jogo, configTLS)
+	}
+	transportes := []Transporte{jogo}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Gateway WebSocket para clientes de navegador
+	if cfg.EnderecoWS != "" {
+		ws, err := novoTransporteWS(cfg.EnderecoWS, cfg.OrigensWS, configTLS)
 		if err != nil {
-			continue
+			panic(err)
 		}
+		transportes = append(transportes, ws)
+	}
 
-		// BAREMA ITEM 5: CONCORRÊNCIA - Cada conexão é processada em uma goroutine separada
-		// As configurações de TCP são aplicadas dentro de handleConnection para evitar contenção
-		semaphore <- struct{}{}
-		go func() {
-			defer func() { <-semaphore }()
-			servidor.handleConnection(conn)
-		}()
+	// BAREMA ITEM 6: LATÊNCIA - Eco ICMP opcional (exige privilégio de raw socket); a
+	// latência do jogo é medida com PING/PONG no próprio protocolo
+	if cfg.ICMP {
+		go servidor.startICMPPingServer(ctx)
+	}
+
+	// BAREMA ITEM 8: PACOTES - Recarga das regras por mudança no arquivo ou SIGHUP
+	go servidor.observarRegras(ctx)
+
+	// BAREMA ITEM 1: ARQUITETURA - API de administração e métricas (/metrics)
+	if cfg.EnderecoAdmin != "" {
+		if err := servidor.iniciarAdmin(cfg.EnderecoAdmin, cfg.TokenAdmin); err != nil {
+			panic(err)
+		}
 	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Aceita conexões de todos os transportes
+	for _, t := range transportes {
+		slog.Info("servidor ouvindo", "endereco", t.Endereco())
+		servidor.servir(t)
+	}
+
+	<-ctx.Done()
+	pararSinais() // Um segundo sinal encerra o processo imediatamente
+	slog.Warn("sinal de desligamento recebido; parando de aceitar conexões")
+
+	// BAREMA ITEM 1: ARQUITETURA - Fecha os transportes, drena partidas, persiste o estado e encerra os workers
+	servidor.desligar()
 }
 
 /* ====================== Servidor ICMP para Ping ====================== */
 
-// BAREMA ITEM 6: LATÊNCIA - Servidor ICMP dedicado para medição de latência
-// Usa ICMP para medição mais precisa e nativa da latência de rede
-func (s *Servidor) startICMPPingServer() {
+// BAREMA ITEM 6: LATÊNCIA - Responde a Echo Requests ICMP (flag -icmp), para
+// ferramentas como o ping do sistema operacional
+func (s *Servidor) startICMPPingServer(ctx context.Context) {
 	// BAREMA ITEM 6: LATÊNCIA - Cria socket ICMP raw
 	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
 	if err != nil {
-		fmt.Printf("[SERVIDOR] Erro ao iniciar servidor ICMP: %v\n", err)
+		slog.Error("erro ao iniciar servidor ICMP", "erro", err)
 		return
 	}
-	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close() // Desbloqueia o ReadFrom no desligamento
+	}()
 
-	fmt.Println("[SERVIDOR] Servidor ICMP de ping iniciado")
+	slog.Info("servidor ICMP de ping iniciado")
 
 	// BAREMA ITEM 6: LATÊNCIA - Buffer para receber pacotes ICMP
 	buffer := make([]byte, 1024)
 
-	// BAREMA ITEM 5: CONCORRÊNCIA - Semáforo para limitar processamento simultâneo de ICMP
-	semaphore := make(chan struct{}, 1000)
-
 	for {
 		n, clientAddr, err := conn.ReadFrom(buffer)
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			continue
 		}
-
-		// BAREMA ITEM 5: CONCORRÊNCIA - Limita processamento simultâneo para evitar sobrecarga
-		semaphore <- struct{}{}
-		go func() {
-			defer func() { <-semaphore }()
-			s.processICMPPacket(conn, buffer[:n], clientAddr)
-		}()
+		// A resposta é só uma cópia com outro tipo: responder aqui mesmo custa
+		// menos que uma goroutine por pacote e não compartilha o buffer
+		s.processICMPPacket(conn, buffer[:n], clientAddr)
 	}
 }
 
@@ -248,52 +416,106 @@ func (s *Servidor) calculateICMPChecksum(data []byte) uint16 {
 
 // BAREMA ITEM 5: CONCORRÊNCIA - Worker que processa requisições de compra de pacotes
 // Cada worker roda em uma goroutine separada, processando requisições do canal
-func (s *Servidor) packWorker() {
-	for req := range s.packWorkerPool {
-		s.processarPacote(req)
+// Encerra quando o contexto do servidor é cancelado; o canal de pedidos nunca
+// é fechado porque leitores ainda ativos poderiam tentar enviar para ele
+func (s *Servidor) packWorker(ctx context.Context) {
+	defer s.workers.Done()
+	for {
+		select {
+		case req := <-s.packWorkerPool:
+			s.processarPacote(req)
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
 // BAREMA ITEM 8: PACOTES - Processa uma requisição de compra de pacote
 // Garante distribuição justa de cartas e evita duplicatas no estoque global
+// O pedido chega retendo o cliente; a referência é solta quando a entrega termina
 func (s *Servidor) processarPacote(req packReq) {
-	if req.cli.Conn == nil {
-		return // Cliente desconectado, ignora requisição
+	cli := req.cli
+	if req.ctx.Err() != nil {
+		cli.soltar()
+		return // Sessão encerrada: pedido cancelado sem retirar cartas do estoque
 	}
 
+	// BAREMA ITEM 8: PACOTES - Usa as regras da partida do cliente
+	regras := s.regrasPara(cli)
+
 	// Calcula total de cartas necessárias
-	totalNecessario := req.quantidade * s.packSize
+	totalNecessario := req.quantidade * regras.CartasPorPacote
 	cartas := make([]Carta, 0, totalNecessario)
 
 	// BAREMA ITEM 8: PACOTES - Gera cartas com distribuição de raridade
 	for i := 0; i < totalNecessario; i++ {
-		c, ok := s.takeOneByRarityWithDowngrade(sampleRaridade())
+		c, ok := s.takeOneByRarityWithDowngrade(regras.sampleRaridade())
 		if !ok {
 			// Se não há cartas da raridade desejada, gera uma carta comum básica
-			c = s.gerarCartaComumBasica()
+			c = s.gerarCartaComumBasica(regras)
 		}
 		cartas = append(cartas, c)
 	}
 
-	// Adiciona cartas ao inventário do cliente
-	req.cli.Inventario = append(req.cli.Inventario, cartas...)
+	// BAREMA ITEM 8: PACOTES - No lobby as cartas vão para o inventário
+	sala := cli.guardarNoLobby(cartas)
+	if sala == nil {
+		s.entregarPacote(cli, cartas, regras)
+		cli.soltar()
+		return
+	}
+	// BAREMA ITEM 7: PARTIDAS - Dentro de uma sala a entrega passa pela
+	// goroutine dela, dona das mãos, que também verifica se a partida pode começar
+	if sala.executar(func() {
+		defer cli.soltar()
+		sala.concluirCompra(cli, cartas, regras)
+	}) {
+		return
+	}
+	s.entregarForaDaSala(cli, cartas, regras) // A sala terminou enquanto o pacote era montado
+	cli.soltar()
+}
 
+// entregarPacote avisa o cliente das cartas que entraram na sua mão
+func (s *Servidor) entregarPacote(cli *Cliente, cartas []Carta, regras *RegrasJogo) bool {
 	// BAREMA ITEM 3: API REMOTA - Envia resultado da compra para o cliente
 	msg := protocolo.Mensagem{
 		Comando: "PACOTE_RESULTADO",
 		Dados:   mustJSON(protocolo.ComprarPacoteResp{Cartas: cartas}),
 	}
-	if s.enviar(req.cli, msg) {
-		// Envia mensagem de ajuda após a compra
-		s.enviar(req.cli, protocolo.Mensagem{
-			Comando: "SISTEMA",
-			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você recebeu novas cartas! Use /cartas para ver sua mão."}),
-		})
+	if !s.enviar(cli, msg) {
+		return false
+	}
+	// Envia mensagem de ajuda após a compra
+	s.enviar(cli, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: regras.mensagem("cartasRecebidas")}),
+	})
+	return true
+}
 
-		// BAREMA ITEM 7: PARTIDAS - Verifica se pode iniciar a partida
-		if req.cli.Sala != nil {
-			req.cli.Sala.marcarCompraEIniciarSePossivel(req.cli)
-		}
+// entregarForaDaSala trata o pacote de quem deixou a sala enquanto ele era
+// montado: de volta ao lobby, as cartas vão para o inventário; se o jogador já
+// está em outra sala, elas voltam ao estoque
+func (s *Servidor) entregarForaDaSala(cli *Cliente, cartas []Carta, regras *RegrasJogo) {
+	if cli.guardarNoLobby(cartas) == nil {
+		s.entregarPacote(cli, cartas, regras)
+		return
+	}
+	s.devolverAoEstoque(cartas)
+	s.enviar(cli, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você mudou de sala antes da entrega do pacote; a compra foi cancelada."}),
+	})
+}
+
+// BAREMA ITEM 8: PACOTES - Devolve cartas ao estoque, cada uma em um shard aleatório
+func (s *Servidor) devolverAoEstoque(cartas []Carta) {
+	for _, c := range cartas {
+		shard := s.shardedEstoque[rand.Intn(len(s.shardedEstoque))]
+		shard.mutex.Lock()
+		shard.estoque[c.Raridade] = append(shard.estoque[c.Raridade], c)
+		shard.mutex.Unlock()
 	}
 }
 
@@ -304,7 +526,7 @@ func (s *Servidor) takeOneByRarityWithDowngrade(r string) (Carta, bool) {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	// BAREMA ITEM 5: CONCORRÊNCIA - Seleciona shard aleatoriamente para distribuir carga
-	shardIndex := rng.Intn(numEstoqueShards)
+	shardIndex := rng.Intn(len(s.shardedEstoque))
 	shard := s.shardedEstoque[shardIndex]
 	shard.mutex.Lock()
 	defer shard.mutex.Unlock()
@@ -340,24 +562,9 @@ func (s *Servidor) takeOneByRarityWithDowngrade(r string) (Carta, bool) {
 }
 
 // BAREMA ITEM 8: PACOTES - Gera carta comum única quando estoque acaba
-func (s *Servidor) gerarCartaComumBasica() Carta {
-	// BAREMA ITEM 8: PACOTES - Lista de nomes variados para cartas comuns
-	nomes := []string{
-		"Guerreiro", "Arqueiro", "Mago", "Cavaleiro", "Ladrão", "Clérigo",
-		"Bárbaro", "Paladino", "Ranger", "Bruxo", "Druida", "Monge",
-		"Assassino", "Bardo", "Necromante", "Elementalista", "Inquisidor",
-		"Gladiador", "Mercenário", "Escudeiro", "Aprendiz", "Novato",
-		"Veterano", "Herói", "Lenda", "Mestre", "Sábio", "Ancião",
-		"Espadachim", "Arqueiro Élfico", "Mago do Caos", "Sacerdote", "Berserker",
-		"Samurai", "Ninja", "Viking", "Cruzado", "Templário", "Caçador",
-		"Explorador", "Navegador", "Alquimista", "Encantador", "Ilusionista",
-		"Summoner", "Conjurador", "Evocador", "Invocador", "Chamador", "Convocador",
-		"Dragão", "Fênix", "Titan", "Sereia", "Lobo", "Águia", "Leão", "Tigre",
-		"Anjo", "Demônio", "Golem", "Elemental", "Espírito", "Fantasma", "Zumbi",
-		"Skeleton", "Orc", "Elfo", "Anão", "Hobbit", "Gigante", "Troll", "Ogro",
-		"Knight", "Wizard", "Rogue", "Priest", "Warrior", "Mage", "Hunter", "Shaman",
-		"Monk", "Paladin", "Druid", "Warlock", "Death Knight", "Demon Hunter", "Evoker",
-	}
+// Os nomes vêm do catálogo de cartas comuns das regras de jogo
+func (s *Servidor) gerarCartaComumBasica(regras *RegrasJogo) Carta {
+	nomes := regras.CatalogoComum
 
 	// BAREMA ITEM 8: PACOTES - Lista de naipes variados
 	naipes := []string{"♠", "♥", "♦", "♣"}
@@ -383,84 +590,186 @@ func (s *Servidor) gerarCartaComumBasica() Carta {
 
 /* ====================== Conexão / IO com Pools ====================== */
 
-// BAREMA ITEM 2: COMUNICAÇÃO - Gerencia uma conexão TCP com um cliente
+// BAREMA ITEM 2: COMUNICAÇÃO - Gerencia a conexão de um cliente
 // Configura a conexão, inicializa estruturas e coordena leitura/escrita
-func (s *Servidor) handleConnection(conn net.Conn) {
-	// BAREMA ITEM 2: COMUNICAÇÃO - Configurações de TCP para melhor performance
-	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		tcpConn.SetKeepAlive(true)                   // Mantém conexão ativa
-		tcpConn.SetKeepAlivePeriod(30 * time.Second) // Verifica conexão a cada 30s
-		tcpConn.SetNoDelay(true)                     // Desabilita algoritmo de Nagle
+func (s *Servidor) handleConnection(t Transporte, conn net.Conn) {
+	// BAREMA ITEM 5: CONCORRÊNCIA - Limite de conexões por IP antes de qualquer trabalho
+	ip := ipDe(conn.RemoteAddr())
+	if ok, motivo := s.limitesIP.admitir(ip); !ok {
+		s.metricas.recusasPorIP.Add(1)
+		registrarRecusaIP(ip, motivo)
+		conn.Close()
+		return
+	}
+	defer s.limitesIP.liberar(ip)
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Ajustes do transporte (opções de TCP, handshake TLS)
+	certificado, err := t.Preparar(conn)
+	if err != nil {
+		conn.Close()
+		return
 	}
 
 	// BAREMA ITEM 5: CONCORRÊNCIA - Reutiliza objeto Cliente do pool para otimização
 	cliente := clientePool.Get().(*Cliente)
+	cliente.iniciarSessao(s.ctx)
+	// Desconexão, expulsão ou desligamento cancelam a sessão; fechar a conexão
+	// desbloqueia o clienteReader, que então faz a limpeza
+	context.AfterFunc(cliente.ctx, func() { conn.Close() })
 	cliente.Conn = conn
+	cliente.Mailbox.capacidade = s.cfg.MaxFilaSaida
 	cliente.Encoder = json.NewEncoder(conn)
-	cliente.Decoder = json.NewDecoder(conn)
-	cliente.Nome = conn.RemoteAddr().String()
-	cliente.UltimoPing = time.Now() // BAREMA ITEM 6: LATÊNCIA - Inicializa timestamp de ping
+	cliente.leitor = &leitorLimitado{r: conn, max: s.cfg.MaxMensagem}
+	cliente.Decoder = json.NewDecoder(cliente.leitor)
+	cliente.definirNome(conn.RemoteAddr().String())
+	cliente.Certificado = certificado
+	if certificado != "" {
+		// BAREMA ITEM 2: COMUNICAÇÃO - Clientes autenticados por TLS mútuo usam o nome do certificado
+		cliente.definirNome(certificado)
+	}
+	// BAREMA ITEM 6: LATÊNCIA - A pulsação e a ociosidade contam a partir da conexão
+	cliente.UltimoPong.Store(time.Now().UnixNano())
+	cliente.UltimaAcao.Store(time.Now().UnixNano())
 
 	s.adicionarCliente(cliente)
+	s.metricas.conexoesAtivas.Add(1)
+	defer s.metricas.conexoesAtivas.Add(-1)
 
 	// BAREMA ITEM 5: CONCORRÊNCIA - Inicia goroutines para escrita e ping em paralelo
-	go s.clienteWriter(cliente) // Goroutine para envio de mensagens
-	go s.pingManager(cliente)   // BAREMA ITEM 6: LATÊNCIA - Goroutine para gerenciar pings
-	s.clienteReader(cliente)    // Loop principal de leitura (bloqueante)
+	var tarefas sync.WaitGroup
+	tarefas.Add(2)
+	go func() {
+		defer tarefas.Done()
+		s.clienteWriter(cliente) // Goroutine para envio de mensagens
+	}()
+	go func() {
+		defer tarefas.Done()
+		s.pingManager(cliente) // BAREMA ITEM 6: LATÊNCIA - Goroutine para gerenciar pings
+	}()
+	// BAREMA ITEM 2: COMUNICAÇÃO - Um IP banido (ou o nome do certificado) é recusado antes do LOGIN
+	if !s.recusarBanido(cliente, certificado) {
+		s.clienteReader(cliente) // Loop principal de leitura (bloqueante)
+	}
 
-	// BAREMA ITEM 5: CONCORRÊNCIA - Limpeza e devolução do objeto para o pool
-	conn.Close() // Garante que a conexão seja fechada
+	// BAREMA ITEM 5: CONCORRÊNCIA - Encerra a sessão: cancela o contexto (writer,
+	// ping e pedidos de pacote pendentes), fecha a conexão e espera as goroutines
+	cliente.cancelar()
+	conn.Close()
 	s.removerCliente(cliente)
+	tarefas.Wait()
 
-	// Limpa o estado do cliente para reutilização
-	cliente.Inventario = cliente.Inventario[:0] // Limpa o slice mantendo capacidade
-	cliente.Sala = nil
-	cliente.Conn = nil
-	cliente.Nome = ""
-	cliente.PingMs = 0
-	cliente.UltimoPing = time.Time{}
-
-	clientePool.Put(cliente) // Devolve objeto para o pool
+	// O objeto só volta ao pool quando a última referência for solta
+	cliente.soltar()
 }
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Escreve a fila de saída, sempre a maior prioridade primeiro
 func (s *Servidor) clienteWriter(c *Cliente) {
-	for msg := range c.Mailbox {
-		if c.Conn == nil {
-			return
+	for c.ativa() {
+		msg, ok := c.Mailbox.proxima()
+		if !ok {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-c.Mailbox.aviso:
+			}
+			continue
 		}
-		c.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		c.Conn.SetWriteDeadline(time.Now().Add(s.cfg.TimeoutEscrita))
 		if err := c.Encoder.Encode(msg); err != nil {
-			fmt.Printf("[SERVIDOR] Erro de escrita para %s: %v\n", c.Nome, err)
+			if c.ativa() {
+				c.log().Warn("erro de escrita", "erro", err)
+			}
+			c.cancelar() // Encerra a sessão; a conexão é fechada e o leitor faz a limpeza
 			return
 		}
 	}
 }
 func (s *Servidor) clienteReader(cliente *Cliente) {
 	for {
-		if cliente.Conn == nil {
-			return
-		}
-		cliente.Conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		// Última barreira: a pulsação (pulsacao.go) normalmente encerra antes
+		cliente.Conn.SetReadDeadline(time.Now().Add(s.cfg.TimeoutLeitura))
 		var msg protocolo.Mensagem
 		if err := cliente.Decoder.Decode(&msg); err != nil {
+			if s.tratarErroLeitura(cliente, err) {
+				return
+			}
+			continue
+		}
+		cliente.leitor.reiniciar(cliente.Decoder)
+
+		// BAREMA ITEM 4: ENCAPSULAMENTO - Rejeita comandos desconhecidos e campos fora dos limites
+		if codigo, descricao := s.validarMensagem(&msg); codigo != "" {
+			if s.registrarViolacao(cliente, codigo, descricao) {
+				return
+			}
+			continue
+		}
+		// BAREMA ITEM 5: CONCORRÊNCIA - Limite de taxa por classe de comando
+		switch s.aplicarLimite(cliente, msg.Comando) {
+		case limiteDescartar:
+			continue
+		case limiteDesconectar:
 			return
 		}
+
+		inicio := time.Now()
+		if msg.Comando != "PING" && msg.Comando != "PONG" {
+			cliente.UltimaAcao.Store(inicio.UnixNano())
+		}
 		switch msg.Comando {
 		case "LOGIN":
 			var dadosLogin protocolo.DadosLogin
-			if json.Unmarshal(msg.Dados, &dadosLogin) == nil && dadosLogin.Nome != "" {
-				cliente.Nome = dadosLogin.Nome
-				fmt.Printf("[SERVIDOR] %s fez login como '%s'\n", cliente.Conn.RemoteAddr().String(), cliente.Nome)
+			if json.Unmarshal(msg.Dados, &dadosLogin) != nil || dadosLogin.Nome == "" {
+				break
+			}
+			nome := dadosLogin.Nome
+			if cliente.Certificado != "" && nome != cliente.Certificado {
+				// A identidade do certificado prevalece sobre o nome informado no LOGIN
+				cliente.log().Warn("login com nome diferente do certificado; mantendo identidade do certificado", "nomeSolicitado", nome)
+				nome = cliente.Certificado
+			}
+			// BAREMA ITEM 2: COMUNICAÇÃO - Um nome banido encerra a sessão; os
+			// demais são reservados para ela e, se verificados, entram na rede de amigos
+			if s.recusarBanido(cliente, nome) {
+				return
 			}
+			// BAREMA ITEM 2: COMUNICAÇÃO - Quem está silenciado não troca de nome
+			if anterior := s.nomeDaSessao(cliente); anterior != "" && anterior != nome {
+				if p, silenciado := s.punicaoDe(cliente, anterior, s.moderacao.silencios()); silenciado {
+					s.avisar(cliente, "[SISTEMA] Você está silenciado "+descreverPunicao(p)+" e não pode trocar de nome até lá.")
+					break
+				}
+			}
+			if err := s.conectarNaRede(cliente, nome, dadosLogin.Senha, cliente.Certificado != ""); err != nil {
+				cliente.log().Info("login recusado", "nomeSolicitado", nome, "motivo", err)
+				if errors.Is(err, errSenhaIncorreta) {
+					// Cada senha errada custa a conexão, o que encarece adivinhá-la
+					s.despedir(cliente, protocolo.Mensagem{
+						Comando: "SISTEMA",
+						Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Login recusado: " + err.Error() + "."}),
+					})
+					return
+				}
+				s.avisar(cliente, "[SISTEMA] Login recusado: "+err.Error()+".")
+				break
+			}
+			cliente.definirNome(nome)
+			cliente.log().Info("login", "endereco", cliente.Conn.RemoteAddr().String())
 		case "ENTRAR_NA_FILA":
 			s.entrarFila(cliente)
 		case "COMPRAR_PACOTE":
-			fmt.Printf("[SERVIDOR] %s solicitou compra de pacote\n", cliente.Nome)
+			cliente.log().Debug("compra de pacote solicitada")
+
+			if s.encerrando.Load() {
+				s.enviar(cliente, protocolo.Mensagem{
+					Comando: "SISTEMA",
+					Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Servidor em manutenção: compras suspensas."}),
+				})
+				break
+			}
 
-			if cliente.Sala != nil {
-				cliente.Sala.mutex.Lock()
-				pronto, ok := cliente.Sala.Prontos[cliente.Nome]
-				cliente.Sala.mutex.Unlock()
-				if ok && pronto {
+			if sala := cliente.salaAtual(); sala != nil {
+				if sala.jaComprou(cliente) {
 					s.enviar(cliente, protocolo.Mensagem{
 						Comando: "SISTEMA",
 						Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você já comprou cartas para esta partida."}),
@@ -469,42 +778,92 @@ func (s *Servidor) clienteReader(cliente *Cliente) {
 				}
 			}
 
+			// O pedido retém o cliente até ser processado ou descartado
+			cliente.reter()
 			select {
-			case s.packWorkerPool <- packReq{cli: cliente, quantidade: 1}:
-				fmt.Printf("[SERVIDOR] %s - pedido de pacote enviado para processamento\n", cliente.Nome)
+			case s.packWorkerPool <- packReq{ctx: cliente.ctx, cli: cliente, quantidade: 1}:
+				if amostraPedidoPacote.permitir() {
+					cliente.log().Debug("pedido de pacote enviado para processamento", "pendentes", len(s.packWorkerPool))
+				}
 			default:
+				cliente.soltar()
+				s.metricas.pacotesRejeitados.Add(1)
 				s.enviar(cliente, protocolo.Mensagem{
 					Comando: "ERRO",
 					Dados:   mustJSON(protocolo.DadosErro{Mensagem: "Servidor sobrecarregado. Tente novamente."}),
 				})
 			}
 		case "JOGAR_CARTA":
-			if cliente.Sala != nil && cliente.Sala.Estado == "JOGANDO" {
+			if sala := cliente.salaAtual(); sala != nil {
 				var dadosJogar protocolo.DadosJogarCarta
 				if json.Unmarshal(msg.Dados, &dadosJogar) == nil {
-					cliente.Sala.processarJogada(cliente, dadosJogar.CartaID)
+					sala.executar(func() { sala.processarJogada(cliente, dadosJogar.CartaID) })
 				}
 			}
 		case "ENVIAR_CHAT":
-			if cliente.Sala != nil {
-				var dadosChat protocolo.DadosEnviarChat
-				if json.Unmarshal(msg.Dados, &dadosChat) == nil {
-					msgParaBroadcast := protocolo.Mensagem{
-						Comando: "RECEBER_CHAT",
-						Dados: mustJSON(protocolo.DadosReceberChat{
-							NomeJogador: cliente.Nome,
-							Texto:       dadosChat.Texto,
-						}),
-					}
-					cliente.Sala.broadcast(nil, msgParaBroadcast)
+			var dadosChat protocolo.DadosEnviarChat
+			if json.Unmarshal(msg.Dados, &dadosChat) != nil {
+				break
+			}
+			if sala := cliente.salaAtual(); sala != nil && dadosChat.Canal == "" {
+				s.chatSala(cliente, sala, dadosChat.Texto)
+				break
+			}
+			// BAREMA ITEM 2: COMUNICAÇÃO - Fora da sala (ou com canal informado) o chat vai para o lobby
+			s.chatCanal(cliente, dadosChat.Canal, dadosChat.Texto)
+		case "ENTRAR_CANAL", "SAIR_CANAL":
+			var dadosCanal protocolo.DadosCanal
+			if json.Unmarshal(msg.Dados, &dadosCanal) == nil {
+				if msg.Comando == "ENTRAR_CANAL" {
+					s.entrarCanal(cliente, dadosCanal.Canal)
+				} else {
+					s.sairCanal(cliente, dadosCanal.Canal)
 				}
 			}
+		case "ADICIONAR_AMIGO", "REMOVER_AMIGO", "DESAFIAR", "ACEITAR_DESAFIO", "RECUSAR_DESAFIO", "BLOQUEAR", "DESBLOQUEAR":
+			var dadosAmigo protocolo.DadosAmigo
+			if json.Unmarshal(msg.Dados, &dadosAmigo) != nil {
+				break
+			}
+			switch msg.Comando {
+			case "ADICIONAR_AMIGO":
+				s.adicionarAmigo(cliente, dadosAmigo.Nome)
+			case "REMOVER_AMIGO":
+				s.removerAmigo(cliente, dadosAmigo.Nome)
+			case "DESAFIAR":
+				s.desafiar(cliente, dadosAmigo.Nome)
+			case "ACEITAR_DESAFIO":
+				s.aceitarDesafio(cliente, dadosAmigo.Nome)
+			case "RECUSAR_DESAFIO":
+				s.recusarDesafio(cliente, dadosAmigo.Nome)
+			case "BLOQUEAR":
+				s.bloquear(cliente, dadosAmigo.Nome)
+			case "DESBLOQUEAR":
+				s.desbloquear(cliente, dadosAmigo.Nome)
+			}
+		case "LISTAR_AMIGOS":
+			s.listarAmigos(cliente)
+		case "MENSAGEM_PRIVADA":
+			var dadosPrivada protocolo.DadosEnviarPrivada
+			if json.Unmarshal(msg.Dados, &dadosPrivada) == nil {
+				s.mensagemPrivada(cliente, dadosPrivada.Para, dadosPrivada.Texto)
+			}
+		case "DENUNCIAR":
+			var dadosDenuncia protocolo.DadosDenuncia
+			if json.Unmarshal(msg.Dados, &dadosDenuncia) == nil {
+				s.denunciar(cliente, dadosDenuncia.Nome, dadosDenuncia.Motivo)
+			}
 		case "PONG":
 			var dadosPong protocolo.DadosPong
 			if json.Unmarshal(msg.Dados, &dadosPong) == nil {
-				cliente.PingMs = time.Now().UnixMilli() - dadosPong.Timestamp
-				cliente.UltimoPing = time.Now()
-				fmt.Printf("[SERVIDOR] Latência de %s: %dms\n", cliente.Nome, cliente.PingMs)
+				// BAREMA ITEM 6: LATÊNCIA - O timestamp é o do PING enviado pelo pingManager
+				rtt := time.Duration(time.Now().UnixMilli()-dadosPong.Timestamp) * time.Millisecond
+				cliente.Latencia.Registrar(rtt)
+				s.metricas.rtt.observar(rtt)
+				s.pongRecebido(cliente)
+				if amostraPong.permitir() {
+					cliente.log().Debug("latência medida", "rtt", rtt, "latencia", cliente.Latencia.Resumo())
+				}
 			}
 		case "VER_CARTAS":
 			s.mostrarCartasDetalhadas(cliente)
@@ -521,57 +880,51 @@ func (s *Servidor) clienteReader(cliente *Cliente) {
 				})
 			}
 		}
+		s.metricas.observarComando(msg.Comando, time.Since(inicio))
 	}
 }
 
 func (s *Servidor) pingManager(c *Cliente) {
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(s.cfg.IntervaloPing)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if c.Conn == nil {
-			return // Encerra se a conexão for nula
+	var enviado int64 // BAREMA ITEM 6: LATÊNCIA - Envio do último PING (UnixNano)
+	perdidos := 0     // PINGs seguidos sem PONG
+	for {
+		select {
+		case <-c.ctx.Done():
+			return // Sessão encerrada
+		case <-ticker.C:
+		}
+		// Um PONG atrasado por mais de um intervalo também conta como perdido
+		if enviado != 0 && c.UltimoPong.Load() < enviado {
+			perdidos++
+		} else {
+			perdidos = 0
+		}
+		if !s.verificarPulsacao(c, perdidos) {
+			return
 		}
-		// Se não receber um PONG em 30 segundos, a conexão será fechada pelo readDeadline
+
+		agora := time.Now()
 		if !s.enviar(c, protocolo.Mensagem{
 			Comando: "PING",
-			Dados:   mustJSON(protocolo.DadosPing{Timestamp: time.Now().UnixMilli()}),
+			Dados:   mustJSON(protocolo.DadosPing{Timestamp: agora.UnixMilli()}),
 		}) {
 			return // Encerra se não conseguir enviar
 		}
-	}
-}
-
-func (s *Servidor) handleSairDaSala(cliente *Cliente) {
-	if cliente.Sala == nil {
-		return // Não está em nenhuma sala
-	}
-
-	sala := cliente.Sala
-	sala.mutex.Lock()
-
-	// Notifica o oponente, se houver
-	var oponente *Cliente
-	if len(sala.Jogadores) == 2 {
-		if sala.Jogadores[0] == cliente {
-			oponente = sala.Jogadores[1]
-		} else {
-			oponente = sala.Jogadores[0]
+		enviado = agora.UnixNano()
+		c.pendentes.Add(1)
+
+		// BAREMA ITEM 6: LATÊNCIA - Em partida, informa a latência de todos os jogadores da sala
+		if sala := c.salaAtual(); sala != nil {
+			if latencias := sala.latencias(); len(latencias) > 0 {
+				s.enviar(c, protocolo.Mensagem{
+					Comando: "LATENCIA",
+					Dados:   mustJSON(protocolo.DadosLatencia{Jogadores: latencias}),
+				})
+			}
 		}
-		s.enviar(oponente, protocolo.Mensagem{
-			Comando: "SISTEMA",
-			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Seu oponente saiu da partida. Colocando você de volta na fila..."}),
-		})
-	}
-
-	// Remove o jogador da sala e limpa a referência
-	sala.removerJogador(cliente)
-	cliente.Sala = nil
-	sala.mutex.Unlock()
-
-	// Se havia um oponente, ele volta para a fila de espera
-	if oponente != nil {
-		s.entrarFila(oponente)
 	}
 }
 
@@ -580,352 +933,155 @@ func (s *Servidor) handleSairDaSala(cliente *Cliente) {
 // BAREMA ITEM 7: PARTIDAS - Sistema de matchmaking para parear jogadores
 // Garante que cada jogador seja pareado com apenas um oponente por vez
 func (s *Servidor) entrarFila(cliente *Cliente) {
+	// BAREMA ITEM 7: PARTIDAS - Em drenagem nenhuma partida nova é formada
+	if s.drenando.Load() {
+		s.avisarManutencao(cliente)
+		return
+	}
+	// Um jogador pertence a no máximo uma sala: quem volta à fila deixa a anterior
+	if sala := cliente.salaAtual(); sala != nil {
+		aviso := fmt.Sprintf("[SISTEMA] %s saiu da sala", cliente.nome())
+		sala.consultar(func() { sala.removerJogador(cliente, aviso) })
+	}
+
 	s.filaMutex.Lock()
 	defer s.filaMutex.Unlock()
 
+	// Uma sessão encerrada não entra na fila: a limpeza dela já passou por aqui
+	if !cliente.ativa() {
+		return
+	}
+	// Pedir a fila de novo enquanto aguarda não pode parear o jogador consigo mesmo
+	if s.filaDeEspera == cliente {
+		return
+	}
+
 	// BAREMA ITEM 7: PARTIDAS - Verifica se já existe um jogador na fila de espera
 	if s.filaDeEspera != nil {
 		oponente := s.filaDeEspera
 		s.filaDeEspera = nil // Limpa a fila para evitar múltiplos pareamentos
 
 		// BAREMA ITEM 7: PARTIDAS - Cria sala com os dois jogadores encontrados
-		fmt.Printf("[SERVIDOR] Jogador %s encontrado para %s. Criando sala...\n", cliente.Nome, oponente.Nome)
-		s.criarSala(oponente, cliente)
+		cliente.log().Info("oponente encontrado; criando sala", "oponente", oponente.nome())
+		s.criarSala(oponente, cliente, false)
+		oponente.soltar() // A referência da fila passa a ser a da sala
 	} else {
 		// BAREMA ITEM 7: PARTIDAS - Nenhum jogador esperando, este cliente aguarda
+		cliente.reter() // A fila retém quem espera até o pareamento ou a saída
 		s.filaDeEspera = cliente
-		fmt.Printf("[SERVIDOR] Jogador %s entrou na fila e está aguardando um oponente.\n", cliente.Nome)
+		s.presencaMudou(cliente, protocolo.PresencaFila)
+		cliente.log().Debug("entrou na fila e aguarda um oponente")
 		s.enviar(cliente, protocolo.Mensagem{
 			Comando: "SISTEMA",
-			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Aguardando um oponente..."}),
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: s.regras.Load().mensagem("aguardandoOponente")}),
 		})
 	}
 }
 
+// BAREMA ITEM 2: COMUNICAÇÃO - Chat da sala, já moderado: vai aos outros
+// jogadores (não volta ao autor), salvo a quem bloqueou o autor
+func (s *Servidor) chatSala(c *Cliente, sala *Sala, texto string) {
+	texto, ok := s.moderarChat(c, texto)
+	if !ok {
+		return
+	}
+	autor := c.nome()
+	linha := linhaChat{De: autor, Onde: "sala", Texto: texto, Em: time.Now()}
+	s.lembrarChat(c, linha)
+	msg := protocolo.Mensagem{
+		Comando: "RECEBER_CHAT",
+		Dados:   mustJSON(protocolo.DadosReceberChat{NomeJogador: autor, Texto: texto}),
+	}
+	sala.executar(func() {
+		for _, j := range sala.Jogadores {
+			if j != c && !j.bloqueou(autor) {
+				s.lembrarChat(j, linha)
+				s.enviar(j, msg)
+			}
+		}
+	})
+}
+
 // BAREMA ITEM 7: PARTIDAS - Cria uma nova sala de jogo com dois jogadores
-// Inicializa o estado da partida e notifica os jogadores
-func (s *Servidor) criarSala(j1, j2 *Cliente) {
+// A goroutine da sala é iniciada aqui e notifica os jogadores. Chamada sob
+// filaMutex; privada indica uma sala criada por desafio entre amigos
+func (s *Servidor) criarSala(j1, j2 *Cliente, privada bool) {
 	salaID := novoID() // Gera ID único para a sala
 
 	// BAREMA ITEM 7: PARTIDAS - Inicializa sala com estado "AGUARDANDO_COMPRA"
-	novaSala := &Sala{
-		ID:              salaID,
-		Jogadores:       []*Cliente{j1, j2},  // Sempre exatamente 2 jogadores
-		Estado:          "AGUARDANDO_COMPRA", // Estado inicial: aguarda compra de cartas
-		CartasNaMesa:    make(map[string]Carta),
-		PontosRodada:    make(map[string]int),
-		PontosPartida:   make(map[string]int),
-		NumeroRodada:    1,
-		JogadasNaRodada: 0,
-		Prontos:         make(map[string]bool), // Rastreia quem já comprou cartas
-		srv:             s,
-	}
+	novaSala := s.novaSala(salaID, j1, j2)
+	novaSala.Privada = privada
 
 	// BAREMA ITEM 5: CONCORRÊNCIA - Armazena sala no map thread-safe
 	s.salas.Store(salaID, novaSala)
-	j1.Sala, j2.Sala = novaSala, novaSala // Associa jogadores à sala
-
-	// BAREMA ITEM 3: API REMOTA - Notifica os jogadores sobre a partida encontrada
-	d1 := protocolo.DadosPartidaEncontrada{SalaID: salaID, OponenteNome: j2.Nome}
-	d2 := protocolo.DadosPartidaEncontrada{SalaID: salaID, OponenteNome: j1.Nome}
-	s.enviar(j1, protocolo.Mensagem{Comando: "PARTIDA_ENCONTRADA", Dados: mustJSON(d1)})
-	s.enviar(j2, protocolo.Mensagem{Comando: "PARTIDA_ENCONTRADA", Dados: mustJSON(d2)})
-
-	// BAREMA ITEM 8: PACOTES - Informa que devem comprar pacotes para iniciar
-	novaSala.broadcast(nil, protocolo.Mensagem{
-		Comando: "SISTEMA",
-		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Partida encontrada! Usem /comprar para adquirir um pacote de cartas e iniciar o jogo."}),
-	})
+	j1.reter() // A sala retém os jogadores enquanto são membros (ver removerJogador)
+	j2.reter()
+	// Associa jogadores à sala; a goroutine dela ainda não começou, então as
+	// mãos podem ser preenchidas aqui com as cartas trazidas do lobby
+	novaSala.Maos[j1] = j1.entrarNaSala(novaSala)
+	novaSala.Maos[j2] = j2.entrarNaSala(novaSala)
+	s.presencaMudou(j1, protocolo.PresencaPartida)
+	s.presencaMudou(j2, protocolo.PresencaPartida)
+
+	go novaSala.rodar()
+	novaSala.executar(novaSala.anunciar)
 }
 
-/* ====================== Lógica do Jogo (em memória) ====================== */
-func (sala *Sala) marcarCompraEIniciarSePossivel(cli *Cliente) {
-	sala.mutex.Lock()
-
-	if sala.Estado == "FINALIZADO" {
-		sala.reiniciarSala()
-	}
-	if sala.Prontos == nil {
-		sala.Prontos = make(map[string]bool)
-	}
-
-	// Marca como "comprou"
-	sala.Prontos[cli.Nome] = true
-
-	// Checa se ambos estão prontos
-	if len(sala.Jogadores) < 2 {
-		sala.mutex.Unlock()
-		return
+func (s *Servidor) handleSairDaSala(cliente *Cliente) {
+	sala := cliente.salaAtual()
+	if sala == nil {
+		return // Não está em nenhuma sala
 	}
-	ready1 := sala.Prontos[sala.Jogadores[0].Nome]
-	ready2 := sala.Prontos[sala.Jogadores[1].Nome]
 
-	sala.mutex.Unlock()
-
-	// Broadcast de pronto
-	sala.broadcast(nil, protocolo.Mensagem{
-		Comando: "SISTEMA",
-		Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] - Jogador \"%s\" está pronto para iniciar", cli.Nome)}),
-	})
+	// Remove o jogador e o oponente da sala; a sala encerra em seguida
+	var oponente *Cliente
+	sala.consultar(func() { oponente = sala.sair(cliente) })
 
-	if ready1 && ready2 {
-		sala.iniciarPartida()
+	// Se havia um oponente, ele volta para a fila de espera (numa sala
+	// privada, só para o lobby)
+	if oponente != nil {
+		if !sala.Privada {
+			s.entrarFila(oponente)
+		}
+		oponente.soltar()
 	}
 }
 
-// Outras funções de jogo foram omitidas para focar na otimização de conexão/compra.
-
 /* ====================== Utilidades Otimizadas ====================== */
-func (s *Servidor) adicionarCliente(c *Cliente) { s.clientes.Store(c.Conn, c) }
+func (s *Servidor) adicionarCliente(c *Cliente) { s.clientes.Store(c.Sessao, c) }
 func (s *Servidor) removerCliente(c *Cliente) {
-	if c.Sala != nil {
-		c.Sala.removerJogador(c)
-	}
-	s.clientes.Delete(c.Conn)
-	// Limpa da fila de espera se o cliente desconectar enquanto espera
+	s.clientes.Delete(c.Sessao)
+	s.sairDosCanais(c)
+	s.desconectarDaRede(c) // Antes da sala: os amigos recebem só "offline"
+	// Limpa da fila de espera se o cliente desconectar enquanto espera.
+	// Vem antes da sala: criarSala roda sob filaMutex, então depois deste
+	// ponto o cliente não pode mais ser colocado em uma sala nova
 	s.filaMutex.Lock()
-	if s.filaDeEspera == c {
-		s.filaDeEspera = nil
-	}
+	s.tirarDaFila(c)
 	s.filaMutex.Unlock()
-	// Não precisamos mais fechar a mailbox, pois o objeto cliente será reutilizado.
-}
-func (sala *Sala) removerJogador(cliente *Cliente) {
-	sala.mutex.Lock()
-	defer sala.mutex.Unlock()
-
-	// Lógica de remoção
-	var outrosJogadores []*Cliente
-	for _, j := range sala.Jogadores {
-		if j != cliente {
-			outrosJogadores = append(outrosJogadores, j)
-		}
-	}
-	sala.Jogadores = outrosJogadores
-
-	// Notifica o oponente se houver
-	if len(sala.Jogadores) > 0 {
-		sala.broadcast(nil, protocolo.Mensagem{
-			Comando: "SISTEMA",
-			Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] %s desconectou da partida", cliente.Nome)}),
-		})
-	}
-	fmt.Printf("[SALA %s] Jogador %s removido\n", sala.ID, cliente.Nome)
-}
-func (s *Servidor) enviar(cli *Cliente, msg protocolo.Mensagem) bool {
-	if cli.Conn == nil {
-		return false
-	}
-	select {
-	case cli.Mailbox <- msg:
-		return true
-	case <-time.After(200 * time.Millisecond): // Timeout mais curto
-		return false
+	if sala := c.salaAtual(); sala != nil {
+		aviso := fmt.Sprintf("[SISTEMA] %s desconectou da partida", c.nome())
+		sala.consultar(func() { sala.removerJogador(c, aviso) })
 	}
+	// A mailbox não é fechada: o objeto será reutilizado e é esvaziado em reciclar.
 }
 
-func (sala *Sala) broadcast(_ *Cliente, msg protocolo.Mensagem) {
-	sala.mutex.Lock()
-	defer sala.mutex.Unlock()
-	for _, j := range sala.Jogadores {
-		select {
-		case j.Mailbox <- msg:
-		default:
-			fmt.Printf("[SALA %s] Mailbox cheia de %s; descartando mensagem\n", sala.ID, j.Nome)
-		}
-	}
-}
-
-func (sala *Sala) enviarAtualizacaoJogo(mensagem, vencedorJogada, vencedorRodada string) {
-	sala.mutex.Lock()
-	defer sala.mutex.Unlock()
-
-	// Envia atualização personalizada para cada jogador
-	for _, jogador := range sala.Jogadores {
-		dados := sala.criarAtualizacaoJogoPersonalizada(mensagem, vencedorJogada, vencedorRodada, jogador)
-		sala.srv.enviar(jogador, protocolo.Mensagem{
-			Comando: "ATUALIZACAO_JOGO",
-			Dados:   mustJSON(dados),
-		})
-	}
-}
-
-// Cria atualização personalizada escondendo poder das cartas do oponente
-func (sala *Sala) criarAtualizacaoJogoPersonalizada(mensagem, vencedorJogada, vencedorRodada string, jogadorAtual *Cliente) protocolo.DadosAtualizacaoJogo {
-	contagem := make(map[string]int)
-	ultima := make(map[string]Carta)
-
-	for _, p := range sala.Jogadores {
-		contagem[p.Nome] = len(p.Inventario)
-		if c, ok := sala.CartasNaMesa[p.Nome]; ok {
-			// Mostra a carta jogada por ambos os jogadores, sem esconder o poder
-			ultima[p.Nome] = c
-		}
-	}
-
-	return protocolo.DadosAtualizacaoJogo{
-		MensagemDoTurno: mensagem,
-		ContagemCartas:  contagem,
-		UltimaJogada:    ultima,
-		VencedorJogada:  vencedorJogada,
-		VencedorRodada:  vencedorRodada,
-		NumeroRodada:    sala.NumeroRodada,
-		PontosRodada:    sala.PontosRodada,
-		PontosPartida:   sala.PontosPartida,
-	}
-}
-
-func (sala *Sala) iniciarPartida() {
-	sala.mutex.Lock()
-	sala.Estado = "JOGANDO"
-	sala.CartasNaMesa = make(map[string]Carta)
-	sala.JogadasNaRodada = 0
-	sala.Prontos = make(map[string]bool)
-
-	if sala.PontosPartida == nil {
-		sala.PontosPartida = make(map[string]int)
-		for _, p := range sala.Jogadores {
-			sala.PontosPartida[p.Nome] = 0
-		}
-	}
-	if sala.PontosRodada == nil {
-		sala.PontosRodada = make(map[string]int)
-	}
-	for _, p := range sala.Jogadores {
-		sala.PontosRodada[p.Nome] = 0
-	}
-	sala.mutex.Unlock()
-
-	sala.enviarAtualizacaoJogo("[SISTEMA] Partida iniciada! Use /jogar <ID_da_carta> para jogar. Use /cartas para ver sua mão.", "", "")
-}
-
-func (sala *Sala) processarJogada(jogador *Cliente, cartaID string) {
-	sala.mutex.Lock()
-
-	if sala.Estado != "JOGANDO" {
-		sala.mutex.Unlock()
-		return
-	}
-
-	if _, ok := sala.CartasNaMesa[jogador.Nome]; ok {
-		sala.mutex.Unlock()
-		sala.enviarAtualizacaoJogo("Você já jogou. Aguarde o oponente.", "", "")
-		return
-	}
-
-	var carta Carta
-	var cartaIndex = -1
-	for i, c := range jogador.Inventario {
-		if c.ID == cartaID {
-			carta = c
-			cartaIndex = i
-			break
-		}
-	}
-
-	if cartaIndex == -1 {
-		sala.mutex.Unlock()
-		sala.enviarAtualizacaoJogo(fmt.Sprintf("[SISTEMA] %s jogou uma carta inválida.", jogador.Nome), "", "")
-		return
-	}
-
-	// Move a carta do inventário para a mesa
-	jogador.Inventario = append(jogador.Inventario[:cartaIndex], jogador.Inventario[cartaIndex+1:]...)
-	sala.CartasNaMesa[jogador.Nome] = carta
-	sala.JogadasNaRodada++
-
-	// Se ambos jogaram, resolve a jogada
-	if len(sala.CartasNaMesa) == 2 {
-		p1 := sala.Jogadores[0]
-		p2 := sala.Jogadores[1]
-		c1 := sala.CartasNaMesa[p1.Nome]
-		c2 := sala.CartasNaMesa[p2.Nome]
-
-		vencedorJogada := "EMPATE"
-		var vencedor *Cliente
-
-		resultado := compararCartas(c1, c2)
-		if resultado > 0 {
-			vencedorJogada = p1.Nome
-			vencedor = p1
-		} else if resultado < 0 {
-			vencedorJogada = p2.Nome
-			vencedor = p2
-		}
-
-		if vencedor != nil {
-			sala.PontosRodada[vencedor.Nome]++
-		}
-		// As cartas são descartadas e não retornam ao inventário.
-
-		// Limpa a mesa para a próxima jogada
-		sala.CartasNaMesa = make(map[string]Carta)
-		sala.mutex.Unlock()
-
-		sala.enviarAtualizacaoJogo(fmt.Sprintf("Vencedor da jogada: %s", vencedorJogada), vencedorJogada, "")
-
-		// Checa fim da partida por 0 cartas (agora acontecerá após 5 rodadas)
-		if len(p1.Inventario) == 0 {
-			vencedorFinal := "EMPATE"
-			p1Pontos := sala.PontosRodada[p1.Nome]
-			p2Pontos := sala.PontosRodada[p2.Nome]
-			if p1Pontos > p2Pontos {
-				vencedorFinal = p1.Nome
-			} else if p2Pontos > p1Pontos {
-				vencedorFinal = p2.Nome
-			}
-			sala.finalizarPartida(vencedorFinal)
-			return
-		}
-
-		sala.enviarAtualizacaoJogo("Próxima jogada. Use /jogar <ID_da_carta> para jogar ou /cartas para ver sua mão.", "", "")
-		return
-	}
-
-	sala.mutex.Unlock()
-	sala.enviarAtualizacaoJogo("Aguardando o oponente...", "", "")
-}
-
-func (sala *Sala) finalizarPartida(vencedor string) {
-	sala.mutex.Lock()
-	sala.Estado = "FINALIZADO"
-	sala.mutex.Unlock()
-
-	sala.broadcast(nil, protocolo.Mensagem{Comando: "FIM_DE_JOGO", Dados: mustJSON(protocolo.DadosFimDeJogo{VencedorNome: vencedor})})
-
-	sala.reiniciarSala()
-	sala.broadcast(nil, protocolo.Mensagem{
-		Comando: "SISTEMA",
-		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Partida finalizada. Use /comprar para adquirir um pacote e iniciar uma nova partida."}),
-	})
-}
-
-func (sala *Sala) reiniciarSala() {
-	sala.mutex.Lock()
-	defer sala.mutex.Unlock()
-
-	sala.Estado = "AGUARDANDO_COMPRA"
-	sala.CartasNaMesa = make(map[string]Carta)
-	sala.PontosRodada = make(map[string]int)
-	sala.PontosPartida = make(map[string]int)
-	sala.NumeroRodada = 1
-	sala.JogadasNaRodada = 0
-	sala.Prontos = make(map[string]bool)
-	for _, j := range sala.Jogadores {
-		j.Inventario = j.Inventario[:0] // Limpa o inventário
-	}
-}
-
-func compararCartas(c1, c2 Carta) int {
-	if c1.Valor != c2.Valor {
-		return c1.Valor - c2.Valor
+// tirarDaFila remove o cliente da fila de espera, soltando a referência dela
+// (sob filaMutex)
+func (s *Servidor) tirarDaFila(c *Cliente) {
+	if s.filaDeEspera == c {
+		s.filaDeEspera = nil
+		c.soltar()
 	}
-	naipes := map[string]int{"♠": 4, "♥": 3, "♦": 2, "♣": 1}
-	return naipes[c1.Naipe] - naipes[c2.Naipe]
 }
-
 func (s *Servidor) mostrarCartasDetalhadas(cliente *Cliente) {
-	if len(cliente.Inventario) == 0 {
+	var inventario []Carta
+	if sala := cliente.salaAtual(); sala != nil {
+		inventario = sala.cartasDe(cliente)
+	} else {
+		inventario = cliente.cartas()
+	}
+	if len(inventario) == 0 {
 		s.enviar(cliente, protocolo.Mensagem{
 			Comando: "SISTEMA",
 			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "Você não possui cartas. Use /comprar para comprar um pacote."}),
@@ -935,7 +1091,7 @@ func (s *Servidor) mostrarCartasDetalhadas(cliente *Cliente) {
 
 	var builder strings.Builder
 	builder.WriteString("\n=== SUAS CARTAS ===\n")
-	for i, carta := range cliente.Inventario {
+	for i, carta := range inventario {
 		builder.WriteString(fmt.Sprintf("%d. %s %s (ID: %s, Poder: %d, Raridade: %s)\n",
 			i+1, carta.Nome, carta.Naipe, carta.ID, carta.Valor, carta.Raridade))
 	}
@@ -956,25 +1112,10 @@ func novoID() string {
 	id := atomic.AddInt64(&idSeq, 1)
 	return fmt.Sprintf("c%d", id)
 }
-func sampleRaridade() string {
-	// BAREMA ITEM 8: PACOTES - Cria gerador aleatório único para esta operação
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	x := rng.Intn(100)
-	if x < 70 {
-		return "C"
-	}
-	if x < 90 {
-		return "U"
-	}
-	if x < 99 {
-		return "R"
-	}
-	return "L"
-}
 
 // BAREMA ITEM 8: PACOTES - Gera estoque inicial distribuído entre shards
 // Cria um grande estoque de cartas com distribuição de raridade para testes de estresse
-func gerarEstoquesIniciais() []map[string][]Carta {
+func gerarEstoquesIniciais(numEstoqueShards int) []map[string][]Carta {
 	rand.Seed(time.Now().UnixNano())
 	allStocks := make([]map[string][]Carta, numEstoqueShards)
 
@@ -0,0 +1,282 @@
+package main
+
+// ===================== BAREMA ITEM 6: LATÊNCIA =====================
+// Métricas no formato de exposição de texto do Prometheus.
+// Contadores são atualizados nos caminhos quentes com operações atômicas;
+// gauges caros (salas, estoque) são calculados apenas no momento da coleta.
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BAREMA ITEM 6: LATÊNCIA - Limites (em segundos) dos buckets do histograma de comandos
+var bucketsLatencia = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+// BAREMA ITEM 6: LATÊNCIA - Limites (em segundos) dos buckets do RTT dos clientes
+var bucketsRTT = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Comandos conhecidos do protocolo; qualquer outro é agregado como "DESCONHECIDO"
+// para que um cliente malicioso não crie séries arbitrárias
+var comandosConhecidos = map[string]bool{
+	"LOGIN": true, "ENTRAR_NA_FILA": true, "COMPRAR_PACOTE": true, "JOGAR_CARTA": true,
+	"ENVIAR_CHAT": true, "PONG": true, "VER_CARTAS": true, "SAIR_DA_SALA": true,
+	"QUIT": true, "PING": true, "ENTRAR_CANAL": true, "SAIR_CANAL": true,
+	"ADICIONAR_AMIGO": true, "REMOVER_AMIGO": true, "LISTAR_AMIGOS": true, "MENSAGEM_PRIVADA": true,
+	"DESAFIAR": true, "ACEITAR_DESAFIO": true, "RECUSAR_DESAFIO": true,
+	"BLOQUEAR": true, "DESBLOQUEAR": true, "DENUNCIAR": true,
+}
+
+// histograma acumula observações em buckets cumulativos
+type histograma struct {
+	limites  []float64       // Limites superiores dos buckets, em segundos
+	buckets  []atomic.Uint64 // Contagem por limite
+	contagem atomic.Uint64
+	somaNs   atomic.Int64
+}
+
+func novoHistograma(limites []float64) *histograma {
+	return &histograma{limites: limites, buckets: make([]atomic.Uint64, len(limites))}
+}
+
+func (h *histograma) observar(d time.Duration) {
+	seg := d.Seconds()
+	for i, limite := range h.limites {
+		if seg <= limite {
+			h.buckets[i].Add(1)
+		}
+	}
+	h.contagem.Add(1)
+	h.somaNs.Add(int64(d))
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Conjunto de métricas do servidor
+type metricas struct {
+	conexoesAtivas          atomic.Int64
+	pacotesRejeitados       atomic.Uint64
+	descartesSaida          [numPrioridades]atomic.Uint64 // Mensagens descartadas por fila de saída cheia
+	atualizacoesCoalescidas atomic.Uint64                 // ATUALIZACAO_JOGO substituídos antes de serem escritos
+	desconexoesLentas       atomic.Uint64                 // Sessões encerradas por estouro da fila crítica
+	partidasConcluidas      atomic.Uint64
+	comandosLimitados       atomic.Uint64 // Comandos descartados pelo limite de taxa
+	desconexoesAbuso        atomic.Uint64 // Clientes desconectados por flood
+	recusasPorIP            atomic.Uint64 // Conexões recusadas pelo limite por IP
+	atrasosAnunciados       atomic.Uint64 // BAREMA ITEM 6: LATÊNCIA - Jogadores anunciados ao oponente como atrasados
+	quedasPulsacao          atomic.Uint64 // Sessões encerradas ou jogadores tirados da sala por falta de PONG
+	desconexoesOciosas      atomic.Uint64 // Sessões encerradas por ociosidade no lobby
+	presencasAnunciadas     atomic.Uint64 // BAREMA ITEM 2: COMUNICAÇÃO - Mudanças de presença enviadas aos amigos
+	privadasEntregues       atomic.Uint64 // Mensagens privadas entregues na hora
+	privadasGuardadas       atomic.Uint64 // Mensagens privadas guardadas para um amigo offline
+	desafiosAceitos         atomic.Uint64 // Salas privadas criadas por desafio
+	chatSilenciado          atomic.Uint64 // BAREMA ITEM 2: COMUNICAÇÃO - Mensagens recusadas por silêncio ou banimento
+	chatBloqueado           atomic.Uint64 // Mensagens barradas pelo filtro de palavras
+	chatMascarado           atomic.Uint64 // Mensagens entregues com palavras mascaradas
+	denunciasRecebidas      atomic.Uint64 // Denúncias enviadas à fila de moderação
+	latenciaPorComando      sync.Map      // comando -> *histograma
+	rtt                     *histograma   // BAREMA ITEM 6: LATÊNCIA - RTT medido pelos PONGs dos clientes
+	entradasInvalidas       sync.Map      // código de erro (protocolo.Erro*) -> *atomic.Uint64
+}
+
+// observarComando registra a duração do processamento de um comando
+func (m *metricas) observarComando(comando string, d time.Duration) {
+	if !comandosConhecidos[comando] {
+		comando = "DESCONHECIDO"
+	}
+	h, ok := m.latenciaPorComando.Load(comando)
+	if !ok {
+		h, _ = m.latenciaPorComando.LoadOrStore(comando, novoHistograma(bucketsLatencia))
+	}
+	h.(*histograma).observar(d)
+}
+
+// observarEntradaInvalida conta mensagens rejeitadas por código de erro
+func (m *metricas) observarEntradaInvalida(codigo string) {
+	v, ok := m.entradasInvalidas.Load(codigo)
+	if !ok {
+		v, _ = m.entradasInvalidas.LoadOrStore(codigo, new(atomic.Uint64))
+	}
+	v.(*atomic.Uint64).Add(1)
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Handler do endpoint /metrics
+func (s *Servidor) handleMetricas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.escreverMetricas(w)
+}
+
+func (s *Servidor) escreverMetricas(w io.Writer) {
+	m := &s.metricas
+
+	escreverMetrica(w, "jogo_conexoes_ativas", "gauge", "Conexões de clientes abertas (TCP e WebSocket).")
+	fmt.Fprintf(w, "jogo_conexoes_ativas %d\n", m.conexoesAtivas.Load())
+
+	// Sem conexões, o número de goroutines deve voltar ao patamar do boot;
+	// crescimento contínuo indica goroutines presas a sessões encerradas
+	escreverMetrica(w, "jogo_goroutines", "gauge", "Goroutines em execução no servidor.")
+	fmt.Fprintf(w, "jogo_goroutines %d\n", runtime.NumGoroutine())
+
+	// Salas por estado
+	porEstado := map[string]int{"AGUARDANDO_COMPRA": 0, "JOGANDO": 0, "FINALIZADO": 0}
+	s.salas.Range(func(_, v any) bool {
+		if estado := v.(*Sala).estadoAtual(); estado != "ENCERRADA" {
+			porEstado[estado]++
+		}
+		return true
+	})
+	escreverMetrica(w, "jogo_salas", "gauge", "Salas ativas por estado.")
+	for _, estado := range chavesOrdenadas(porEstado) {
+		fmt.Fprintf(w, "jogo_salas{estado=%q} %d\n", estado, porEstado[estado])
+	}
+
+	s.filaMutex.Lock()
+	tamanhoFila := 0
+	if s.filaDeEspera != nil {
+		tamanhoFila = 1
+	}
+	s.filaMutex.Unlock()
+	escreverMetrica(w, "jogo_fila_tamanho", "gauge", "Jogadores aguardando pareamento.")
+	fmt.Fprintf(w, "jogo_fila_tamanho %d\n", tamanhoFila)
+
+	escreverMetrica(w, "jogo_pacotes_pendentes", "gauge", "Pedidos de pacote aguardando no packWorkerPool.")
+	fmt.Fprintf(w, "jogo_pacotes_pendentes %d\n", len(s.packWorkerPool))
+	escreverMetrica(w, "jogo_pacotes_capacidade", "gauge", "Capacidade do buffer do packWorkerPool.")
+	fmt.Fprintf(w, "jogo_pacotes_capacidade %d\n", cap(s.packWorkerPool))
+	escreverMetrica(w, "jogo_pacotes_rejeitados_total", "counter", "Pedidos de pacote rejeitados por packWorkerPool cheio.")
+	fmt.Fprintf(w, "jogo_pacotes_rejeitados_total %d\n", m.pacotesRejeitados.Load())
+
+	escreverMetrica(w, "jogo_mensagens_descartadas_total", "counter", "Mensagens descartadas por fila de saída cheia, por prioridade.")
+	for p := prioridadeBaixa; p < prioridadeCritica; p++ {
+		fmt.Fprintf(w, "jogo_mensagens_descartadas_total{prioridade=%q} %d\n", nomesPrioridade[p], m.descartesSaida[p].Load())
+	}
+	escreverMetrica(w, "jogo_atualizacoes_coalescidas_total", "counter", "ATUALIZACAO_JOGO substituídos por um mais recente antes de serem escritos.")
+	fmt.Fprintf(w, "jogo_atualizacoes_coalescidas_total %d\n", m.atualizacoesCoalescidas.Load())
+	escreverMetrica(w, "jogo_desconexoes_cliente_lento_total", "counter", "Sessões encerradas por não acompanharem as mensagens críticas.")
+	fmt.Fprintf(w, "jogo_desconexoes_cliente_lento_total %d\n", m.desconexoesLentas.Load())
+
+	// BAREMA ITEM 6: LATÊNCIA - Pulsação e ociosidade
+	escreverMetrica(w, "jogo_atrasos_anunciados_total", "counter", "Jogadores anunciados ao oponente como atrasados por PINGs sem resposta.")
+	fmt.Fprintf(w, "jogo_atrasos_anunciados_total %d\n", m.atrasosAnunciados.Load())
+	escreverMetrica(w, "jogo_quedas_pulsacao_total", "counter", "Sessões encerradas ou jogadores tirados da partida por PINGs sem resposta.")
+	fmt.Fprintf(w, "jogo_quedas_pulsacao_total %d\n", m.quedasPulsacao.Load())
+	escreverMetrica(w, "jogo_desconexoes_ociosas_total", "counter", "Sessões encerradas por ociosidade no lobby.")
+	fmt.Fprintf(w, "jogo_desconexoes_ociosas_total %d\n", m.desconexoesOciosas.Load())
+
+	// Estoque restante por raridade (soma de todos os shards)
+	estoque := map[string]int{"C": 0, "U": 0, "R": 0, "L": 0}
+	for _, shard := range s.shardedEstoque {
+		shard.mutex.Lock()
+		for raridade, cartas := range shard.estoque {
+			estoque[raridade] += len(cartas)
+		}
+		shard.mutex.Unlock()
+	}
+	escreverMetrica(w, "jogo_estoque_cartas", "gauge", "Cartas restantes no estoque global por raridade.")
+	for _, raridade := range chavesOrdenadas(estoque) {
+		fmt.Fprintf(w, "jogo_estoque_cartas{raridade=%q} %d\n", raridade, estoque[raridade])
+	}
+
+	escreverMetrica(w, "jogo_comandos_limitados_total", "counter", "Comandos descartados pelo limite de taxa por cliente.")
+	fmt.Fprintf(w, "jogo_comandos_limitados_total %d\n", m.comandosLimitados.Load())
+	escreverMetrica(w, "jogo_desconexoes_abuso_total", "counter", "Clientes desconectados por excesso de comandos.")
+	fmt.Fprintf(w, "jogo_desconexoes_abuso_total %d\n", m.desconexoesAbuso.Load())
+	escreverMetrica(w, "jogo_conexoes_recusadas_ip_total", "counter", "Conexões recusadas pelo limite por IP de origem.")
+	fmt.Fprintf(w, "jogo_conexoes_recusadas_ip_total %d\n", m.recusasPorIP.Load())
+
+	escreverMetrica(w, "jogo_entradas_invalidas_total", "counter", "Mensagens rejeitadas por formato ou tamanho, por código de erro.")
+	var codigos []string
+	m.entradasInvalidas.Range(func(k, _ any) bool {
+		codigos = append(codigos, k.(string))
+		return true
+	})
+	sort.Strings(codigos)
+	for _, codigo := range codigos {
+		v, _ := m.entradasInvalidas.Load(codigo)
+		fmt.Fprintf(w, "jogo_entradas_invalidas_total{codigo=%q} %d\n", codigo, v.(*atomic.Uint64).Load())
+	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Canais de chat do lobby
+	escreverMetrica(w, "jogo_canal_membros", "gauge", "Sessões inscritas em cada canal de chat do lobby.")
+	for _, nome := range chavesOrdenadas(s.canais) {
+		fmt.Fprintf(w, "jogo_canal_membros{canal=%q} %d\n", nome, s.canais[nome].quantidade())
+	}
+	escreverMetrica(w, "jogo_canal_mensagens_total", "counter", "Mensagens publicadas em cada canal de chat do lobby.")
+	for _, nome := range chavesOrdenadas(s.canais) {
+		fmt.Fprintf(w, "jogo_canal_mensagens_total{canal=%q} %d\n", nome, s.canais[nome].mensagens.Load())
+	}
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Amigos e mensagens privadas
+	escreverMetrica(w, "jogo_presencas_anunciadas_total", "counter", "Mudanças de presença enviadas aos amigos.")
+	fmt.Fprintf(w, "jogo_presencas_anunciadas_total %d\n", m.presencasAnunciadas.Load())
+	escreverMetrica(w, "jogo_mensagens_privadas_total", "counter", "Mensagens privadas entregues na hora ou guardadas para um amigo offline.")
+	fmt.Fprintf(w, "jogo_mensagens_privadas_total{entrega=\"direta\"} %d\n", m.privadasEntregues.Load())
+	fmt.Fprintf(w, "jogo_mensagens_privadas_total{entrega=\"guardada\"} %d\n", m.privadasGuardadas.Load())
+	escreverMetrica(w, "jogo_desafios_aceitos_total", "counter", "Salas privadas criadas por desafio entre amigos.")
+	fmt.Fprintf(w, "jogo_desafios_aceitos_total %d\n", m.desafiosAceitos.Load())
+
+	// BAREMA ITEM 2: COMUNICAÇÃO - Moderação do chat
+	escreverMetrica(w, "jogo_chat_moderado_total", "counter", "Mensagens de chat recusadas (silêncio ou banimento), barradas pelo filtro ou entregues com palavras mascaradas.")
+	fmt.Fprintf(w, "jogo_chat_moderado_total{acao=\"silenciada\"} %d\n", m.chatSilenciado.Load())
+	fmt.Fprintf(w, "jogo_chat_moderado_total{acao=\"bloqueada\"} %d\n", m.chatBloqueado.Load())
+	fmt.Fprintf(w, "jogo_chat_moderado_total{acao=\"mascarada\"} %d\n", m.chatMascarado.Load())
+	escreverMetrica(w, "jogo_denuncias_total", "counter", "Denúncias recebidas pela fila de moderação.")
+	fmt.Fprintf(w, "jogo_denuncias_total %d\n", m.denunciasRecebidas.Load())
+	s.moderacao.mutex.Lock()
+	pendentes := len(s.moderacao.Denuncias)
+	s.moderacao.mutex.Unlock()
+	escreverMetrica(w, "jogo_denuncias_pendentes", "gauge", "Denúncias na fila aguardando um moderador.")
+	fmt.Fprintf(w, "jogo_denuncias_pendentes %d\n", pendentes)
+
+	escreverMetrica(w, "jogo_partidas_concluidas_total", "counter", "Partidas que chegaram ao FIM_DE_JOGO.")
+	fmt.Fprintf(w, "jogo_partidas_concluidas_total %d\n", m.partidasConcluidas.Load())
+
+	// Histograma de latência de processamento por comando
+	escreverMetrica(w, "jogo_comando_duracao_segundos", "histogram", "Tempo de processamento de cada comando recebido.")
+	var comandos []string
+	m.latenciaPorComando.Range(func(k, _ any) bool {
+		comandos = append(comandos, k.(string))
+		return true
+	})
+	sort.Strings(comandos)
+	for _, comando := range comandos {
+		v, _ := m.latenciaPorComando.Load(comando)
+		escreverHistograma(w, "jogo_comando_duracao_segundos", fmt.Sprintf("comando=%q", comando), v.(*histograma))
+	}
+
+	escreverMetrica(w, "jogo_rtt_segundos", "histogram", "Tempo de ida e volta PING/PONG dos clientes, medido pelo servidor.")
+	escreverHistograma(w, "jogo_rtt_segundos", "", m.rtt)
+}
+
+// escreverHistograma escreve as séries de h; rotulos vem sem chaves (ex.: `comando="PING"`)
+func escreverHistograma(w io.Writer, nome, rotulos string, h *histograma) {
+	prefixo, chaves := "", ""
+	if rotulos != "" {
+		prefixo, chaves = rotulos+",", "{"+rotulos+"}"
+	}
+	for i, limite := range h.limites {
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%g\"} %d\n", nome, prefixo, limite, h.buckets[i].Load())
+	}
+	contagem := h.contagem.Load()
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", nome, prefixo, contagem)
+	fmt.Fprintf(w, "%s_sum%s %g\n", nome, chaves, time.Duration(h.somaNs.Load()).Seconds())
+	fmt.Fprintf(w, "%s_count%s %d\n", nome, chaves, contagem)
+}
+
+func escreverMetrica(w io.Writer, nome, tipo, ajuda string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", nome, strings.ReplaceAll(ajuda, "\n", " "), nome, tipo)
+}
+
+func chavesOrdenadas[V any](m map[string]V) []string {
+	chaves := make([]string, 0, len(m))
+	for k := range m {
+		chaves = append(chaves, k)
+	}
+	sort.Strings(chaves)
+	return chaves
+}
@@ -0,0 +1,360 @@
+package main
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Moderação do chat.
+// Toda mensagem de chat (da sala, dos canais do lobby e privada) passa por
+// moderarChat antes de ser entregue: quem está silenciado ou banido não fala,
+// e o filtroChat das regras de jogo mascara palavrões ou barra a mensagem
+// inteira. As mensagens de quem o jogador bloqueou não chegam a ele (ver
+// amigos.go), e nenhuma mensagem volta ao próprio autor. Cada sessão guarda
+// as últimas -historico-denuncia linhas de chat que enviou ou recebeu;
+// DENUNCIAR copia essas linhas para a fila de moderação. A API de
+// administração consulta a fila e aplica silêncios e banimentos com prazo.
+// Se o administrador pedir, a punição também se estende ao IP das sessões
+// conectadas com o nome naquele momento, para que reconectar com outro nome
+// não a contorne; enquanto ela vale, a sessão não pode trocar de nome.
+// Punições vigentes e denúncias pendentes são gravadas em -arquivo-moderacao
+// no desligamento e lidas no boot.
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"meujogo/protocolo"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Denúncias pendentes além deste número descartam as mais antigas
+const maxDenuncias = 1000
+
+// BAREMA ITEM 4: ENCAPSULAMENTO - Palavras filtradas no chat (parte das regras recarregáveis)
+// A comparação ignora maiúsculas e só considera palavras inteiras
+type FiltroChat struct {
+	Mascarar []string `json:"mascarar,omitempty"` // Trocadas por asteriscos
+	Bloquear []string `json:"bloquear,omitempty"` // Impedem o envio da mensagem inteira
+}
+
+// aplicar mascara os termos de Mascarar; devolve false se o texto contém um termo de Bloquear
+func (f FiltroChat) aplicar(texto string) (string, bool) {
+	if len(f.Mascarar) == 0 && len(f.Bloquear) == 0 {
+		return texto, true
+	}
+	runas := []rune(texto)
+	baixas := minusculas(texto)
+	for _, termo := range f.Bloquear {
+		if len(ocorrencias(baixas, termo)) > 0 {
+			return "", false
+		}
+	}
+	for _, termo := range f.Mascarar {
+		n := utf8.RuneCountInString(termo)
+		for _, i := range ocorrencias(baixas, termo) {
+			for j := i; j < i+n; j++ {
+				if !unicode.IsSpace(runas[j]) {
+					runas[j] = '*'
+				}
+			}
+		}
+	}
+	return string(runas), true
+}
+
+// minusculas converte runa a runa, mantendo as posições do texto original
+func minusculas(texto string) []rune {
+	runas := []rune(texto)
+	for i, r := range runas {
+		runas[i] = unicode.ToLower(r)
+	}
+	return runas
+}
+
+// ocorrencias devolve o início de cada aparição do termo como palavra inteira
+func ocorrencias(texto []rune, termo string) []int {
+	alvo := minusculas(termo)
+	var posicoes []int
+	for i := 0; i+len(alvo) <= len(texto); i++ {
+		if slices.Equal(texto[i:i+len(alvo)], alvo) && !alfanumerico(texto, i-1) && !alfanumerico(texto, i+len(alvo)) {
+			posicoes = append(posicoes, i)
+		}
+	}
+	return posicoes
+}
+
+func alfanumerico(texto []rune, i int) bool {
+	return i >= 0 && i < len(texto) && (unicode.IsLetter(texto[i]) || unicode.IsDigit(texto[i]))
+}
+
+/* ====================== Histórico para denúncias ====================== */
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Uma mensagem de chat como a sessão a viu
+type linhaChat struct {
+	De    string    `json:"de"`
+	Onde  string    `json:"onde"` // "sala", "#canal" ou "privada"
+	Texto string    `json:"texto"`
+	Em    time.Time `json:"em"`
+}
+
+// recentesChat guarda as últimas linhas de chat de uma sessão; salas, canais
+// e a rede de amigos escrevem de goroutines diferentes
+type recentesChat struct {
+	mutex  sync.Mutex
+	linhas []linhaChat // Da mais antiga para a mais nova
+}
+
+func (r *recentesChat) lembrar(l linhaChat, max int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.linhas) >= max {
+		r.linhas = append(r.linhas[:0], r.linhas[len(r.linhas)-max+1:]...)
+	}
+	r.linhas = append(r.linhas, l)
+}
+
+func (r *recentesChat) copia() []linhaChat {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.linhas)
+}
+
+func (r *recentesChat) limpar() {
+	r.mutex.Lock()
+	r.linhas = r.linhas[:0]
+	r.mutex.Unlock()
+}
+
+// lembrarChat registra a linha no histórico da sessão (se -historico-denuncia > 0)
+func (s *Servidor) lembrarChat(c *Cliente, l linhaChat) {
+	if s.cfg.HistoricoDenuncia > 0 {
+		c.recentes.lembrar(l, s.cfg.HistoricoDenuncia)
+	}
+}
+
+/* ====================== Punições e denúncias ====================== */
+
+// punicao é um silêncio ou banimento com prazo
+type punicao struct {
+	Ate    time.Time `json:"ate"`
+	Motivo string    `json:"motivo,omitempty"`
+	Nome   string    `json:"nome,omitempty"` // Nas punições por IP: o nome punido que o usava
+}
+
+// BAREMA ITEM 1: ARQUITETURA - Denúncia na fila de moderação
+type denuncia struct {
+	ID          int         `json:"id"`
+	Denunciante string      `json:"denunciante"`
+	Denunciado  string      `json:"denunciado"`
+	Motivo      string      `json:"motivo,omitempty"`
+	CriadaEm    time.Time   `json:"criadaEm"`
+	Mensagens   []linhaChat `json:"mensagens"` // Últimas linhas vistas pelo denunciante
+}
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Punições por nome e por IP e fila de denúncias
+// Também é o conteúdo de -arquivo-moderacao
+type moderacao struct {
+	mutex       sync.Mutex
+	Silenciados map[string]punicao `json:"silenciados"`
+	Banidos     map[string]punicao `json:"banidos"`
+	SilenciosIP map[string]punicao `json:"silenciosIP"`
+	BanidosIP   map[string]punicao `json:"banidosIP"`
+	Denuncias   []denuncia         `json:"denuncias"` // Da mais antiga para a mais nova
+	ProximoID   int                `json:"proximoId"`
+}
+
+func novaModeracao() *moderacao {
+	return &moderacao{
+		Silenciados: make(map[string]punicao),
+		Banidos:     make(map[string]punicao),
+		SilenciosIP: make(map[string]punicao),
+		BanidosIP:   make(map[string]punicao),
+		ProximoID:   1,
+	}
+}
+
+// tipoPunicao junta as punições de um tipo (silêncio ou banimento) por nome e por IP
+type tipoPunicao struct {
+	nomes, ips map[string]punicao
+}
+
+func (m *moderacao) silencios() tipoPunicao {
+	return tipoPunicao{nomes: m.Silenciados, ips: m.SilenciosIP}
+}
+
+func (m *moderacao) banimentos() tipoPunicao {
+	return tipoPunicao{nomes: m.Banidos, ips: m.BanidosIP}
+}
+
+// carregarModeracao lê as punições e denúncias do último desligamento
+func carregarModeracao(arquivo string) (*moderacao, error) {
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		return novaModeracao(), err
+	}
+	m := novaModeracao()
+	if err := json.Unmarshal(conteudo, m); err != nil {
+		return novaModeracao(), fmt.Errorf("moderação %s: %w", arquivo, err)
+	}
+	if m.Silenciados == nil {
+		m.Silenciados = make(map[string]punicao)
+	}
+	if m.Banidos == nil {
+		m.Banidos = make(map[string]punicao)
+	}
+	if m.SilenciosIP == nil {
+		m.SilenciosIP = make(map[string]punicao)
+	}
+	if m.BanidosIP == nil {
+		m.BanidosIP = make(map[string]punicao)
+	}
+	slog.Info("moderação restaurada", "arquivo", arquivo, "silenciados", len(m.Silenciados), "banidos", len(m.Banidos), "ips", len(m.SilenciosIP)+len(m.BanidosIP), "denuncias", len(m.Denuncias))
+	return m, nil
+}
+
+// salvar grava as punições ainda vigentes e as denúncias pendentes
+func (m *moderacao) salvar(arquivo string) error {
+	m.mutex.Lock()
+	m.expirar(time.Now())
+	conteudo, err := json.Marshal(m)
+	m.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return gravarArquivo(arquivo, conteudo)
+}
+
+// expirar descarta as punições vencidas (sob m.mutex)
+func (m *moderacao) expirar(agora time.Time) {
+	for _, punicoes := range []map[string]punicao{m.Silenciados, m.Banidos, m.SilenciosIP, m.BanidosIP} {
+		for nome, p := range punicoes {
+			if !agora.Before(p.Ate) {
+				delete(punicoes, nome)
+			}
+		}
+	}
+}
+
+// vigente devolve a punição do nome se ela ainda não venceu
+func (m *moderacao) vigente(punicoes map[string]punicao, nome string) (punicao, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	p, ok := punicoes[nome]
+	if ok && !time.Now().Before(p.Ate) {
+		delete(punicoes, nome)
+		return punicao{}, false
+	}
+	return p, ok
+}
+
+// vincular estende a punição do nome ao IP de uma sessão conectada com ele;
+// no IP fica a mais longa, caso ele já tenha outra. Só a API de
+// administração vincula, a pedido: um LOGIN ou uma mensagem não podem levar
+// a punição de um nome a todos os que compartilham o IP
+func (m *moderacao) vincular(t tipoPunicao, ip, nome string, p punicao) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if atual, ok := t.ips[ip]; !ok || atual.Ate.Before(p.Ate) {
+		p.Nome = nome
+		t.ips[ip] = p
+	}
+}
+
+// desvincular retira as punições por IP que vieram do nome (sob m.mutex)
+func (m *moderacao) desvincular(t tipoPunicao, nome string) {
+	for ip, p := range t.ips {
+		if p.Nome == nome {
+			delete(t.ips, ip)
+		}
+	}
+}
+
+// punicaoDe devolve a punição vigente da sessão que usa o nome: a do nome ou
+// a do IP de origem
+func (s *Servidor) punicaoDe(c *Cliente, nome string, t tipoPunicao) (punicao, bool) {
+	if p, ok := s.moderacao.vigente(t.nomes, nome); ok {
+		return p, true
+	}
+	return s.moderacao.vigente(t.ips, ipDe(c.Conn.RemoteAddr()))
+}
+
+// descreverPunicao completa os avisos de silêncio e banimento
+func descreverPunicao(p punicao) string {
+	texto := "até " + p.Ate.Local().Format("02/01 15:04")
+	if p.Motivo != "" {
+		texto += " (motivo: " + p.Motivo + ")"
+	}
+	return texto
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Aplica silêncio, banimento e filtro a uma mensagem de chat
+// Devolve o texto a entregar, ou false se a mensagem não deve sair (o autor já foi avisado)
+func (s *Servidor) moderarChat(c *Cliente, texto string) (string, bool) {
+	nome := c.nome()
+	p, punido := s.punicaoDe(c, nome, s.moderacao.banimentos())
+	if !punido {
+		p, punido = s.punicaoDe(c, nome, s.moderacao.silencios())
+	}
+	if punido {
+		s.metricas.chatSilenciado.Add(1)
+		s.avisar(c, "[SISTEMA] Você está silenciado "+descreverPunicao(p)+". Sua mensagem não foi enviada.")
+		return "", false
+	}
+	filtrado, ok := s.regras.Load().FiltroChat.aplicar(texto)
+	if !ok {
+		s.metricas.chatBloqueado.Add(1)
+		s.avisar(c, "[SISTEMA] Sua mensagem não foi enviada: ela contém um termo proibido neste servidor.")
+		return "", false
+	}
+	if filtrado != texto {
+		s.metricas.chatMascarado.Add(1)
+	}
+	return filtrado, true
+}
+
+// recusarBanido despede a sessão que tenta usar um nome banido ou vem de um
+// IP banido; devolve true se ela deve terminar
+func (s *Servidor) recusarBanido(c *Cliente, nome string) bool {
+	p, banido := s.punicaoDe(c, nome, s.moderacao.banimentos())
+	if !banido {
+		return false
+	}
+	c.log().Info("login recusado: jogador banido", "nomeSolicitado", nome, "ate", p.Ate)
+	s.despedir(c, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você está banido deste servidor " + descreverPunicao(p) + "."}),
+	})
+	return true
+}
+
+// BAREMA ITEM 3: API REMOTA - DENUNCIAR: copia as últimas mensagens vistas para a fila de moderação
+func (s *Servidor) denunciar(c *Cliente, denunciado, motivo string) {
+	nome := c.nome()
+	if denunciado == nome {
+		s.avisar(c, "[SISTEMA] Você não pode denunciar a si mesmo.")
+		return
+	}
+	d := denuncia{
+		Denunciante: nome,
+		Denunciado:  denunciado,
+		Motivo:      strings.TrimSpace(motivo),
+		CriadaEm:    time.Now(),
+		Mensagens:   c.recentes.copia(),
+	}
+	m := s.moderacao
+	m.mutex.Lock()
+	d.ID = m.ProximoID
+	m.ProximoID++
+	if len(m.Denuncias) >= maxDenuncias {
+		m.Denuncias = slices.Delete(m.Denuncias, 0, len(m.Denuncias)-maxDenuncias+1)
+	}
+	m.Denuncias = append(m.Denuncias, d)
+	m.mutex.Unlock()
+
+	s.metricas.denunciasRecebidas.Add(1)
+	c.log().Info("denúncia registrada", "id", d.ID, "denunciado", denunciado, "mensagens", len(d.Mensagens))
+	s.avisar(c, fmt.Sprintf("[SISTEMA] Denúncia #%d contra %s registrada com %d mensagens recentes. Um moderador vai analisá-la.", d.ID, denunciado, len(d.Mensagens)))
+}
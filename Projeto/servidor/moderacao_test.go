@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"meujogo/clientesdk"
+	"meujogo/protocolo"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BAREMA ITEM 9: TESTES - Punições não se contornam trocando de nome
+// Eva é silenciada com estenderIP e tenta trocar de nome na mesma sessão,
+// depois reconecta da mesma máquina com outro nome: continua silenciada até
+// o administrador retirar o silêncio. Banida com o nome novo, a máquina passa
+// a ser recusada já na conexão. Já tentar entrar com um nome banido não pune
+// o IP de quem tentou.
+func TestPunicaoNaoMudaComNome(t *testing.T) {
+	s, transporte := iniciarServidorMemoria(t)
+	ouvinte := conectarMemoria(t, transporte, "ouvinte")
+	entrarNoCanal(t, ouvinte, "global")
+
+	// O nome banido é recusado, mas a máquina de quem o digitou continua livre
+	if r := chamarAdmin(s.adminBanir, http.MethodPost, "vilao", `{"duracao": "10m"}`); r.Code != http.StatusOK {
+		t.Fatalf("banir: %d %s", r.Code, r.Body)
+	}
+	curioso := conectarDe(t, transporte, "10.0.0.9:50001", "")
+	curioso.Login("vilao")
+	esperarAviso(t, curioso, "banido")
+	entrarNoCanal(t, conectarDe(t, transporte, "10.0.0.9:50002", "inocente"), "global")
+
+	eva := conectarDe(t, transporte, "10.0.0.7:40001", "eva")
+	entrarNoCanal(t, eva, "global")
+	if r := chamarAdmin(s.adminSilenciar, http.MethodPost, "eva", `{"duracao": "10m", "estenderIP": true}`); r.Code != http.StatusOK {
+		t.Fatalf("silenciar: %d %s", r.Code, r.Body)
+	}
+	eva.ChatCanal("global", "primeira")
+	esperarAviso(t, eva, "silenciado")
+
+	eva.Login("eva-nova")
+	esperarAviso(t, eva, "não pode trocar de nome")
+	if c := s.buscarClientePorNome("eva"); c == nil {
+		t.Fatal("a sessão silenciada trocou de nome")
+	} else {
+		c.soltar()
+	}
+
+	// Outra conexão da mesma máquina, com outro nome, herda o silêncio
+	eva.Fechar()
+	eva = conectarDe(t, transporte, "10.0.0.7:40002", "outra-eva")
+	entrarNoCanal(t, eva, "global")
+	eva.ChatCanal("global", "segunda")
+	esperarAviso(t, eva, "silenciado")
+
+	// Retirar o silêncio do nome libera também o IP
+	if r := chamarAdmin(s.adminSilenciar, http.MethodDelete, "eva", ""); r.Code != http.StatusOK {
+		t.Fatalf("retirar silêncio: %d %s", r.Code, r.Body)
+	}
+	eva.ChatCanal("global", "liberada")
+	if texto := proximoChat(t, ouvinte); texto != "liberada" {
+		t.Fatalf("o ouvinte recebeu %q de uma sessão silenciada", texto)
+	}
+
+	// O banimento do nome novo vale para a máquina: um terceiro nome é recusado
+	if r := chamarAdmin(s.adminBanir, http.MethodPost, "outra-eva", `{"duracao": "10m", "estenderIP": true}`); r.Code != http.StatusOK {
+		t.Fatalf("banir: %d %s", r.Code, r.Body)
+	}
+	if esperarEvento(eva, "RECEBER_CHAT", 5*time.Second) {
+		t.Fatal("sessão banida continuou conectada")
+	}
+	esperarAviso(t, conectarDe(t, transporte, "10.0.0.7:40003", ""), "banido") // Recusada antes do LOGIN
+	if r := chamarAdmin(s.adminBanir, http.MethodDelete, "outra-eva", ""); r.Code != http.StatusOK {
+		t.Fatalf("retirar banimento: %d %s", r.Code, r.Body)
+	}
+	entrarNoCanal(t, conectarDe(t, transporte, "10.0.0.7:40004", "eva"), "global")
+}
+
+// conectarDe é conectarMemoria com o endereço remoto escolhido, para simular
+// várias conexões da mesma máquina; sem nome, não faz LOGIN
+func conectarDe(t *testing.T, transporte *transporteMemoria, endereco, nome string) *clientesdk.Cliente {
+	t.Helper()
+	cliente, err := clientesdk.Conectar(context.Background(), clientesdk.Opcoes{
+		Nome:           nome,
+		TamanhoEventos: 256,
+		Discar: func(context.Context) (net.Conn, error) {
+			servidor, cliente := net.Pipe()
+			if err := transporte.entregar(&conexaoMemoria{Conn: servidor, remoto: enderecoMemoria(endereco)}); err != nil {
+				return nil, err
+			}
+			return cliente, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("conectar %s de %s: %v", nome, endereco, err)
+	}
+	t.Cleanup(func() { cliente.Fechar() })
+	return cliente
+}
+
+func entrarNoCanal(t *testing.T, cliente *clientesdk.Cliente, canal string) {
+	t.Helper()
+	cliente.EntrarCanal(canal)
+	if !esperarEvento(cliente, "HISTORICO_CANAL", 5*time.Second) {
+		t.Fatalf("sem HISTORICO_CANAL de #%s", canal)
+	}
+}
+
+// proximoChat devolve o texto do próximo RECEBER_CHAT
+func proximoChat(t *testing.T, cliente *clientesdk.Cliente) string {
+	t.Helper()
+	limite := time.NewTimer(5 * time.Second)
+	defer limite.Stop()
+	for {
+		select {
+		case ev, ok := <-cliente.Eventos():
+			if !ok || ev.Comando == clientesdk.EventoDesconectado {
+				t.Fatal("conexão encerrada antes do chat")
+			}
+			if d, ok := ev.Dados.(protocolo.DadosReceberChat); ok {
+				return d.Texto
+			}
+		case <-limite.C:
+			t.Fatal("nenhuma mensagem de chat chegou")
+		}
+	}
+}
+
+// chamarAdmin chama uma rota de moderação da API com o nome no caminho
+func chamarAdmin(rota http.HandlerFunc, metodo, nome, corpo string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(metodo, "/api/clientes/"+nome, strings.NewReader(corpo))
+	req.SetPathValue("nome", nome)
+	resposta := httptest.NewRecorder()
+	rota(resposta, req)
+	return resposta
+}
@@ -0,0 +1,135 @@
+package main
+
+// ===================== BAREMA ITEM 6: LATÊNCIA =====================
+// Pulsação (heartbeat) e ociosidade.
+// O pingManager conta os PINGs seguidos que ficaram sem PONG. Em partida, o
+// oponente espera pelo jogador: depois de -pings-atraso perdidos ele é
+// avisado de que o jogador está atrasado (e de novo quando o PONG volta), e
+// em -pings-partida aplica-se a política -queda-partida. Fora de uma sala
+// ninguém espera, então a tolerância é maior (-pings-lobby) e o jogador só é
+// desconectado. Independentemente da rede, quem fica no lobby sem enviar
+// comandos (PING e PONG não contam) por -ocioso-lobby também é desconectado,
+// salvo enquanto aguarda um oponente na fila. O prazo de leitura
+// (-timeout-leitura) continua como última barreira.
+
+import (
+	"meujogo/protocolo"
+	"time"
+)
+
+// Políticas para quem para de responder durante uma partida
+const (
+	quedaDesconectar = "desconectar" // Encerra a sessão
+	quedaAbandonar   = "abandonar"   // Tira o jogador da sala e devolve o oponente à fila; a conexão segue sob as regras do lobby
+)
+
+// BAREMA ITEM 6: LATÊNCIA - Aplica a política de pulsação após cada intervalo de PING
+// perdidos é o número de PINGs seguidos sem PONG; devolve false se a sessão
+// foi encerrada
+func (s *Servidor) verificarPulsacao(c *Cliente, perdidos int) bool {
+	sala := c.salaAtual()
+	if sala == nil {
+		if perdidos >= s.cfg.PingsLobby {
+			s.metricas.quedasPulsacao.Add(1)
+			c.log().Warn("sem resposta aos PINGs no lobby; desconectando", "pingsPerdidos", perdidos)
+			c.cancelar()
+			return false
+		}
+		return s.verificarOciosidade(c)
+	}
+
+	if perdidos >= s.cfg.PingsAtraso && c.atrasado.CompareAndSwap(false, true) {
+		s.metricas.atrasosAnunciados.Add(1)
+		semResposta := time.Since(time.Unix(0, c.UltimoPong.Load()))
+		c.log().Info("jogador atrasado", "pingsPerdidos", perdidos, "semResposta", semResposta)
+		s.avisarAtraso(sala, c, true, semResposta)
+	}
+	if perdidos < s.cfg.PingsPartida {
+		return true
+	}
+
+	s.metricas.quedasPulsacao.Add(1)
+	if s.cfg.QuedaPartida == quedaAbandonar {
+		c.log().Warn("sem resposta aos PINGs na partida; removendo da sala", "pingsPerdidos", perdidos)
+		s.handleSairDaSala(c)
+		c.atrasado.Store(false) // O aviso de volta não teria mais a quem ser enviado
+		s.enviar(c, protocolo.Mensagem{
+			Comando: "SISTEMA",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Você foi removido da partida por não responder ao servidor."}),
+		})
+		return true
+	}
+	c.log().Warn("sem resposta aos PINGs na partida; desconectando", "pingsPerdidos", perdidos)
+	c.cancelar()
+	return false
+}
+
+// verificarOciosidade desconecta quem está no lobby sem enviar comandos há mais de -ocioso-lobby
+func (s *Servidor) verificarOciosidade(c *Cliente) bool {
+	if s.cfg.OciosoLobby <= 0 {
+		return true
+	}
+	ocioso := time.Since(time.Unix(0, c.UltimaAcao.Load()))
+	if ocioso < s.cfg.OciosoLobby {
+		return true
+	}
+	s.filaMutex.Lock()
+	naFila := s.filaDeEspera == c
+	s.filaMutex.Unlock()
+	if naFila {
+		return true // Esperar um oponente não é ociosidade
+	}
+	s.metricas.desconexoesOciosas.Add(1)
+	c.log().Info("ocioso no lobby; desconectando", "ocioso", ocioso)
+	s.despedir(c, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] Desconectado por inatividade."}),
+	})
+	c.cancelar()
+	return false
+}
+
+// pongRecebido registra a chegada de um PONG e anuncia a volta de um jogador atrasado
+func (s *Servidor) pongRecebido(c *Cliente) {
+	c.UltimoPong.Store(time.Now().UnixNano())
+	if !c.atrasado.CompareAndSwap(true, false) {
+		return
+	}
+	c.log().Info("jogador voltou a responder")
+	if sala := c.salaAtual(); sala != nil {
+		s.avisarAtraso(sala, c, false, 0)
+	}
+}
+
+// responderPing desconta um PING pendente; PONGs além deles não respondem a
+// nada e seguem o limite de taxa
+func (c *Cliente) responderPing() bool {
+	for {
+		n := c.pendentes.Load()
+		if n <= 0 {
+			return false
+		}
+		if c.pendentes.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Informa aos demais jogadores da sala que c está (ou deixou de estar) atrasado
+func (s *Servidor) avisarAtraso(sala *Sala, c *Cliente, atrasado bool, semResposta time.Duration) {
+	msg := protocolo.Mensagem{
+		Comando: "ATRASO_JOGADOR",
+		Dados: mustJSON(protocolo.DadosAtraso{
+			Jogador:       c.nome(),
+			Atrasado:      atrasado,
+			SemRespostaMs: semResposta.Milliseconds(),
+		}),
+	}
+	sala.executar(func() {
+		for _, j := range sala.Jogadores {
+			if j != c {
+				s.enviar(j, msg)
+			}
+		}
+	})
+}
@@ -0,0 +1,254 @@
+package main
+
+// ===================== BAREMA ITEM 8: PACOTES =====================
+// Regras de jogo recarregáveis sem reiniciar o servidor.
+// Probabilidades de raridade dos pacotes, cartas por pacote, desempate entre
+// naipes, catálogo de cartas comuns e textos das mensagens ficam em um
+// arquivo JSON (-regras). O arquivo é relido quando muda, ao receber SIGHUP
+// ou via POST /api/regras/recarregar. As regras vigentes ficam em um ponteiro
+// atômico; cada sala guarda o ponteiro do momento em que a partida foi
+// formada, então uma recarga só afeta partidas iniciadas depois dela. O
+// filtro de palavras do chat também fica aqui, mas vale sempre o vigente.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Ordem de sorteio das raridades (da mais comum para a mais rara)
+var ordemRaridades = []string{"C", "U", "R", "L"}
+
+// BAREMA ITEM 8: PACOTES - Regras aplicadas às partidas
+type RegrasJogo struct {
+	Raridades       map[string]int    `json:"raridades"`       // Peso de cada raridade no sorteio (C, U, R, L)
+	CartasPorPacote int               `json:"cartasPorPacote"` // Cartas entregues em cada pacote
+	ForcaNaipes     map[string]int    `json:"forcaNaipes"`     // Desempate entre cartas de mesmo valor
+	CatalogoComum   []string          `json:"catalogoComum"`   // Nomes das cartas comuns geradas com o estoque esgotado
+	Mensagens       map[string]string `json:"mensagens"`       // Textos enviados aos jogadores
+	FiltroChat      FiltroChat        `json:"filtroChat"`      // Palavras mascaradas ou proibidas no chat
+
+	Versao      int       `json:"-"` // Incrementada a cada recarga aceita
+	CarregadoEm time.Time `json:"-"`
+}
+
+// regrasPadrao reproduz os valores históricos do jogo
+func regrasPadrao(cartasPorPacote int) *RegrasJogo {
+	return &RegrasJogo{
+		Raridades:       map[string]int{"C": 70, "U": 20, "R": 9, "L": 1},
+		CartasPorPacote: cartasPorPacote,
+		ForcaNaipes:     map[string]int{"♠": 4, "♥": 3, "♦": 2, "♣": 1},
+		CatalogoComum: []string{
+			"Guerreiro", "Arqueiro", "Mago", "Cavaleiro", "Ladrão", "Clérigo",
+			"Bárbaro", "Paladino", "Ranger", "Bruxo", "Druida", "Monge",
+			"Assassino", "Bardo", "Necromante", "Elementalista", "Inquisidor",
+			"Gladiador", "Mercenário", "Escudeiro", "Aprendiz", "Novato",
+			"Veterano", "Herói", "Lenda", "Mestre", "Sábio", "Ancião",
+			"Espadachim", "Arqueiro Élfico", "Mago do Caos", "Sacerdote", "Berserker",
+			"Samurai", "Ninja", "Viking", "Cruzado", "Templário", "Caçador",
+			"Explorador", "Navegador", "Alquimista", "Encantador", "Ilusionista",
+			"Summoner", "Conjurador", "Evocador", "Invocador", "Chamador", "Convocador",
+			"Dragão", "Fênix", "Titan", "Sereia", "Lobo", "Águia", "Leão", "Tigre",
+			"Anjo", "Demônio", "Golem", "Elemental", "Espírito", "Fantasma", "Zumbi",
+			"Skeleton", "Orc", "Elfo", "Anão", "Hobbit", "Gigante", "Troll", "Ogro",
+			"Knight", "Wizard", "Rogue", "Priest", "Warrior", "Mage", "Hunter", "Shaman",
+			"Monk", "Paladin", "Druid", "Warlock", "Death Knight", "Demon Hunter", "Evoker",
+		},
+		Mensagens: map[string]string{
+			"aguardandoOponente": "[SISTEMA] Aguardando um oponente...",
+			"partidaEncontrada":  "[SISTEMA] Partida encontrada! Usem /comprar para adquirir um pacote de cartas e iniciar o jogo.",
+			"cartasRecebidas":    "[SISTEMA] Você recebeu novas cartas! Use /cartas para ver sua mão.",
+			"partidaIniciada":    "[SISTEMA] Partida iniciada! Use /jogar <posição ou nome da carta> para jogar. Use /cartas para ver sua mão.",
+			"proximaJogada":      "Próxima jogada. Use /jogar <posição ou nome da carta> para jogar ou /cartas para ver sua mão.",
+			"partidaFinalizada":  "[SISTEMA] Partida finalizada. Use /comprar para adquirir um pacote e iniciar uma nova partida.",
+		},
+	}
+}
+
+// Validar rejeita regras que deixariam o jogo inconsistente
+func (r *RegrasJogo) Validar() error {
+	var erros []error
+	total := 0
+	for raridade, peso := range r.Raridades {
+		if !strings.Contains("CURL", raridade) || len(raridade) != 1 {
+			erros = append(erros, fmt.Errorf("raridades: raridade desconhecida %q", raridade))
+		}
+		if peso < 0 {
+			erros = append(erros, fmt.Errorf("raridades: peso negativo para %q", raridade))
+		}
+		total += peso
+	}
+	if total <= 0 {
+		erros = append(erros, errors.New("raridades: a soma dos pesos deve ser positiva"))
+	}
+	if r.CartasPorPacote <= 0 || r.CartasPorPacote > 100 {
+		erros = append(erros, fmt.Errorf("cartasPorPacote deve estar entre 1 e 100 (recebido %d)", r.CartasPorPacote))
+	}
+	for naipe, forca := range r.ForcaNaipes {
+		if forca < 0 {
+			erros = append(erros, fmt.Errorf("forcaNaipes: força negativa para %q", naipe))
+		}
+	}
+	if len(r.CatalogoComum) == 0 {
+		erros = append(erros, errors.New("catalogoComum não pode ser vazio"))
+	}
+	for _, nome := range r.CatalogoComum {
+		if strings.TrimSpace(nome) == "" {
+			erros = append(erros, errors.New("catalogoComum contém nome vazio"))
+			break
+		}
+	}
+	padrao := regrasPadrao(1).Mensagens
+	for chave, texto := range r.Mensagens {
+		if _, ok := padrao[chave]; !ok {
+			erros = append(erros, fmt.Errorf("mensagens: chave desconhecida %q", chave))
+		} else if strings.TrimSpace(texto) == "" {
+			erros = append(erros, fmt.Errorf("mensagens: texto vazio para %q", chave))
+		}
+	}
+	for _, termo := range append(slices.Clone(r.FiltroChat.Mascarar), r.FiltroChat.Bloquear...) {
+		if termo == "" || strings.TrimSpace(termo) != termo {
+			erros = append(erros, fmt.Errorf("filtroChat: termo vazio ou com espaços nas pontas: %q", termo))
+		}
+	}
+	return errors.Join(erros...)
+}
+
+// BAREMA ITEM 8: PACOTES - Sorteia a raridade de uma carta conforme os pesos
+func (r *RegrasJogo) sampleRaridade() string {
+	total := 0
+	for _, raridade := range ordemRaridades {
+		total += r.Raridades[raridade]
+	}
+	x := rand.Intn(total)
+	for _, raridade := range ordemRaridades {
+		if x < r.Raridades[raridade] {
+			return raridade
+		}
+		x -= r.Raridades[raridade]
+	}
+	return "C"
+}
+
+// mensagem devolve o texto configurado para a chave
+func (r *RegrasJogo) mensagem(chave string) string {
+	return r.Mensagens[chave]
+}
+
+// compararCartas compara valor e, em caso de empate, a força do naipe
+func (r *RegrasJogo) compararCartas(c1, c2 Carta) int {
+	if c1.Valor != c2.Valor {
+		return c1.Valor - c2.Valor
+	}
+	return r.ForcaNaipes[c1.Naipe] - r.ForcaNaipes[c2.Naipe]
+}
+
+// regrasPara devolve as regras da partida do cliente ou, fora de sala, as vigentes
+func (s *Servidor) regrasPara(c *Cliente) *RegrasJogo {
+	if sala := c.salaAtual(); sala != nil {
+		var regras *RegrasJogo
+		if sala.consultar(func() { regras = sala.regras }) {
+			return regras
+		}
+	}
+	return s.regras.Load()
+}
+
+/* ====================== Carga e recarga ====================== */
+
+// lerRegras lê o arquivo sobre os valores padrão: campos ausentes mantêm o padrão
+func lerRegras(arquivo string, cartasPorPacote int) (*RegrasJogo, error) {
+	regras := regrasPadrao(cartasPorPacote)
+	if arquivo == "" {
+		return regras, nil
+	}
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("lendo regras: %w", err)
+	}
+	// Raridades e naipes informados substituem o padrão por inteiro;
+	// mensagens são mescladas, permitindo trocar apenas alguns textos
+	var brutas map[string]json.RawMessage
+	if err := json.Unmarshal(conteudo, &brutas); err != nil {
+		return nil, fmt.Errorf("regras %s: %w", arquivo, err)
+	}
+	if _, ok := brutas["raridades"]; ok {
+		regras.Raridades = nil
+	}
+	if _, ok := brutas["forcaNaipes"]; ok {
+		regras.ForcaNaipes = nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(conteudo))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(regras); err != nil {
+		return nil, fmt.Errorf("regras %s: %w", arquivo, err)
+	}
+	if err := regras.Validar(); err != nil {
+		return nil, fmt.Errorf("regras %s inválidas: %w", arquivo, err)
+	}
+	return regras, nil
+}
+
+// BAREMA ITEM 8: PACOTES - Relê o arquivo de regras e troca o ponteiro atômico
+// Em caso de erro as regras vigentes são mantidas
+func (s *Servidor) recarregarRegras(origem string) (*RegrasJogo, error) {
+	s.regrasMutex.Lock()
+	defer s.regrasMutex.Unlock()
+
+	novas, err := lerRegras(s.cfg.ArquivoRegras, s.cfg.PackSize)
+	if err != nil {
+		slog.Error("regras rejeitadas; mantendo as vigentes", "origem", origem, "versao", s.regras.Load().Versao, "erro", err)
+		return nil, err
+	}
+	novas.Versao = s.regras.Load().Versao + 1
+	novas.CarregadoEm = time.Now()
+	s.regras.Store(novas)
+	slog.Info("regras de jogo recarregadas", "origem", origem, "versao", novas.Versao)
+	return novas, nil
+}
+
+// BAREMA ITEM 8: PACOTES - Observa o arquivo de regras (mtime) e o sinal SIGHUP
+func (s *Servidor) observarRegras(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	ultimaModificacao := modificacaoArquivo(s.cfg.ArquivoRegras)
+	ticker := time.NewTicker(s.cfg.IntervaloRegras)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			s.recarregarRegras("SIGHUP")
+		case <-ticker.C:
+			if s.cfg.ArquivoRegras == "" {
+				continue
+			}
+			if atual := modificacaoArquivo(s.cfg.ArquivoRegras); !atual.Equal(ultimaModificacao) {
+				ultimaModificacao = atual
+				s.recarregarRegras("arquivo")
+			}
+		}
+	}
+}
+
+func modificacaoArquivo(arquivo string) time.Time {
+	info, err := os.Stat(arquivo)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
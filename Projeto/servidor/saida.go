@@ -0,0 +1,169 @@
+package main
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Fila de saída com prioridades.
+// Cada cliente tem uma fila por prioridade e o clienteWriter sempre escreve
+// primeiro a mais alta: estado de jogo e fim de partida passam na frente de
+// avisos, que passam na frente de chat e PING/PONG. Enfileirar nunca
+// bloqueia. Um ATUALIZACAO_JOGO que é só um retrato do estado (sem anunciar
+// vencedor de jogada ou rodada) e ainda não foi escrito é substituído pelo
+// seguinte, já que só o retrato mais recente importa. Se a fila crítica
+// estoura, o cliente é lento demais para acompanhar a partida e a sessão é
+// encerrada, em vez de seguir dessincronizada; nas demais a mensagem é
+// descartada e contabilizada.
+
+import (
+	"meujogo/protocolo"
+	"sync"
+)
+
+// Prioridades de saída, da menor para a maior
+const (
+	prioridadeBaixa   = iota // Chat, PING/PONG e LATENCIA
+	prioridadeNormal         // Avisos do sistema, erros e consultas
+	prioridadeCritica        // Estado do jogo; perder uma destas dessincroniza o cliente
+	numPrioridades
+)
+
+var nomesPrioridade = [numPrioridades]string{"baixa", "normal", "critica"}
+
+// Comandos fora deste mapa têm prioridade normal
+var prioridadeComando = map[string]int{
+	"ATUALIZACAO_JOGO":   prioridadeCritica,
+	"FIM_DE_JOGO":        prioridadeCritica,
+	"PARTIDA_ENCONTRADA": prioridadeCritica,
+	"PACOTE_RESULTADO":   prioridadeCritica,
+	"RECEBER_CHAT":       prioridadeBaixa,
+	"PING":               prioridadeBaixa,
+	"PONG":               prioridadeBaixa,
+	"LATENCIA":           prioridadeBaixa,
+}
+
+// Resultados de enfileirar
+const (
+	saidaEnfileirada = iota
+	saidaCoalescida
+	saidaDescartada
+	saidaEstourada // Fila crítica cheia: o cliente deve ser desconectado
+)
+
+// Mensagem pendente; retrato indica que a próxima ATUALIZACAO_JOGO pode substituí-la
+type itemSaida struct {
+	msg     protocolo.Mensagem
+	retrato bool
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Fila de saída de um cliente
+type caixaSaida struct {
+	mutex      sync.Mutex
+	filas      [numPrioridades][]itemSaida
+	aviso      chan struct{} // Acorda o clienteWriter (capacidade 1)
+	capacidade int           // Mensagens pendentes por prioridade
+}
+
+func novaCaixaSaida() *caixaSaida {
+	return &caixaSaida{aviso: make(chan struct{}, 1)}
+}
+
+func prioridadeDe(comando string) int {
+	if p, ok := prioridadeComando[comando]; ok {
+		return p
+	}
+	return prioridadeNormal
+}
+
+// enfileirar adiciona a mensagem sem bloquear
+func (m *caixaSaida) enfileirar(msg protocolo.Mensagem, retrato bool) int {
+	p := prioridadeDe(msg.Comando)
+	item := itemSaida{msg: msg, retrato: retrato}
+	m.mutex.Lock()
+	fila := m.filas[p]
+	resultado := saidaEnfileirada
+	switch {
+	case msg.Comando == "ATUALIZACAO_JOGO" && len(fila) > 0 && fila[len(fila)-1].retrato:
+		fila[len(fila)-1] = item // O retrato pendente ficou velho
+		resultado = saidaCoalescida
+	case len(fila) >= m.capacidade:
+		m.mutex.Unlock()
+		if p == prioridadeCritica {
+			return saidaEstourada
+		}
+		return saidaDescartada
+	default:
+		m.filas[p] = append(fila, item)
+	}
+	m.mutex.Unlock()
+
+	select {
+	case m.aviso <- struct{}{}:
+	default: // O writer já tem um aviso pendente
+	}
+	return resultado
+}
+
+// proxima retira a mensagem pendente de maior prioridade
+func (m *caixaSaida) proxima() (protocolo.Mensagem, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for p := numPrioridades - 1; p >= 0; p-- {
+		if fila := m.filas[p]; len(fila) > 0 {
+			msg := fila[0].msg
+			fila[0] = itemSaida{} // Libera os dados para o GC
+			m.filas[p] = fila[1:]
+			if len(m.filas[p]) == 0 {
+				m.filas[p] = fila[:0] // Reaproveita o array desde o início
+			}
+			return msg, true
+		}
+	}
+	return protocolo.Mensagem{}, false
+}
+
+// esvaziar descarta tudo o que não foi escrito (reciclagem do cliente)
+func (m *caixaSaida) esvaziar() {
+	m.mutex.Lock()
+	for p := range m.filas {
+		clear(m.filas[p])
+		m.filas[p] = m.filas[p][:0]
+	}
+	m.mutex.Unlock()
+	select {
+	case <-m.aviso:
+	default:
+	}
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Envia uma mensagem ao cliente sem bloquear quem chama
+// Devolve false se a sessão terminou ou a mensagem foi descartada
+func (s *Servidor) enviar(cli *Cliente, msg protocolo.Mensagem) bool {
+	return s.entregar(cli, msg, false)
+}
+
+// enviarRetrato envia um ATUALIZACAO_JOGO que a próxima atualização pode substituir
+func (s *Servidor) enviarRetrato(cli *Cliente, msg protocolo.Mensagem) bool {
+	return s.entregar(cli, msg, true)
+}
+
+func (s *Servidor) entregar(cli *Cliente, msg protocolo.Mensagem, retrato bool) bool {
+	if !cli.ativa() {
+		return false
+	}
+	switch cli.Mailbox.enfileirar(msg, retrato) {
+	case saidaCoalescida:
+		s.metricas.atualizacoesCoalescidas.Add(1)
+	case saidaDescartada:
+		p := prioridadeDe(msg.Comando)
+		s.metricas.descartesSaida[p].Add(1)
+		if amostraMailboxCheia.permitir() {
+			cli.log().Warn("fila de saída cheia; descartando mensagem", "comando", msg.Comando, "prioridade", nomesPrioridade[p])
+		}
+		return false
+	case saidaEstourada:
+		// Desconexão determinística: o cliente não recebe mais nada desta sessão
+		s.metricas.desconexoesLentas.Add(1)
+		cli.log().Warn("cliente lento demais para o estado do jogo; desconectando", "comando", msg.Comando)
+		cli.cancelar()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,516 @@
+package main
+
+// ===================== BAREMA ITEM 7: PARTIDAS =====================
+// Salas como atores.
+// Cada sala roda em uma goroutine própria que consome uma fila de comandos.
+// Jogadores, mesa, placar e as mãos dos jogadores só são alterados por essa
+// goroutine, então o estado da sala dispensa mutex. Leitores dos clientes,
+// workers de pacotes e a API de administração pedem alterações enviando
+// funções para a fila (executar) ou esperam pela resposta (consultar).
+// Mãos, mesa, placar e prontos são indexados pelo cliente, e não pelo nome,
+// que um LOGIN pode trocar no meio da partida. Quando o último jogador sai, a
+// goroutine termina e a sala é removida do servidor.
+
+import (
+	"fmt"
+	"meujogo/protocolo"
+	"slices"
+	"sync"
+)
+
+// Capacidade da fila de comandos de cada sala
+const capacidadeComandosSala = 64
+
+// BAREMA ITEM 7: PARTIDAS - Estrutura que representa uma sala de jogo
+// Gerencia o estado de uma partida entre dois jogadores. Os campos abaixo de
+// srv só são acessados pela goroutine da sala (ver rodar).
+type Sala struct {
+	ID              string             // Identificador único da sala
+	Jogadores       []*Cliente         // Lista dos jogadores na sala (2 enquanto ninguém sai)
+	Estado          string             // Estado atual: "AGUARDANDO_COMPRA" | "JOGANDO" | "FINALIZADO"
+	CartasNaMesa    map[*Cliente]Carta // Cartas jogadas na jogada atual (jogador -> carta)
+	PontosRodada    map[*Cliente]int   // Pontos de cada jogador na rodada atual
+	PontosPartida   map[*Cliente]int   // Rodadas ganhas por cada jogador na partida
+	NumeroRodada    int                // Número da rodada atual (1, 2, 3...)
+	JogadasNaRodada int                // Quantas jogadas foram feitas na rodada atual (0, 1, 2)
+	Prontos         map[*Cliente]bool  // Quais jogadores já compraram pacotes para esta partida
+	Privada         bool               // BAREMA ITEM 7: PARTIDAS - Criada por desafio entre amigos (fixo após criarSala)
+	regras          *RegrasJogo        // BAREMA ITEM 8: PACOTES - Regras vigentes quando a partida foi formada
+	srv             *Servidor          // Referência para o servidor principal
+
+	// BAREMA ITEM 8: PACOTES - Mão de cada jogador enquanto está na sala. Compras,
+	// concessões do administrador e jogadas chegam como comandos da sala
+	Maos map[*Cliente][]Carta
+
+	comandos  chan func()  // BAREMA ITEM 5: CONCORRÊNCIA - Fila de comandos consumida pela goroutine da sala
+	envio     sync.RWMutex // Impede envios para a fila depois que a sala encerrou
+	encerrada bool         // Protegido por envio
+}
+
+func (s *Servidor) novaSala(id string, j1, j2 *Cliente) *Sala {
+	return &Sala{
+		ID:              id,
+		Jogadores:       []*Cliente{j1, j2},  // Sempre exatamente 2 jogadores
+		Estado:          "AGUARDANDO_COMPRA", // Estado inicial: aguarda compra de cartas
+		CartasNaMesa:    make(map[*Cliente]Carta),
+		PontosRodada:    make(map[*Cliente]int),
+		PontosPartida:   make(map[*Cliente]int),
+		NumeroRodada:    1,
+		JogadasNaRodada: 0,
+		Prontos:         make(map[*Cliente]bool), // Rastreia quem já comprou cartas
+		regras:          s.regras.Load(),         // BAREMA ITEM 8: PACOTES - Regras fixadas para esta partida
+		srv:             s,
+		Maos:            make(map[*Cliente][]Carta),
+		comandos:        make(chan func(), capacidadeComandosSala),
+	}
+}
+
+/* ====================== Ator da sala ====================== */
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Goroutine dona do estado da sala
+func (sala *Sala) rodar() {
+	for cmd := range sala.comandos {
+		cmd()
+		if len(sala.Jogadores) == 0 {
+			sala.encerrar()
+			return
+		}
+	}
+}
+
+// encerrar remove a sala do servidor e executa os comandos que já estavam a
+// caminho; eles encontram a sala vazia e seguem o caminho de "fora da sala"
+func (sala *Sala) encerrar() {
+	sala.srv.salas.Delete(sala.ID)
+	sala.log().Debug("sala encerrada")
+
+	// O bloqueio de escrita só é obtido quando nenhum envio está em curso;
+	// até lá a fila continua sendo consumida para não travar quem envia
+	bloqueada := make(chan struct{})
+	go func() {
+		sala.envio.Lock()
+		sala.encerrada = true
+		sala.envio.Unlock()
+		close(bloqueada)
+	}()
+	for {
+		select {
+		case cmd := <-sala.comandos:
+			cmd()
+		case <-bloqueada:
+			for {
+				select {
+				case cmd := <-sala.comandos:
+					cmd()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// executar entrega um comando à sala sem esperar; devolve false se a sala já
+// encerrou. Não deve ser chamada pela própria goroutine da sala.
+func (sala *Sala) executar(cmd func()) bool {
+	sala.envio.RLock()
+	defer sala.envio.RUnlock()
+	if sala.encerrada {
+		return false
+	}
+	sala.comandos <- cmd
+	return true
+}
+
+// consultar executa o comando na sala e espera que ele termine
+func (sala *Sala) consultar(cmd func()) bool {
+	feito := make(chan struct{})
+	if !sala.executar(func() { cmd(); close(feito) }) {
+		return false
+	}
+	<-feito
+	return true
+}
+
+// estadoAtual consulta o estado da partida ("ENCERRADA" se a sala já terminou)
+func (sala *Sala) estadoAtual() string {
+	estado := "ENCERRADA"
+	sala.consultar(func() { estado = sala.Estado })
+	return estado
+}
+
+// BAREMA ITEM 6: LATÊNCIA - Latência de cada jogador da sala, medida pelo servidor
+// (quem ainda não respondeu a nenhum PING fica de fora)
+func (sala *Sala) latencias() map[string]protocolo.ResumoLatencia {
+	latencias := make(map[string]protocolo.ResumoLatencia)
+	sala.consultar(func() {
+		for _, j := range sala.Jogadores {
+			if r := j.Latencia.Resumo(); r.Amostras > 0 {
+				latencias[j.nome()] = r
+			}
+		}
+	})
+	return latencias
+}
+
+// cartasDe devolve uma cópia da mão do jogador (nil se a sala já encerrou)
+func (sala *Sala) cartasDe(c *Cliente) []Carta {
+	var cartas []Carta
+	sala.consultar(func() { cartas = slices.Clone(sala.Maos[c]) })
+	return cartas
+}
+
+// jaComprou diz se o jogador já comprou cartas para a próxima partida
+func (sala *Sala) jaComprou(c *Cliente) bool {
+	pronto := false
+	sala.consultar(func() { pronto = sala.Prontos[c] })
+	return pronto
+}
+
+/* ====================== Comandos (goroutine da sala) ====================== */
+
+func (sala *Sala) membro(c *Cliente) bool {
+	for _, j := range sala.Jogadores {
+		if j == c {
+			return true
+		}
+	}
+	return false
+}
+
+// BAREMA ITEM 3: API REMOTA - Notifica os jogadores sobre a partida encontrada
+func (sala *Sala) anunciar() {
+	j1, j2 := sala.Jogadores[0], sala.Jogadores[1]
+	d1 := protocolo.DadosPartidaEncontrada{SalaID: sala.ID, OponenteNome: j2.nome()}
+	d2 := protocolo.DadosPartidaEncontrada{SalaID: sala.ID, OponenteNome: j1.nome()}
+	sala.srv.enviar(j1, protocolo.Mensagem{Comando: "PARTIDA_ENCONTRADA", Dados: mustJSON(d1)})
+	sala.srv.enviar(j2, protocolo.Mensagem{Comando: "PARTIDA_ENCONTRADA", Dados: mustJSON(d2)})
+
+	// BAREMA ITEM 8: PACOTES - Informa que devem comprar pacotes para iniciar
+	sala.broadcast(nil, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: sala.regras.mensagem("partidaEncontrada")}),
+	})
+}
+
+// BAREMA ITEM 8: PACOTES - Entrega um pacote comprado por um jogador da sala
+// Se ele saiu enquanto o pacote era montado, a entrega segue fora da sala
+func (sala *Sala) concluirCompra(cli *Cliente, cartas []Carta, regras *RegrasJogo) {
+	if !sala.membro(cli) {
+		sala.srv.entregarForaDaSala(cli, cartas, regras)
+		return
+	}
+	sala.adicionarCartas(cli, cartas)
+	if sala.srv.entregarPacote(cli, cartas, regras) {
+		sala.marcarCompraEIniciarSePossivel(cli)
+	}
+}
+
+func (sala *Sala) adicionarCartas(c *Cliente, cartas []Carta) {
+	sala.Maos[c] = append(sala.Maos[c], cartas...)
+}
+
+// retirarCarta remove da mão do jogador a carta com o ID informado
+func (sala *Sala) retirarCarta(c *Cliente, id string) (Carta, bool) {
+	mao := sala.Maos[c]
+	for i, carta := range mao {
+		if carta.ID == id {
+			sala.Maos[c] = slices.Delete(mao, i, i+1)
+			return carta, true
+		}
+	}
+	return Carta{}, false
+}
+
+func (sala *Sala) marcarCompraEIniciarSePossivel(cli *Cliente) {
+	if sala.Estado == "FINALIZADO" {
+		sala.reiniciarSala()
+	}
+
+	// Marca como "comprou"
+	sala.Prontos[cli] = true
+
+	// Checa se ambos estão prontos
+	if len(sala.Jogadores) < 2 {
+		return
+	}
+	ready1 := sala.Prontos[sala.Jogadores[0]]
+	ready2 := sala.Prontos[sala.Jogadores[1]]
+
+	// Broadcast de pronto
+	sala.broadcast(nil, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: fmt.Sprintf("[SISTEMA] - Jogador \"%s\" está pronto para iniciar", cli.nome())}),
+	})
+
+	// Durante o desligamento nenhuma partida nova começa
+	if ready1 && ready2 && sala.Estado != "JOGANDO" && !sala.srv.encerrando.Load() {
+		sala.iniciarPartida()
+	}
+}
+
+// removerJogador tira o cliente da sala e avisa quem ficou
+func (sala *Sala) removerJogador(cliente *Cliente, aviso string) {
+	if !sala.membro(cliente) {
+		return
+	}
+	var outrosJogadores []*Cliente
+	for _, j := range sala.Jogadores {
+		if j != cliente {
+			outrosJogadores = append(outrosJogadores, j)
+		}
+	}
+	sala.Jogadores = outrosJogadores
+	// Solta, ao final, a referência tomada em criarSala
+	defer cliente.soltar()
+	delete(sala.Maos, cliente) // As cartas não saem da sala
+	// Nenhum mapa guarda o cliente depois que a sala solta a referência
+	delete(sala.Prontos, cliente)
+	delete(sala.CartasNaMesa, cliente)
+	delete(sala.PontosRodada, cliente)
+	delete(sala.PontosPartida, cliente)
+	cliente.sairDaSala(sala)
+	sala.srv.presencaMudou(cliente, protocolo.PresencaOnline)
+
+	// A partida não pode continuar com um jogador só
+	if sala.Estado == "JOGANDO" {
+		sala.reiniciarSala()
+	}
+	if len(outrosJogadores) > 0 && aviso != "" {
+		sala.broadcast(nil, protocolo.Mensagem{
+			Comando: "SISTEMA",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: aviso}),
+		})
+	}
+	sala.log().Info("jogador removido", "jogador", cliente.nome())
+}
+
+// sair atende SAIR_DA_SALA: os dois jogadores deixam a sala e o oponente
+// devolvido, já retido, é recolocado na fila por quem chamou
+func (sala *Sala) sair(cliente *Cliente) *Cliente {
+	if !sala.membro(cliente) {
+		return nil
+	}
+	var oponente *Cliente
+	for _, j := range sala.Jogadores {
+		if j != cliente {
+			oponente = j
+		}
+	}
+	sala.removerJogador(cliente, fmt.Sprintf("[SISTEMA] %s desconectou da partida", cliente.nome()))
+	if oponente != nil {
+		aviso := "[SISTEMA] Seu oponente saiu da partida. Colocando você de volta na fila..."
+		if sala.Privada {
+			aviso = "[SISTEMA] Seu oponente saiu da partida privada. Use /fila para procurar outro oponente."
+		}
+		sala.srv.enviar(oponente, protocolo.Mensagem{
+			Comando: "SISTEMA",
+			Dados:   mustJSON(protocolo.DadosErro{Mensagem: aviso}),
+		})
+		oponente.reter() // Ainda é membro, então a sessão dele não terminou
+		sala.removerJogador(oponente, "")
+	}
+	return oponente
+}
+
+// fechar encerra a sala a pedido do administrador; os jogadores voltam ao
+// lobby sem entrar na fila, senão seriam pareados de novo um com o outro
+func (sala *Sala) fechar() {
+	sala.broadcast(nil, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: "[SISTEMA] A sala foi encerrada por um administrador. Use /fila para procurar um novo oponente."}),
+	})
+	jogadores := sala.Jogadores
+	sala.Jogadores = nil
+	sala.reiniciarSala() // Esvazia mãos, mesa, placar e prontos
+	sala.Estado = "FINALIZADO"
+	for _, j := range jogadores {
+		j.sairDaSala(sala)
+		sala.srv.presencaMudou(j, protocolo.PresencaOnline)
+		j.soltar()
+	}
+}
+
+func (sala *Sala) broadcast(_ *Cliente, msg protocolo.Mensagem) {
+	for _, j := range sala.Jogadores {
+		sala.srv.enviar(j, msg)
+	}
+}
+
+func (sala *Sala) enviarAtualizacaoJogo(mensagem, vencedorJogada, vencedorRodada string) {
+	// Sem vencedor anunciado a atualização é só um retrato e pode ser coalescida
+	retrato := vencedorJogada == "" && vencedorRodada == ""
+	// Envia atualização personalizada para cada jogador
+	for _, jogador := range sala.Jogadores {
+		dados := sala.criarAtualizacaoJogoPersonalizada(mensagem, vencedorJogada, vencedorRodada, jogador)
+		msg := protocolo.Mensagem{
+			Comando: "ATUALIZACAO_JOGO",
+			Dados:   mustJSON(dados),
+		}
+		if retrato {
+			sala.srv.enviarRetrato(jogador, msg)
+		} else {
+			sala.srv.enviar(jogador, msg)
+		}
+	}
+}
+
+// Cria atualização personalizada: cada jogador recebe apenas a própria mão
+func (sala *Sala) criarAtualizacaoJogoPersonalizada(mensagem, vencedorJogada, vencedorRodada string, jogadorAtual *Cliente) protocolo.DadosAtualizacaoJogo {
+	contagem := make(map[string]int)
+	ultima := make(map[string]Carta)
+
+	for _, p := range sala.Jogadores {
+		nome := p.nome()
+		contagem[nome] = len(sala.Maos[p])
+		if c, ok := sala.CartasNaMesa[p]; ok {
+			// Mostra a carta jogada por ambos os jogadores, sem esconder o poder
+			ultima[nome] = c
+		}
+	}
+
+	// A mão do destinatário permite ao cliente reconciliar sua cópia local
+	mao := sala.Maos[jogadorAtual]
+	if mao == nil {
+		mao = []Carta{}
+	}
+
+	return protocolo.DadosAtualizacaoJogo{
+		MensagemDoTurno: mensagem,
+		ContagemCartas:  contagem,
+		UltimaJogada:    ultima,
+		VencedorJogada:  vencedorJogada,
+		VencedorRodada:  vencedorRodada,
+		NumeroRodada:    sala.NumeroRodada,
+		PontosRodada:    porNome(sala.PontosRodada),
+		PontosPartida:   porNome(sala.PontosPartida),
+		MinhaMao:        mao,
+	}
+}
+
+func (sala *Sala) iniciarPartida() {
+	sala.Estado = "JOGANDO"
+	sala.CartasNaMesa = make(map[*Cliente]Carta)
+	sala.JogadasNaRodada = 0
+	sala.Prontos = make(map[*Cliente]bool)
+
+	if sala.PontosPartida == nil {
+		sala.PontosPartida = make(map[*Cliente]int)
+		for _, p := range sala.Jogadores {
+			sala.PontosPartida[p] = 0
+		}
+	}
+	if sala.PontosRodada == nil {
+		sala.PontosRodada = make(map[*Cliente]int)
+	}
+	for _, p := range sala.Jogadores {
+		sala.PontosRodada[p] = 0
+	}
+
+	sala.enviarAtualizacaoJogo(sala.regras.mensagem("partidaIniciada"), "", "")
+}
+
+func (sala *Sala) processarJogada(jogador *Cliente, cartaID string) {
+	if sala.Estado != "JOGANDO" || !sala.membro(jogador) {
+		return
+	}
+
+	nome := jogador.nome()
+	if _, ok := sala.CartasNaMesa[jogador]; ok {
+		sala.enviarAtualizacaoJogo("Você já jogou. Aguarde o oponente.", "", "")
+		return
+	}
+
+	// Move a carta da mão para a mesa
+	carta, ok := sala.retirarCarta(jogador, cartaID)
+	if !ok {
+		sala.enviarAtualizacaoJogo(fmt.Sprintf("[SISTEMA] %s jogou uma carta inválida.", nome), "", "")
+		return
+	}
+	sala.CartasNaMesa[jogador] = carta
+	sala.JogadasNaRodada++
+
+	if len(sala.CartasNaMesa) < 2 {
+		sala.enviarAtualizacaoJogo("Aguardando o oponente...", "", "")
+		return
+	}
+
+	// Ambos jogaram: resolve a jogada
+	p1 := sala.Jogadores[0]
+	p2 := sala.Jogadores[1]
+	c1 := sala.CartasNaMesa[p1]
+	c2 := sala.CartasNaMesa[p2]
+
+	vencedorJogada := "EMPATE"
+	var vencedor *Cliente
+
+	resultado := sala.regras.compararCartas(c1, c2)
+	if resultado > 0 {
+		vencedorJogada = p1.nome()
+		vencedor = p1
+	} else if resultado < 0 {
+		vencedorJogada = p2.nome()
+		vencedor = p2
+	}
+
+	if vencedor != nil {
+		sala.PontosRodada[vencedor]++
+	}
+	// As cartas são descartadas e não retornam ao inventário.
+
+	// Limpa a mesa para a próxima jogada
+	sala.CartasNaMesa = make(map[*Cliente]Carta)
+
+	sala.enviarAtualizacaoJogo(fmt.Sprintf("Vencedor da jogada: %s", vencedorJogada), vencedorJogada, "")
+
+	// Checa fim da partida por 0 cartas (agora acontecerá após 5 rodadas)
+	if len(sala.Maos[p1]) == 0 {
+		vencedorFinal := "EMPATE"
+		p1Pontos := sala.PontosRodada[p1]
+		p2Pontos := sala.PontosRodada[p2]
+		if p1Pontos > p2Pontos {
+			vencedorFinal = p1.nome()
+		} else if p2Pontos > p1Pontos {
+			vencedorFinal = p2.nome()
+		}
+		sala.finalizarPartida(vencedorFinal)
+		return
+	}
+
+	sala.enviarAtualizacaoJogo(sala.regras.mensagem("proximaJogada"), "", "")
+}
+
+func (sala *Sala) finalizarPartida(vencedor string) {
+	sala.Estado = "FINALIZADO"
+	regras := sala.regras
+	sala.srv.metricas.partidasConcluidas.Add(1)
+
+	sala.broadcast(nil, protocolo.Mensagem{Comando: "FIM_DE_JOGO", Dados: mustJSON(protocolo.DadosFimDeJogo{VencedorNome: vencedor})})
+
+	sala.reiniciarSala()
+	sala.broadcast(nil, protocolo.Mensagem{
+		Comando: "SISTEMA",
+		Dados:   mustJSON(protocolo.DadosErro{Mensagem: regras.mensagem("partidaFinalizada")}),
+	})
+}
+
+func (sala *Sala) reiniciarSala() {
+	sala.Estado = "AGUARDANDO_COMPRA"
+	sala.CartasNaMesa = make(map[*Cliente]Carta)
+	sala.PontosRodada = make(map[*Cliente]int)
+	sala.PontosPartida = make(map[*Cliente]int)
+	sala.NumeroRodada = 1
+	sala.JogadasNaRodada = 0
+	sala.Prontos = make(map[*Cliente]bool)
+	sala.regras = sala.srv.regras.Load() // BAREMA ITEM 8: PACOTES - A próxima partida usa as regras vigentes
+	sala.Maos = make(map[*Cliente][]Carta)
+}
+
+// porNome converte um mapa da sala para o formato do protocolo (nome -> valor)
+func porNome[V any](m map[*Cliente]V) map[string]V {
+	convertido := make(map[string]V, len(m))
+	for j, v := range m {
+		convertido[j.nome()] = v
+	}
+	return convertido
+}
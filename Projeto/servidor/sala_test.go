@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meujogo/clientesdk"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// BAREMA ITEM 9: TESTES - Estresse das mãos sob concorrência
+// Em partida as mãos pertencem às salas; este teste mistura tudo o que mexe
+// nelas ao mesmo tempo: partidas completas, jogadores que compram e abandonam
+// a sala antes da entrega, pedidos de VER_CARTAS e a API de administração
+// listando clientes e concedendo cartas. Serve para rodar com o detector de corridas:
+//
+//	go test -race -run Concorrencia ./servidor
+func TestMaosSobConcorrencia(t *testing.T) {
+	const (
+		pares        = 10 // Bots que jogam partidas completas
+		desistentes  = 4  // Bots que compram e saem da sala em seguida
+		rodadas      = 5  // Salas abandonadas por cada desistente
+		alvoPartidas = 30 // Partidas concluídas antes de encerrar o teste
+	)
+	s, transporte := iniciarServidorMemoria(t)
+	parar := make(chan struct{})
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		erros []error
+	)
+	falhar := func(err error) {
+		mutex.Lock()
+		erros = append(erros, err)
+		mutex.Unlock()
+	}
+
+	// Jogadores: partidas completas até o sinal de parar
+	jogadores := make([]*clientesdk.Cliente, 0, 2*pares)
+	for i := 0; i < 2*pares; i++ {
+		nome := fmt.Sprintf("jogador%02d", i)
+		cliente := conectarMemoria(t, transporte, nome)
+		jogadores = append(jogadores, cliente)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jogarPartidas(cliente, nome, 1<<30, parar); err != nil {
+				falhar(err)
+			}
+		}()
+	}
+
+	// Desistentes: compram um pacote e saem antes (ou logo depois) da entrega
+	todos := append([]*clientesdk.Cliente(nil), jogadores...)
+	var wgDesistentes sync.WaitGroup
+	for i := 0; i < desistentes; i++ {
+		nome := fmt.Sprintf("desistente%02d", i)
+		cliente := conectarMemoria(t, transporte, nome)
+		todos = append(todos, cliente)
+		wgDesistentes.Add(1)
+		go func() {
+			defer wgDesistentes.Done()
+			for r := 0; r < rodadas; r++ {
+				if err := cliente.EntrarNaFila(); err != nil {
+					falhar(err)
+					return
+				}
+				if !esperarEvento(cliente, "PARTIDA_ENCONTRADA", 30*time.Second) {
+					falhar(fmt.Errorf("%s: sem partida na rodada %d", nome, r))
+					return
+				}
+				cliente.ComprarPacote(1)
+				cliente.VerCartas()
+				cliente.SairDaSala()
+			}
+		}()
+	}
+
+	// Curioso: pede VER_CARTAS aos montes, consultando as salas pelo leitor
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			for _, cliente := range jogadores {
+				select {
+				case <-parar:
+					return
+				default:
+				}
+				cliente.VerCartas()
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	// Administrador: lista clientes e salas e concede cartas aos desistentes
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-parar:
+				return
+			default:
+			}
+			s.adminListarClientes(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/clientes", nil))
+			s.adminListarSalas(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/salas", nil))
+
+			nome := fmt.Sprintf("desistente%02d", i%desistentes)
+			req := httptest.NewRequest(http.MethodPost, "/api/clientes/"+nome+"/cartas", strings.NewReader(`{"quantidade": 2}`))
+			req.SetPathValue("nome", nome)
+			resposta := httptest.NewRecorder()
+			s.adminConcederCartas(resposta, req)
+			switch resposta.Code {
+			case http.StatusOK, http.StatusNotFound, http.StatusConflict:
+			default:
+				falhar(fmt.Errorf("concessão para %s: %d %s", nome, resposta.Code, resposta.Body))
+				return
+			}
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+
+	wgDesistentes.Wait()
+	if !esperarAte(prazoPartidasTeste, func() bool { return s.metricas.partidasConcluidas.Load() >= alvoPartidas }) {
+		falhar(fmt.Errorf("só %d de %d partidas concluídas no prazo", s.metricas.partidasConcluidas.Load(), alvoPartidas))
+	}
+	close(parar)
+	wg.Wait()
+	if err := errors.Join(erros...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Com todos desconectados, nenhuma sala (e nenhuma mão) pode sobrar
+	for _, cliente := range todos {
+		cliente.Fechar()
+	}
+	salasAbertas := func() int {
+		n := 0
+		s.salas.Range(func(_, _ any) bool { n++; return true })
+		return n
+	}
+	if !esperarAte(5*time.Second, func() bool { return salasAbertas() == 0 }) {
+		t.Fatalf("%d salas ainda abertas depois que os jogadores saíram", salasAbertas())
+	}
+}
+
+// BAREMA ITEM 8: PACOTES - Compras e concessões no lobby vão para o
+// inventário, que vira a mão do jogador quando uma sala é formada
+func TestCompraNoLobby(t *testing.T) {
+	s, transporte := iniciarServidorMemoria(t)
+	ana := conectarMemoria(t, transporte, "ana")
+	if err := ana.ComprarPacote(1); err != nil {
+		t.Fatal(err)
+	}
+	if !esperarEvento(ana, "PACOTE_RESULTADO", 5*time.Second) {
+		t.Fatal("compra no lobby sem PACOTE_RESULTADO")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/clientes/ana/cartas", strings.NewReader(`{"quantidade": 2}`))
+	req.SetPathValue("nome", "ana")
+	resposta := httptest.NewRecorder()
+	s.adminConcederCartas(resposta, req)
+	if resposta.Code != http.StatusOK {
+		t.Fatalf("concessão no lobby: %d %s", resposta.Code, resposta.Body)
+	}
+
+	esperado := s.regras.Load().CartasPorPacote + 2
+	cliente := s.buscarClientePorNome("ana")
+	if cliente == nil {
+		t.Fatal("ana não encontrada no servidor")
+	}
+	defer cliente.soltar()
+	if n := len(cliente.cartas()); n != esperado {
+		t.Fatalf("inventário com %d cartas, esperado %d", n, esperado)
+	}
+
+	bia := conectarMemoria(t, transporte, "bia")
+	ana.EntrarNaFila()
+	bia.EntrarNaFila()
+	if !esperarEvento(ana, "PARTIDA_ENCONTRADA", 5*time.Second) {
+		t.Fatal("sem partida para ana")
+	}
+	sala := cliente.salaAtual()
+	if sala == nil {
+		t.Fatal("ana fora de uma sala depois de PARTIDA_ENCONTRADA")
+	}
+	if n := len(sala.cartasDe(cliente)); n != esperado || len(cliente.cartas()) != 0 {
+		t.Fatalf("mão na sala com %d cartas e %d no inventário, esperado %d e 0", n, len(cliente.cartas()), esperado)
+	}
+}
+
+// BAREMA ITEM 7: PARTIDAS - Trocar de nome no meio da partida não separa o
+// estado do jogador: a compra feita antes do LOGIN ainda conta para começar
+func TestTrocaDeNomeNaSala(t *testing.T) {
+	_, transporte := iniciarServidorMemoria(t)
+	ana := conectarMemoria(t, transporte, "ana")
+	bia := conectarMemoria(t, transporte, "bia")
+	ana.EntrarNaFila()
+	bia.EntrarNaFila()
+	for _, c := range []*clientesdk.Cliente{ana, bia} {
+		if !esperarEvento(c, "PARTIDA_ENCONTRADA", 5*time.Second) {
+			t.Fatal("jogadores não foram pareados")
+		}
+	}
+
+	ana.ComprarPacote(1)
+	if !esperarEvento(ana, "PACOTE_RESULTADO", 5*time.Second) {
+		t.Fatal("compra de ana sem PACOTE_RESULTADO")
+	}
+	// VER_CARTAS passa pelo mesmo leitor: a resposta garante que o LOGIN já valeu
+	ana.Login("ana2")
+	ana.VerCartas()
+	if !esperarEvento(ana, "CARTAS_DETALHADAS", 5*time.Second) {
+		t.Fatal("sem CARTAS_DETALHADAS depois do LOGIN")
+	}
+
+	bia.ComprarPacote(1)
+	if !esperarEvento(bia, "ATUALIZACAO_JOGO", 5*time.Second) {
+		t.Fatal("a partida não começou depois da troca de nome")
+	}
+}
+
+// esperarEvento descarta eventos até chegar o comando pedido
+func esperarEvento(cliente *clientesdk.Cliente, comando string, prazo time.Duration) bool {
+	limite := time.NewTimer(prazo)
+	defer limite.Stop()
+	for {
+		select {
+		case ev, ok := <-cliente.Eventos():
+			if !ok || ev.Comando == clientesdk.EventoDesconectado {
+				return false
+			}
+			if ev.Comando == comando {
+				return true
+			}
+		case <-limite.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+// ===================== BAREMA ITEM 5: CONCORRÊNCIA =====================
+// Ciclo de vida das sessões.
+// Cada conexão aceita abre uma sessão com ID único e um context.Context
+// próprio. O Cliente que a representa vem do clientePool, mas só volta para
+// ele quando ninguém mais o referencia: a própria conexão, a sala e a fila de
+// espera em que ele está, pedidos de pacote ainda na fila e consultas da API
+// de administração retêm o cliente e o soltam ao terminar. Quem guarda só o
+// ponteiro e o ID da sessão (canais, rede de amigos, lista de clientes) usa
+// reterSessao antes de tocar no objeto. O último a soltar limpa o estado,
+// esvazia a mailbox e o devolve ao pool, então um objeto reciclado nunca
+// recebe cartas ou mensagens destinadas à sessão anterior.
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Gerador dos IDs de sessão
+var proximaSessao atomic.Uint64
+
+// iniciarSessao prepara um cliente recém-saído do pool para uma nova conexão
+// O contexto da sessão deriva do contexto do servidor, então o desligamento
+// também a encerra. A sessão nasce com uma referência, pertencente a
+// handleConnection
+func (c *Cliente) iniciarSessao(pai context.Context) {
+	c.Sessao = proximaSessao.Add(1)
+	c.ctx, c.cancelar = context.WithCancel(pai)
+	c.refs.Store(1)
+}
+
+// reter registra mais uma referência; só pode ser chamada por quem já retém o cliente
+func (c *Cliente) reter() {
+	c.refs.Add(1)
+}
+
+// tentarReter retém o cliente se ele ainda não foi devolvido ao pool
+func (c *Cliente) tentarReter() bool {
+	for {
+		n := c.refs.Load()
+		if n == 0 {
+			return false
+		}
+		if c.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// reterSessao retém o cliente se ele ainda pertence à sessão indicada; o
+// objeto pode ter sido reciclado para outra conexão depois de guardado
+func (c *Cliente) reterSessao(sessao uint64) bool {
+	if !c.tentarReter() {
+		return false // Já devolvido ao pool
+	}
+	if c.Sessao != sessao {
+		c.soltar()
+		return false
+	}
+	return true
+}
+
+// soltar libera uma referência; a última devolve o cliente ao pool
+func (c *Cliente) soltar() {
+	if c.refs.Add(-1) == 0 {
+		c.reciclar()
+	}
+}
+
+// reciclar limpa o estado da sessão encerrada e devolve o objeto ao pool
+func (c *Cliente) reciclar() {
+	// Mensagens que não chegaram a ser escritas pertencem à sessão encerrada
+	c.Mailbox.esvaziar()
+
+	c.mutex.Lock()
+	c.Inventario = c.Inventario[:0] // Limpa o slice mantendo capacidade
+	c.Sala = nil
+	c.Nome = ""
+	c.mutex.Unlock()
+	c.Conn = nil
+	c.Encoder = nil
+	c.Decoder = nil
+	c.Sessao = 0
+	c.ctx, c.cancelar = nil, nil
+	c.Latencia.Zerar()
+	c.UltimoPong.Store(0)
+	c.UltimaAcao.Store(0)
+	c.atrasado.Store(false)
+	c.pendentes.Store(0)
+	c.Certificado = ""
+	c.limitador = limitadorCliente{}
+	c.leitor = nil
+	c.violacoes = 0
+	c.canais = c.canais[:0]
+	c.bloqueados.definir(nil)
+	c.recentes.limpar()
+
+	clientePool.Put(c) // Devolve objeto para o pool
+}
+
+// ativa diz se a sessão ainda não foi encerrada
+func (c *Cliente) ativa() bool {
+	return c.ctx.Err() == nil
+}
+
+// esperar pausa pela duração indicada ou até o contexto ser cancelado,
+// dizendo se a pausa chegou ao fim
+func esperar(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// paraCadaCliente chama f para cada cliente conectado, retendo-o durante a chamada
+func (s *Servidor) paraCadaCliente(f func(c *Cliente)) {
+	s.clientes.Range(func(chave, v any) bool {
+		c := v.(*Cliente)
+		// O objeto pode ter sido reciclado para outra sessão depois do Range lê-lo
+		if !c.reterSessao(chave.(uint64)) {
+			return true
+		}
+		defer c.soltar()
+		f(c)
+		return true
+	})
+}
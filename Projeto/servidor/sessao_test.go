@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"meujogo/clientesdk"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+// BAREMA ITEM 9: TESTES - Sessões encerradas não deixam goroutines para trás
+// Cada rodada abre sessões que passam por tudo o que cria goroutines ou
+// timers: clienteWriter e pingManager, partidas, pedidos de pacote ainda na
+// fila, expulsão pelo administrador, JSON inválido (despedida com espera) e
+// conexões que caem sem QUIT. Quando todas terminam, o número de goroutines
+// tem de voltar ao de antes e nenhum cliente pode continuar registrado.
+func TestSessoesNaoVazamGoroutines(t *testing.T) {
+	const rodadas, porGrupo = 3, 6
+	s, transporte := iniciarServidorMemoria(t)
+	antes := runtime.NumGoroutine()
+
+	for rodada := 0; rodada < rodadas; rodada++ {
+		var clientes []*clientesdk.Cliente
+		nome := func(grupo string, i int) string { return fmt.Sprintf("%s%d-%d", grupo, rodada, i) }
+
+		// Saem com QUIT logo depois do LOGIN
+		for i := 0; i < porGrupo; i++ {
+			conectarMemoria(t, transporte, nome("breve", i)).Fechar()
+		}
+		// Entram em partidas e saem com o pacote ainda sendo montado
+		for i := 0; i < porGrupo; i++ {
+			c := conectarMemoria(t, transporte, nome("comprador", i))
+			c.EntrarNaFila()
+			clientes = append(clientes, c)
+		}
+		for _, c := range clientes {
+			if esperarEvento(c, "PARTIDA_ENCONTRADA", 5*time.Second) {
+				c.ComprarPacote(1)
+			}
+			c.Fechar()
+		}
+		// Ficam na fila até um administrador expulsá-los
+		for i := 0; i < porGrupo; i++ {
+			n := nome("expulso", i)
+			conectarMemoria(t, transporte, n).EntrarNaFila()
+			req := httptest.NewRequest(http.MethodPost, "/api/clientes/"+n+"/expulsar", nil)
+			req.SetPathValue("nome", n)
+			resposta := httptest.NewRecorder()
+			esperarAte(2*time.Second, func() bool {
+				resposta = httptest.NewRecorder()
+				s.adminExpulsar(resposta, req)
+				return resposta.Code == http.StatusOK
+			})
+			if resposta.Code != http.StatusOK {
+				t.Fatalf("expulsar %s: %d %s", n, resposta.Code, resposta.Body)
+			}
+		}
+		// Enviam JSON inválido ou só derrubam a conexão, sem QUIT
+		for i := 0; i < porGrupo; i++ {
+			conn, err := transporte.Discar()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i%2 == 0 {
+				fmt.Fprintf(conn, `{"comando":"LOGIN","dados":{"nome":%q}}`+"\n", nome("queda", i))
+				conn.Close()
+			} else {
+				go conn.Write([]byte("{isto não é json}\n")) // net.Pipe bloqueia até o servidor ler
+				time.AfterFunc(time.Second, func() { conn.Close() })
+			}
+		}
+	}
+
+	if !esperarAte(10*time.Second, func() bool { return s.metricas.conexoesAtivas.Load() == 0 }) {
+		t.Fatalf("%d conexões ainda ativas", s.metricas.conexoesAtivas.Load())
+	}
+	registrados := 0
+	s.clientes.Range(func(_, _ any) bool { registrados++; return true })
+	if registrados != 0 {
+		t.Fatalf("%d clientes ainda registrados depois que todas as sessões terminaram", registrados)
+	}
+	if !esperarAte(5*time.Second, func() bool { return runtime.NumGoroutine() <= antes }) {
+		var pilhas bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&pilhas, 1)
+		t.Fatalf("goroutines: %d antes, %d depois\n%s", antes, runtime.NumGoroutine(), pilhas.String())
+	}
+}
+
+// BAREMA ITEM 9: TESTES - A expulsão alcança todas as sessões do nome
+// Um nome verificado pode estar conectado em dois lugares; expulsá-lo
+// derruba as duas sessões e não mexe nas de outros nomes.
+func TestExpulsarTodasAsSessoes(t *testing.T) {
+	s, transporte := iniciarServidorMemoria(t)
+	logadas := func(nome string, n int) bool {
+		return esperarAte(5*time.Second, func() bool {
+			s.rede.mutex.Lock()
+			defer s.rede.mutex.Unlock()
+			_, anonima := s.rede.anonimos[nome]
+			return len(s.rede.sessoes[nome]) == n || (n == 1 && anonima)
+		})
+	}
+	// A primeira sessão registra o nome antes de a segunda entrar com a senha
+	var anas []*clientesdk.Cliente
+	for i := 1; i <= 2; i++ {
+		anas = append(anas, conectarConta(t, transporte, "ana", "senha-da-ana"))
+		if !logadas("ana", i) {
+			t.Fatalf("sessão %d da ana não fez LOGIN", i)
+		}
+	}
+	bia := conectarMemoria(t, transporte, "bia")
+	if !logadas("bia", 1) {
+		t.Fatal("bia não fez LOGIN")
+	}
+
+	resposta := chamarAdmin(s.adminExpulsar, http.MethodPost, "ana", "")
+	var corpo struct{ Desconectados int }
+	json.NewDecoder(resposta.Body).Decode(&corpo)
+	if resposta.Code != http.StatusOK || corpo.Desconectados != 2 {
+		t.Fatalf("expulsar ana: %d, %d sessões desconectadas, esperado 2", resposta.Code, corpo.Desconectados)
+	}
+	for _, ana := range anas {
+		esperarAviso(t, ana, "desconectado por um administrador")
+	}
+	if !esperarAte(5*time.Second, func() bool { return s.metricas.conexoesAtivas.Load() == 1 }) {
+		t.Fatalf("%d conexões ativas depois da expulsão, esperada só a da bia", s.metricas.conexoesAtivas.Load())
+	}
+	if bia.VerCartas() != nil || !esperarEvento(bia, "SISTEMA", 5*time.Second) {
+		t.Fatal("a sessão da bia caiu junto com as da ana")
+	}
+}
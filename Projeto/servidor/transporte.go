@@ -0,0 +1,228 @@
+package main
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Transportes de conexões de jogo.
+// O servidor não sabe de onde vêm as conexões: cada origem (TCP, TLS,
+// WebSocket ou pipes em memória) implementa Transporte e Servidor.servir
+// aceita dela como de qualquer outra. Tudo o que é específico da origem
+// (opções de TCP, handshake TLS) fica em Preparar, que roda na goroutine da
+// própria conexão para não atrasar o loop de aceitação. O transporte em
+// memória permite rodar o servidor inteiro dentro do processo, sem sockets.
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Origem de conexões de jogo
+type Transporte interface {
+	// Aceitar bloqueia até a próxima conexão; depois de Fechar devolve net.ErrClosed
+	Aceitar() (net.Conn, error)
+	// Preparar ajusta a conexão antes do uso e devolve o nome do certificado
+	// do cliente, se houver. Um erro descarta a conexão
+	Preparar(conn net.Conn) (certificado string, err error)
+	// Fechar para de aceitar conexões; as já aceitas não são afetadas
+	Fechar() error
+	// Endereco descreve onde o transporte atende (para logs)
+	Endereco() string
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Passa a aceitar conexões do transporte
+// O transporte fica registrado para o desligamento fechá-lo
+func (s *Servidor) servir(t Transporte) {
+	s.transpMutex.Lock()
+	s.transportes = append(s.transportes, t)
+	s.transpMutex.Unlock()
+	go s.aceitarConexoes(t)
+}
+
+// BAREMA ITEM 5: CONCORRÊNCIA - Loop de aceitação de um transporte
+// Retorna quando o transporte é fechado. Todos os transportes compartilham o
+// semáforo de conexões
+func (s *Servidor) aceitarConexoes(t Transporte) {
+	for {
+		conn, err := t.Aceitar()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return // Transporte fechado pelo desligamento
+			}
+			continue
+		}
+
+		// BAREMA ITEM 5: CONCORRÊNCIA - Cada conexão é processada em uma goroutine separada
+		// O semáforo evita sobrecarga com muitas conexões simultâneas
+		s.semaforo <- struct{}{}
+		go func() {
+			defer func() { <-s.semaforo }()
+			s.handleConnection(t, conn)
+		}()
+	}
+}
+
+// fecharTransportes para de aceitar conexões em todos os transportes
+func (s *Servidor) fecharTransportes() {
+	s.transpMutex.Lock()
+	defer s.transpMutex.Unlock()
+	for _, t := range s.transportes {
+		t.Fechar()
+	}
+}
+
+/* ====================== TCP ====================== */
+
+// transporteTCP aceita conexões TCP puras
+type transporteTCP struct {
+	listener net.Listener
+}
+
+func novoTransporteTCP(endereco string) (*transporteTCP, error) {
+	listener, err := net.Listen("tcp", endereco)
+	if err != nil {
+		return nil, err
+	}
+	return &transporteTCP{listener: listener}, nil
+}
+
+func (t *transporteTCP) Aceitar() (net.Conn, error) { return t.listener.Accept() }
+func (t *transporteTCP) Fechar() error              { return t.listener.Close() }
+func (t *transporteTCP) Endereco() string           { return "tcp://" + t.listener.Addr().String() }
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Configurações de TCP para melhor performance
+func (t *transporteTCP) Preparar(conn net.Conn) (string, error) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetKeepAlive(true)                   // Mantém conexão ativa
+		tcpConn.SetKeepAlivePeriod(30 * time.Second) // Verifica conexão a cada 30s
+		tcpConn.SetNoDelay(true)                     // Desabilita algoritmo de Nagle
+	}
+	return "", nil
+}
+
+/* ====================== TLS ====================== */
+
+// transporteTLS envolve outro transporte com TLS (e TLS mútuo, se configurado)
+type transporteTLS struct {
+	base   Transporte
+	config *tls.Config
+}
+
+func novoTransporteTLS(base Transporte, config *tls.Config) *transporteTLS {
+	return &transporteTLS{base: base, config: config}
+}
+
+func (t *transporteTLS) Aceitar() (net.Conn, error) {
+	conn, err := t.base.Aceitar()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Server(conn, t.config), nil
+}
+
+func (t *transporteTLS) Fechar() error    { return t.base.Fechar() }
+func (t *transporteTLS) Endereco() string { return t.base.Endereco() + " (tls)" }
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Conclui o handshake TLS antes de registrar o cliente
+func (t *transporteTLS) Preparar(conn net.Conn) (string, error) {
+	tlsConn := conn.(*tls.Conn)
+	if _, err := t.base.Preparar(tlsConn.NetConn()); err != nil {
+		return "", err
+	}
+	tlsConn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := tlsConn.Handshake(); err != nil {
+		slog.Warn("falha no handshake TLS", "endereco", conn.RemoteAddr().String(), "erro", err)
+		return "", err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	if certs := tlsConn.ConnectionState().VerifiedChains; len(certs) > 0 {
+		return certs[0][0].Subject.CommonName, nil
+	}
+	return "", nil
+}
+
+/* ====================== Fila de conexões ====================== */
+
+// filaConexoes entrega a Aceitar conexões produzidas em outras goroutines
+// (upgrades do gateway WebSocket, pipes em memória)
+type filaConexoes struct {
+	conexoes chan net.Conn
+	fechada  chan struct{}
+	fechar   sync.Once
+}
+
+func novaFilaConexoes() filaConexoes {
+	return filaConexoes{conexoes: make(chan net.Conn), fechada: make(chan struct{})}
+}
+
+// entregar passa a conexão a Aceitar; com o transporte fechado devolve net.ErrClosed
+func (f *filaConexoes) entregar(conn net.Conn) error {
+	select {
+	case f.conexoes <- conn:
+		return nil
+	case <-f.fechada:
+		return net.ErrClosed
+	}
+}
+
+func (f *filaConexoes) Aceitar() (net.Conn, error) {
+	select {
+	case conn := <-f.conexoes:
+		return conn, nil
+	case <-f.fechada:
+		return nil, net.ErrClosed
+	}
+}
+
+func (f *filaConexoes) encerrar() {
+	f.fechar.Do(func() { close(f.fechada) })
+}
+
+/* ====================== Memória ====================== */
+
+// BAREMA ITEM 9: TESTES - Transporte em memória
+// Cada Discar cria um net.Pipe: a ponta do servidor sai em Aceitar e a do
+// cliente é devolvida a quem discou. Permite simular partidas inteiras dentro
+// do processo, com centenas de clientes, sem portas nem rede
+type transporteMemoria struct {
+	filaConexoes
+	proximo atomic.Uint64
+}
+
+func novoTransporteMemoria() *transporteMemoria {
+	return &transporteMemoria{filaConexoes: novaFilaConexoes()}
+}
+
+// Discar conecta um novo cliente ao servidor; bloqueia até o servidor aceitar
+func (t *transporteMemoria) Discar() (net.Conn, error) {
+	servidor, cliente := net.Pipe()
+	// Um endereço distinto por conexão, para o limite por IP tratar cada
+	// cliente simulado como uma máquina diferente
+	endereco := enderecoMemoria(fmt.Sprintf("memoria-%d", t.proximo.Add(1)))
+	if err := t.entregar(&conexaoMemoria{Conn: servidor, remoto: endereco}); err != nil {
+		servidor.Close()
+		cliente.Close()
+		return nil, err
+	}
+	return cliente, nil
+}
+
+func (t *transporteMemoria) Preparar(net.Conn) (string, error) { return "", nil }
+func (t *transporteMemoria) Fechar() error                     { t.encerrar(); return nil }
+func (t *transporteMemoria) Endereco() string                  { return "memoria" }
+
+type enderecoMemoria string
+
+func (e enderecoMemoria) Network() string { return "memoria" }
+func (e enderecoMemoria) String() string  { return string(e) }
+
+// conexaoMemoria é a ponta do servidor de um net.Pipe com endereço remoto próprio
+type conexaoMemoria struct {
+	net.Conn
+	remoto net.Addr
+}
+
+func (c *conexaoMemoria) RemoteAddr() net.Addr { return c.remoto }
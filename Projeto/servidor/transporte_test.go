@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"meujogo/clientesdk"
+	"meujogo/protocolo"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// BAREMA ITEM 9: TESTES - Partidas completas sobre o transporte em memória
+// O servidor inteiro roda dentro do processo de teste: os clientes do SDK
+// discam para o transporteMemoria (net.Pipe), sem portas nem rede.
+
+// Prazo para um bot concluir todas as suas partidas
+const prazoPartidasTeste = 60 * time.Second
+
+// iniciarServidorMemoria sobe um servidor sem arquivos, portas nem limites de
+// taxa apertados, atendendo só o transporte em memória. O desligamento roda
+// no fim do teste, depois que os clientes registrados em seguida fecharam
+func iniciarServidorMemoria(t testing.TB) (*Servidor, *transporteMemoria) {
+	t.Helper()
+	cfg := configPadrao()
+	cfg.ArquivoEstado, cfg.ArquivoAmigos, cfg.ArquivoModeracao = "", "", ""
+	cfg.PackWorkers = 8
+	cfg.Limites = "*=1000:1000" // Os bots jogam mais rápido que um humano
+	cfg.PrazoDesligamento = time.Second
+	if err := configurarLog("texto", "error"); err != nil {
+		t.Fatal(err)
+	}
+	regras, err := lerRegras("", cfg.PackSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := novoServidor(cfg, regras)
+	transporte := novoTransporteMemoria()
+	s.servir(transporte)
+	t.Cleanup(s.desligar)
+	return s, transporte
+}
+
+// conectarMemoria conecta um cliente do SDK pelo transporte em memória e faz o LOGIN
+func conectarMemoria(t testing.TB, transporte *transporteMemoria, nome string) *clientesdk.Cliente {
+	t.Helper()
+	return conectarConta(t, transporte, nome, "")
+}
+
+// conectarConta é conectarMemoria com a senha do nome no LOGIN
+func conectarConta(t testing.TB, transporte *transporteMemoria, nome, senha string) *clientesdk.Cliente {
+	t.Helper()
+	cliente, err := clientesdk.Conectar(context.Background(), clientesdk.Opcoes{
+		Nome:           nome,
+		Senha:          senha,
+		TamanhoEventos: 256,
+		Discar:         func(context.Context) (net.Conn, error) { return transporte.Discar() },
+	})
+	if err != nil {
+		t.Fatalf("conectar %s: %v", nome, err)
+	}
+	t.Cleanup(func() { cliente.Fechar() })
+	return cliente
+}
+
+// cartasNoEstoque soma as cartas de todos os shards
+func (s *Servidor) cartasNoEstoque() int {
+	total := 0
+	for _, shard := range s.shardedEstoque {
+		shard.mutex.Lock()
+		for _, cartas := range shard.estoque {
+			total += len(cartas)
+		}
+		shard.mutex.Unlock()
+	}
+	return total
+}
+
+// resultadoBot é o que um bot viu ao longo das partidas
+type resultadoBot struct {
+	oponente   string
+	vencedores []string // VencedorNome de cada FIM_DE_JOGO, em ordem
+}
+
+// jogarPartidas conduz um bot já logado por n partidas: entra na fila, compra
+// um pacote a cada partida e joga as cartas na ordem da mão. Uma jogada só é
+// feita depois que a anterior aparece fora da mão, para que retratos antigos
+// não provoquem jogadas repetidas. Se o oponente abandona a sala, o servidor
+// recoloca o bot na fila e ele recomeça com o próximo. Fechar parar encerra
+// o bot antes das n partidas, sem erro
+func jogarPartidas(cliente *clientesdk.Cliente, nome string, n int, parar <-chan struct{}) (resultadoBot, error) {
+	var r resultadoBot
+	if err := cliente.EntrarNaFila(); err != nil {
+		return r, err
+	}
+	prazo := time.NewTimer(prazoPartidasTeste)
+	defer prazo.Stop()
+	pendente := "" // Carta enviada que o servidor ainda não tirou da mão
+	for len(r.vencedores) < n {
+		var ev clientesdk.Evento
+		var ok bool
+		select {
+		case ev, ok = <-cliente.Eventos():
+			if !ok || ev.Comando == clientesdk.EventoDesconectado {
+				return r, fmt.Errorf("%s: conexão encerrada após %d partidas", nome, len(r.vencedores))
+			}
+		case <-prazo.C:
+			return r, fmt.Errorf("%s: prazo esgotado após %d partidas", nome, len(r.vencedores))
+		case <-parar:
+			return r, nil
+		}
+
+		switch d := ev.Dados.(type) {
+		case protocolo.DadosPartidaEncontrada:
+			r.oponente = d.OponenteNome
+			pendente = ""
+			if err := cliente.ComprarPacote(1); err != nil {
+				return r, err
+			}
+		case protocolo.DadosAtualizacaoJogo:
+			if pendente != "" {
+				if contemCarta(d.MinhaMao, pendente) {
+					continue // Retrato anterior à jogada
+				}
+				pendente = ""
+			}
+			if _, jogou := d.UltimaJogada[nome]; jogou || len(d.MinhaMao) == 0 {
+				continue
+			}
+			pendente = d.MinhaMao[0].ID
+			if err := cliente.JogarCarta(pendente); err != nil {
+				return r, err
+			}
+		case protocolo.DadosFimDeJogo:
+			r.vencedores = append(r.vencedores, d.VencedorNome)
+			pendente = ""
+			if len(r.vencedores) < n {
+				if err := cliente.ComprarPacote(1); err != nil {
+					return r, err
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+func contemCarta(mao []protocolo.Carta, id string) bool {
+	for _, c := range mao {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// jogarEmParalelo conecta 2*pares bots e faz cada um jogar n partidas
+func jogarEmParalelo(t *testing.T, transporte *transporteMemoria, pares, n int) map[string]resultadoBot {
+	t.Helper()
+	var (
+		wg         sync.WaitGroup
+		mutex      sync.Mutex
+		resultados = make(map[string]resultadoBot)
+		erros      []error
+	)
+	for i := 0; i < 2*pares; i++ {
+		nome := fmt.Sprintf("bot%03d", i)
+		cliente := conectarMemoria(t, transporte, nome)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := jogarPartidas(cliente, nome, n, nil)
+			mutex.Lock()
+			defer mutex.Unlock()
+			resultados[nome] = r
+			if err != nil {
+				erros = append(erros, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(erros...); err != nil {
+		t.Fatal(err)
+	}
+	return resultados
+}
+
+func TestPartidasCompletasEmMemoria(t *testing.T) {
+	const pares, partidas = 50, 3
+	s, transporte := iniciarServidorMemoria(t)
+	estoqueInicial := s.cartasNoEstoque()
+
+	resultados := jogarEmParalelo(t, transporte, pares, partidas)
+
+	// Os dois lados de cada sala viram os mesmos vencedores, na mesma ordem
+	for nome, r := range resultados {
+		oponente, ok := resultados[r.oponente]
+		if !ok || oponente.oponente != nome {
+			t.Fatalf("%s pareado com %q, que foi pareado com %q", nome, r.oponente, oponente.oponente)
+		}
+		if fmt.Sprint(r.vencedores) != fmt.Sprint(oponente.vencedores) {
+			t.Errorf("%s viu %v e %s viu %v", nome, r.vencedores, r.oponente, oponente.vencedores)
+		}
+		for _, v := range r.vencedores {
+			if v != nome && v != r.oponente && v != "EMPATE" {
+				t.Errorf("%s: vencedor %q não jogou a partida", nome, v)
+			}
+		}
+	}
+	if got := s.metricas.partidasConcluidas.Load(); got != pares*partidas {
+		t.Errorf("partidas concluídas = %d, esperado %d", got, pares*partidas)
+	}
+	// Cada partida consome um pacote de cada jogador; as cartas jogadas são descartadas
+	consumidas := estoqueInicial - s.cartasNoEstoque()
+	if esperadas := 2 * pares * partidas * s.regras.Load().CartasPorPacote; consumidas != esperadas {
+		t.Errorf("cartas retiradas do estoque = %d, esperado %d", consumidas, esperadas)
+	}
+}
+
+func TestTransporteMemoriaFechado(t *testing.T) {
+	transporte := novoTransporteMemoria()
+	transporte.Fechar()
+	if _, err := transporte.Discar(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Discar após Fechar: %v, esperado net.ErrClosed", err)
+	}
+	if _, err := transporte.Aceitar(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Aceitar após Fechar: %v, esperado net.ErrClosed", err)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+// ===================== BAREMA ITEM 2: COMUNICAÇÃO =====================
+// Gateway WebSocket (RFC 6455) para clientes de navegador.
+// Cada sessão WebSocket é adaptada para net.Conn e entregue por um Transporte,
+// de forma que passa pelo mesmo handleConnection, mailbox e lógica de Sala
+// das conexões TCP.
+// Os quadros de texto carregam o mesmo JSON de protocolo.Mensagem e podem vir
+// fragmentados; quadros binários, continuações sem início e mensagens
+// intercaladas encerram a sessão com o código de fechamento da RFC. O upgrade
+// só é aceito de páginas das origens permitidas (-ws-origens), para que um
+// site qualquer aberto pelo jogador não abra sessões em nome dele.
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/tls"
+	"embed"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GUID fixo definido pela RFC 6455 para o cálculo do Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes dos quadros WebSocket
+const (
+	wsOpContinuacao = 0x0
+	wsOpTexto       = 0x1
+	wsOpBinario     = 0x2
+	wsOpFechar      = 0x8
+	wsOpPing        = 0x9
+	wsOpPong        = 0xA
+)
+
+// Códigos de fechamento usados (RFC 6455, seção 7.4.1)
+const (
+	wsFechamentoProtocolo    = 1002 // Quadro fora da sequência ou malformado
+	wsFechamentoTipoInvalido = 1003 // Dados binários, que o protocolo do jogo não usa
+)
+
+// Tamanho máximo aceito para o payload de um único quadro
+const wsMaxPayload = 1 << 20
+
+// Prazo para o navegador enviar os cabeçalhos da requisição HTTP
+const wsPrazoCabecalho = 10 * time.Second
+
+//go:embed web
+var arquivosWeb embed.FS
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Transporte WebSocket
+// Um servidor HTTP serve o cliente HTML/JS estático em "/" e faz o upgrade em
+// "/ws"; cada sessão aceita chega a Aceitar já adaptada para net.Conn.
+// Com configTLS não nulo, o gateway atende via HTTPS/WSS.
+type transporteWS struct {
+	filaConexoes
+	http     *http.Server
+	endereco string
+	origens  []string // Origens aceitas no upgrade (vazio: só o próprio host)
+}
+
+func novoTransporteWS(endereco, origens string, configTLS *tls.Config) (*transporteWS, error) {
+	estaticos, err := fs.Sub(arquivosWeb, "web")
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", endereco)
+	if err != nil {
+		return nil, err
+	}
+	t := &transporteWS{filaConexoes: novaFilaConexoes(), endereco: "ws://" + listener.Addr().String()}
+	for _, origem := range strings.Split(origens, ",") {
+		if origem = strings.TrimSpace(origem); origem != "" {
+			t.origens = append(t.origens, strings.ToLower(strings.TrimSuffix(origem, "/")))
+		}
+	}
+	if configTLS != nil {
+		listener = tls.NewListener(listener, configTLS)
+		t.endereco = "wss://" + listener.Addr().String()
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(estaticos)))
+	mux.HandleFunc("/ws", t.handleWebSocket)
+	t.http = &http.Server{Handler: mux, ReadHeaderTimeout: wsPrazoCabecalho}
+	go t.http.Serve(listener)
+	return t, nil
+}
+
+// Fechar encerra o servidor HTTP; sessões já promovidas a WebSocket seguem abertas
+func (t *transporteWS) Fechar() error {
+	t.encerrar()
+	return t.http.Close()
+}
+
+func (t *transporteWS) Endereco() string { return t.endereco }
+
+func (t *transporteWS) Preparar(conn net.Conn) (string, error) {
+	if ws, ok := conn.(*wsConn); ok {
+		if tcpConn, ok := ws.Conn.(*net.TCPConn); ok {
+			tcpConn.SetNoDelay(true) // Desabilita algoritmo de Nagle
+		}
+	}
+	return "", nil
+}
+
+// BAREMA ITEM 2: COMUNICAÇÃO - Faz o handshake WebSocket e entrega a conexão
+// adaptada para o mesmo ciclo de vida das conexões TCP
+func (t *transporteWS) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet ||
+		!cabecalhoContem(r.Header, "Connection", "upgrade") ||
+		!cabecalhoContem(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "esperado upgrade para websocket", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "versão de websocket não suportada", http.StatusUpgradeRequired)
+		return
+	}
+	chave := r.Header.Get("Sec-WebSocket-Key")
+	if chave == "" {
+		http.Error(w, "Sec-WebSocket-Key ausente", http.StatusBadRequest)
+		return
+	}
+	if !t.origemPermitida(r) {
+		http.Error(w, "origem não permitida", http.StatusForbidden)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "upgrade não suportado", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	resposta := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + calcularAcceptWS(chave) + "\r\n\r\n"
+	if _, err := rw.WriteString(resposta); err != nil || rw.Flush() != nil {
+		conn.Close()
+		return
+	}
+
+	// A partir daqui a conexão segue o mesmo caminho das conexões TCP
+	ws := &wsConn{Conn: conn, leitor: rw.Reader}
+	if t.entregar(ws) != nil {
+		ws.Close() // Transporte fechado durante o upgrade
+	}
+}
+
+// origemPermitida confere o cabeçalho Origin que os navegadores enviam no
+// upgrade. Sem lista configurada, vale só o próprio host; clientes fora do
+// navegador não enviam Origin e são aceitos
+func (t *transporteWS) origemPermitida(r *http.Request) bool {
+	origem := r.Header.Get("Origin")
+	if origem == "" {
+		return true
+	}
+	if len(t.origens) == 0 {
+		u, err := url.Parse(origem)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	return slices.Contains(t.origens, "*") || slices.Contains(t.origens, strings.ToLower(origem))
+}
+
+func calcularAcceptWS(chave string) string {
+	h := sha1.Sum([]byte(chave + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func cabecalhoContem(h http.Header, nome, valor string) bool {
+	for _, v := range h.Values(nome) {
+		for _, parte := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(parte), valor) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* ====================== Adaptador WebSocket -> net.Conn ====================== */
+
+// wsConn expõe o fluxo de quadros de dados como um net.Conn comum.
+// Read devolve os payloads concatenados (o json.Decoder delimita as mensagens)
+// e cada Write vira um quadro de texto, já que o json.Encoder escreve uma
+// mensagem inteira por chamada.
+type wsConn struct {
+	net.Conn
+	leitor    *bufio.Reader
+	restante  uint64     // Bytes ainda não lidos do quadro de dados atual
+	mascara   [4]byte    // Máscara do quadro atual (clientes sempre mascaram)
+	posMasc   int        // Posição atual na máscara
+	escrita   sync.Mutex // Serializa quadros do writer e respostas de controle
+	encerrada bool       // Já houve quadro de fechamento: Read só devolve io.EOF
+	fechou    bool       // Quadro de fechamento já enviado (sob escrita): nada mais sai
+	// Mensagem de texto fragmentada em andamento: o próximo quadro de dados
+	// tem de ser uma continuação
+	fragmentada bool
+}
+
+var (
+	errQuadroInvalido = errors.New("websocket: quadro inválido")
+	errQuadroBinario  = errors.New("websocket: quadro binário não suportado")
+)
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	for c.restante == 0 || c.encerrada {
+		if c.encerrada {
+			return 0, io.EOF
+		}
+		if err := c.lerCabecalho(); err != nil {
+			return 0, err
+		}
+	}
+
+	if uint64(len(p)) > c.restante {
+		p = p[:c.restante]
+	}
+	n, err := c.leitor.Read(p)
+	for i := 0; i < n; i++ {
+		p[i] ^= c.mascara[c.posMasc%4]
+		c.posMasc++
+	}
+	c.restante -= uint64(n)
+	return n, err
+}
+
+// lerCabecalho consome o próximo quadro. Quadros de controle são tratados aqui
+// mesmo; para quadros de dados apenas prepara o payload para Read.
+func (c *wsConn) lerCabecalho() error {
+	var cab [2]byte
+	if _, err := io.ReadFull(c.leitor, cab[:]); err != nil {
+		return err
+	}
+	fin := cab[0]&0x80 != 0
+	opcode := cab[0] & 0x0F
+	mascarado := cab[1]&0x80 != 0
+	tamanho := uint64(cab[1] & 0x7F)
+	if cab[0]&0x70 != 0 {
+		return c.recusar(wsFechamentoProtocolo, errQuadroInvalido) // Bits RSV sem extensão negociada
+	}
+
+	switch tamanho {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.leitor, ext[:]); err != nil {
+			return err
+		}
+		tamanho = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.leitor, ext[:]); err != nil {
+			return err
+		}
+		tamanho = binary.BigEndian.Uint64(ext[:])
+	}
+	if !mascarado || tamanho > wsMaxPayload {
+		return errQuadroInvalido
+	}
+	if _, err := io.ReadFull(c.leitor, c.mascara[:]); err != nil {
+		return err
+	}
+	c.posMasc = 0
+
+	switch opcode {
+	case wsOpBinario:
+		return c.recusar(wsFechamentoTipoInvalido, errQuadroBinario)
+	case wsOpTexto, wsOpContinuacao:
+		// Um texto novo não pode interromper um fragmentado, e uma continuação precisa de início
+		if (opcode == wsOpTexto) == c.fragmentada {
+			return c.recusar(wsFechamentoProtocolo, errQuadroInvalido)
+		}
+		c.fragmentada = !fin
+		c.restante = tamanho
+		return nil
+	case wsOpPing, wsOpPong, wsOpFechar:
+		if tamanho > 125 || !fin {
+			return c.recusar(wsFechamentoProtocolo, errQuadroInvalido) // Controle não se fragmenta
+		}
+		payload := make([]byte, tamanho)
+		if _, err := io.ReadFull(c.leitor, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= c.mascara[i%4]
+		}
+		switch opcode {
+		case wsOpPing:
+			return c.escreverQuadro(wsOpPong, payload)
+		case wsOpFechar:
+			c.encerrada = true
+			c.escreverQuadro(wsOpFechar, payload)
+		}
+		return nil
+	default:
+		return errQuadroInvalido
+	}
+}
+
+// recusar encerra a conversa com o código de fechamento e devolve o erro a Read
+func (c *wsConn) recusar(codigo uint16, err error) error {
+	c.encerrada = true
+	c.escreverQuadro(wsOpFechar, binary.BigEndian.AppendUint16(nil, codigo))
+	return err
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	if err := c.escreverQuadro(wsOpTexto, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// escreverQuadro envia um quadro único (FIN) sem máscara, como exige a RFC
+// para quadros enviados pelo servidor. Depois do quadro de fechamento a
+// conexão não envia mais nada
+func (c *wsConn) escreverQuadro(opcode byte, payload []byte) error {
+	c.escrita.Lock()
+	defer c.escrita.Unlock()
+	if c.fechou {
+		return net.ErrClosed
+	}
+	c.fechou = opcode == wsOpFechar
+
+	cab := make([]byte, 2, 10+len(payload))
+	cab[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		cab[1] = byte(n)
+	case n <= 0xFFFF:
+		cab[1] = 126
+		cab = binary.BigEndian.AppendUint16(cab, uint16(n))
+	default:
+		cab[1] = 127
+		cab = binary.BigEndian.AppendUint64(cab, uint64(n))
+	}
+	_, err := c.Conn.Write(append(cab, payload...))
+	return err
+}
+
+func (c *wsConn) Close() error {
+	c.Conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.escreverQuadro(wsOpFechar, nil)
+	return c.Conn.Close()
+}
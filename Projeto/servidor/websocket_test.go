@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// quadroCliente monta um quadro mascarado, como os navegadores enviam
+func quadroCliente(fin bool, opcode byte, payload string) []byte {
+	quadro := []byte{opcode, 0x80 | byte(len(payload))}
+	if fin {
+		quadro[0] |= 0x80
+	}
+	mascara := [4]byte{0x12, 0x34, 0x56, 0x78}
+	quadro = append(quadro, mascara[:]...)
+	for i := range len(payload) {
+		quadro = append(quadro, payload[i]^mascara[i%4])
+	}
+	return quadro
+}
+
+// BAREMA ITEM 9: TESTES - Sequência de quadros da RFC 6455, seção 5.4
+// Cada caso envia quadros ao wsConn e confere os dados que Read entrega, o
+// erro que encerra a leitura e o código do quadro de fechamento devolvido.
+func TestQuadrosWebSocket(t *testing.T) {
+	casos := []struct {
+		nome    string
+		quadros [][]byte
+		dados   string
+		erro    error
+		codigo  uint16 // 0: fechamento sem código (eco do cliente)
+	}{
+		{"fragmentada", [][]byte{quadroCliente(false, wsOpTexto, `{"a":`), quadroCliente(true, wsOpContinuacao, `1}`), quadroCliente(true, wsOpFechar, "")}, `{"a":1}`, io.EOF, 0},
+		{"ping no meio da fragmentada", [][]byte{quadroCliente(false, wsOpTexto, "a"), quadroCliente(true, wsOpPing, ""), quadroCliente(true, wsOpContinuacao, "b"), quadroCliente(true, wsOpFechar, "")}, "ab", io.EOF, 0},
+		{"continuação sem início", [][]byte{quadroCliente(true, wsOpContinuacao, "x")}, "", errQuadroInvalido, wsFechamentoProtocolo},
+		{"binário", [][]byte{quadroCliente(true, wsOpBinario, "x")}, "", errQuadroBinario, wsFechamentoTipoInvalido},
+		{"intercalada", [][]byte{quadroCliente(false, wsOpTexto, "a"), quadroCliente(true, wsOpTexto, "b")}, "a", errQuadroInvalido, wsFechamentoProtocolo},
+		{"controle fragmentado", [][]byte{quadroCliente(false, wsOpPing, "")}, "", errQuadroInvalido, wsFechamentoProtocolo},
+		{"dados após o fechamento", [][]byte{quadroCliente(true, wsOpTexto, "a"), quadroCliente(true, wsOpFechar, ""), quadroCliente(true, wsOpTexto, "b")}, "a", io.EOF, 0},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			servidor, cliente := net.Pipe()
+			defer cliente.Close()
+			ws := &wsConn{Conn: servidor, leitor: bufio.NewReader(servidor)}
+			go func() {
+				for _, q := range caso.quadros {
+					if _, err := cliente.Write(q); err != nil {
+						return
+					}
+				}
+			}()
+			// Fechamentos enviados pelo servidor; pongs são descartados
+			fechamentos := make(chan []byte, 1)
+			go func() {
+				leitor := bufio.NewReader(cliente)
+				for {
+					var cab [2]byte
+					if _, err := io.ReadFull(leitor, cab[:]); err != nil {
+						return
+					}
+					payload := make([]byte, cab[1]&0x7F)
+					if _, err := io.ReadFull(leitor, payload); err != nil {
+						return
+					}
+					if cab[0]&0x0F == wsOpFechar {
+						fechamentos <- payload
+						return
+					}
+				}
+			}()
+
+			var lidos []byte
+			buf := make([]byte, 16)
+			var err error
+			for err == nil {
+				var n int
+				n, err = ws.Read(buf)
+				lidos = append(lidos, buf[:n]...)
+			}
+			if string(lidos) != caso.dados || !errors.Is(err, caso.erro) {
+				t.Fatalf("Read entregou %q e %v, esperado %q e %v", lidos, err, caso.dados, caso.erro)
+			}
+			payload := <-fechamentos
+			codigo := uint16(0)
+			if len(payload) >= 2 {
+				codigo = binary.BigEndian.Uint16(payload)
+			}
+			if codigo != caso.codigo {
+				t.Fatalf("fechamento com código %d, esperado %d", codigo, caso.codigo)
+			}
+			if _, err := ws.Write([]byte("{}")); err == nil {
+				t.Fatal("quadro de dados enviado depois do fechamento")
+			}
+		})
+	}
+}
+
+func TestOrigemWebSocket(t *testing.T) {
+	casos := []struct {
+		origens string
+		origem  string
+		aceita  bool
+	}{
+		{"", "", true}, // Cliente fora do navegador
+		{"", "http://jogo.local:8080", true},
+		{"", "https://outro.site", false},
+		{"https://jogo.exemplo.com", "https://jogo.exemplo.com", true},
+		{"https://jogo.exemplo.com", "http://jogo.local:8080", false},
+		{"*", "https://outro.site", true},
+	}
+	for _, caso := range casos {
+		ts := &transporteWS{}
+		if caso.origens != "" {
+			ts.origens = []string{caso.origens}
+		}
+		req := httptest.NewRequest(http.MethodGet, "http://jogo.local:8080/ws", nil)
+		if caso.origem != "" {
+			req.Header.Set("Origin", caso.origem)
+		}
+		if aceita := ts.origemPermitida(req); aceita != caso.aceita {
+			t.Errorf("origens %q, Origin %q: aceita = %v", caso.origens, caso.origem, aceita)
+		}
+	}
+
+	// O handler recusa o upgrade de outra origem antes do handshake
+	req := httptest.NewRequest(http.MethodGet, "http://jogo.local:8080/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "https://outro.site")
+	resposta := httptest.NewRecorder()
+	(&transporteWS{}).handleWebSocket(resposta, req)
+	if resposta.Code != http.StatusForbidden {
+		t.Fatalf("upgrade de outra origem: %d, esperado %d", resposta.Code, http.StatusForbidden)
+	}
+}
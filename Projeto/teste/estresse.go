@@ -6,9 +6,9 @@ package main
 // Foca em testar estabilidade, justiça na concorrência e múltiplas conexões.
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net"
+	"meujogo/clientesdk"
 	"sync"
 	"time"
 )
@@ -20,58 +20,57 @@ const quantidadeUsuarios = 20000 // Número total de usuários para testar
 // Isso evita que o próprio cliente se torne o gargalo do teste
 const maxConcurrentConnects = 300
 
-// Estruturas do protocolo (sem alterações)
-type Mensagem struct {
-	Comando string          `json:"comando"`
-	Dados   json.RawMessage `json:"dados"`
-}
-type DadosLogin struct {
-	Nome string `json:"nome"`
-}
-type ComprarPacoteReq struct {
-	Quantidade int `json:"quantidade"`
-}
+// BAREMA ITEM 3: API REMOTA - Cliente de teste construído sobre o SDK
 type ClienteTeste struct {
-	conn    net.Conn
-	nome    string
-	encoder *json.Encoder
-	decoder *json.Decoder
+	*clientesdk.Cliente
+	nome string
 }
 
 func (c *ClienteTeste) conectar() error {
-	conn, err := net.DialTimeout("tcp", "servidor:65432", 10*time.Second)
+	cliente, err := clientesdk.Conectar(context.Background(), clientesdk.Opcoes{
+		Endereco: "servidor:65432",
+		Timeout:  10 * time.Second,
+	})
 	if err != nil {
 		return err
 	}
-	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		tcpConn.SetNoDelay(true)
-	}
-	c.conn = conn
-	c.encoder = json.NewEncoder(conn)
-	c.decoder = json.NewDecoder(conn)
+	c.Cliente = cliente
 	return nil
 }
 func (c *ClienteTeste) login() error {
-	dados, _ := json.Marshal(DadosLogin{Nome: c.nome})
-	return c.encoder.Encode(Mensagem{Comando: "LOGIN", Dados: dados})
+	return c.Login(c.nome)
 }
 func (c *ClienteTeste) entrarNaFila() error {
-	return c.encoder.Encode(Mensagem{Comando: "ENTRAR_NA_FILA"})
+	return c.EntrarNaFila()
 }
 func (c *ClienteTeste) comprarPacote() error {
-	dados, _ := json.Marshal(ComprarPacoteReq{Quantidade: 1})
-	return c.encoder.Encode(Mensagem{Comando: "COMPRAR_PACOTE", Dados: dados})
+	return c.ComprarPacote(1)
 }
-func (c *ClienteTeste) lerMensagens() {
-	defer c.conn.Close()
+
+// esperar aguarda um evento com o comando indicado (vazio: qualquer um)
+// Devolve false se a conexão terminar ou nada chegar dentro do prazo
+func (c *ClienteTeste) esperar(comando string, prazo time.Duration) bool {
+	limite := time.NewTimer(prazo)
+	defer limite.Stop()
 	for {
-		c.conn.SetReadDeadline(time.Now().Add(20 * time.Second))
-		var msg Mensagem
-		if err := c.decoder.Decode(&msg); err != nil {
-			return
+		select {
+		case ev, ok := <-c.Eventos():
+			if !ok || ev.Comando == clientesdk.EventoDesconectado {
+				return false
+			}
+			if comando == "" || ev.Comando == comando {
+				return true
+			}
+		case <-limite.C:
+			return false
 		}
 	}
 }
+func (c *ClienteTeste) lerMensagens() {
+	defer c.Fechar()
+	for c.esperar("", 20*time.Second) {
+	}
+}
 
 // BAREMA ITEM 9: TESTES - Teste 1: Estabilidade sob carga
 // Verifica se o servidor consegue manter muitas conexões simultâneas
@@ -97,7 +96,7 @@ func testeEstabilidade(n int, wg *sync.WaitGroup, resultChan chan<- string) {
 			if err := cliente.conectar(); err != nil {
 				return
 			}
-			defer cliente.conn.Close()
+			defer cliente.Fechar()
 			if err := cliente.login(); err != nil {
 				return
 			}
@@ -143,7 +142,7 @@ func testeJustica(n int, wg *sync.WaitGroup, resultChan chan<- string) {
 				wgProntos.Done()
 				return
 			}
-			defer cliente.conn.Close()
+			defer cliente.Fechar()
 			if err := cliente.login(); err != nil {
 				wgProntos.Done()
 				return
@@ -158,25 +157,11 @@ func testeJustica(n int, wg *sync.WaitGroup, resultChan chan<- string) {
 			}
 
 			// BAREMA ITEM 9: TESTES - Aguarda resultado da compra
-			timeout := time.After(20 * time.Second)
-			for {
-				select {
-				case <-timeout:
-					return
-				default:
-					cliente.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-					var msg Mensagem
-					if err := cliente.decoder.Decode(&msg); err != nil {
-						return
-					}
-					if msg.Comando == "PACOTE_RESULTADO" {
-						// BAREMA ITEM 9: TESTES - Registra compra bem-sucedida
-						mutex.Lock()
-						sucessos++
-						mutex.Unlock()
-						return
-					}
-				}
+			if cliente.esperar("PACOTE_RESULTADO", 20*time.Second) {
+				// BAREMA ITEM 9: TESTES - Registra compra bem-sucedida
+				mutex.Lock()
+				sucessos++
+				mutex.Unlock()
 			}
 		}(i)
 	}
@@ -208,7 +193,7 @@ func testeConexoes(n int, wg *sync.WaitGroup, resultChan chan<- string) {
 			if err := cliente.conectar(); err != nil {
 				return
 			}
-			defer cliente.conn.Close()
+			defer cliente.Fechar()
 			if err := cliente.login(); err != nil {
 				return
 			}